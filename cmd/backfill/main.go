@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/application/container"
+	"gin-demo/internal/system/backfill"
+	"os"
+	"time"
+)
+
+func main() {
+	columnId := flag.Int64("column", 0, "Dynamic column id to enqueue a new backfill job for before running")
+	jobId := flag.Int64("job", 0, "Backfill job id to run or resume (default: every pending or interrupted job)")
+	chunkSize := flag.Int("chunk", 0, "Number of rows refreshed per transaction when enqueuing a job")
+	status := flag.Bool("status", false, "Print the status of every backfill job and exit")
+	watch := flag.Duration("watch", 0, "Keep polling for pending jobs at this interval (e.g. 30s)")
+	flag.Parse()
+
+	// Load config
+	configEnv := config.LoadEnv()
+
+	// Connect to database
+	db := config.NewDB(configEnv)
+	ctx := context.WithValue(context.Background(), config.ContextKeyDB, db)
+	c := container.NewContainer()
+
+	if *status {
+		printStatus(ctx, c)
+		return
+	}
+
+	if *columnId > 0 {
+		job, err := c.BackfillService.Enqueue(ctx, &backfill.BackfillJobCreateRequest{DynamicColumnId: *columnId, ChunkSize: *chunkSize})
+		if err != nil {
+			fmt.Println("Error enqueuing backfill job:", err)
+			os.Exit(1)
+		}
+		*jobId = job.ID
+	}
+
+	for {
+		var err error
+		if *jobId > 0 {
+			err = c.BackfillService.RunJob(ctx, *jobId, printProgress)
+		} else {
+			err = c.BackfillService.RunPendingJobs(ctx, printProgress)
+		}
+		if err != nil {
+			fmt.Println("Backfill failed:", err)
+			os.Exit(1)
+		}
+		if *watch == 0 || *jobId > 0 {
+			break
+		}
+		time.Sleep(*watch)
+	}
+
+	fmt.Println("Backfill completed!")
+}
+
+func printProgress(job *backfill.DynamicColumnBackfillJob) {
+	fmt.Printf("job %d %s.%s [%s]: %d/%d rows (%.2f%%), checkpoint id %d\n",
+		job.ID, job.TableName, job.ColumnName, job.Status,
+		job.ProcessedRows, job.TotalRows, job.Progress(), job.LastId)
+}
+
+func printStatus(ctx context.Context, c *container.Container) {
+	jobs := c.BackfillService.GetAll(ctx)
+	if len(jobs) == 0 {
+		fmt.Println("No backfill jobs")
+		return
+	}
+	for i := range jobs {
+		printProgress(&jobs[i])
+		if jobs[i].Error != "" {
+			fmt.Printf("    error: %s\n", jobs[i].Error)
+		}
+	}
+}
@@ -7,11 +7,20 @@ import (
 	"gin-demo/internal/application/config"
 	"gin-demo/internal/application/container"
 	"gin-demo/internal/shared/constants"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
+	cron := flag.Bool("cron", false, "Run the refresh schedules of time dependent dynamic columns in the foreground instead of refreshing ids")
 	flag.Parse()
+	if *cron {
+		crontab()
+		return
+	}
 	args := flag.Args()
 	table := args[0]
 	idsStr := args[1]
@@ -32,9 +41,40 @@ func main() {
 	ctx = context.WithValue(ctx, config.ContextKeyDB, db)
 	c := container.NewContainer()
 
-	c.DynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableName(table), ids, constants.ActionRefresh, nil, nil)
+	result, err := c.DynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableName(table), ids, constants.ActionRefresh, nil, nil)
+	if err != nil {
+		fmt.Println("Refresh failed:", err)
+		os.Exit(1)
+	}
+	for _, field := range result.Columns {
+		fmt.Printf("%s.%s: %d rows changed\n", field.TableName, field.Column, field.RowsAffected)
+	}
 }
 
+// crontab refreshes time dependent dynamic columns on their schedules, and the rows whose transition has passed, until interrupted
 func crontab() {
+	configEnv := config.LoadEnv()
+	logger := config.NewLogger()
+	db := config.NewDB(configEnv)
+	c := container.NewContainer()
+	err := c.RefreshScheduleService.SetDefaultCron(configEnv.RefreshCron)
+	if err != nil {
+		fmt.Println("Invalid refresh cron:", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = context.WithValue(ctx, config.ContextKeyDB, db)
 
+	schedules, err := c.RefreshScheduleService.GetSchedules(ctx)
+	if err != nil {
+		fmt.Println("Error loading refresh schedules:", err)
+		os.Exit(1)
+	}
+	for _, schedule := range schedules {
+		fmt.Printf("%s: %s, next run at %s\n", schedule.Cron, strings.Join(schedule.Columns, ", "), schedule.NextRunAt.Format(time.RFC3339))
+	}
+	go c.TransitionService.Run(ctx, constants.TRANSITION_POLL_INTERVAL, logger)
+	c.RefreshScheduleService.Run(ctx, logger)
 }
@@ -17,7 +17,11 @@ func SeedContracts(db *gorm.DB, logger *slog.Logger) {
 	// add db to ctx so that it can be used in service/repository layers
 	container := container.NewContainer()
 	ctx = context.WithValue(ctx, config.ContextKeyDB, db)
-	companies := container.CompanyService.GetAllCompanies(ctx)
+	companies, err := container.CompanyService.GetAllCompanies(ctx)
+	if err != nil {
+		logger.Error("Failed to get companies", "error", err)
+		return
+	}
 	for _, comp := range companies {
 		fmt.Printf("Seeding contracts for company ID %d...\n", comp.Id)
 		totalStart := time.Now()
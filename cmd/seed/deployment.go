@@ -20,7 +20,11 @@ func SeedDeployments(db *gorm.DB, logger *slog.Logger) {
 	ctx = context.WithValue(ctx, config.ContextKeyDB, db)
 	logPayload := &config.LogPayload{}
 	ctx = context.WithValue(ctx, config.LogPayloadKey, logPayload)
-	contracts := container.ContractService.GetAll(ctx)
+	contracts, err := container.ContractService.GetAll(ctx)
+	if err != nil {
+		logger.Error("Failed to get contracts", "error", err)
+		return
+	}
 	fmt.Println(len(contracts))
 	for _, contract := range contracts {
 		for i := 1; i <= 10; i++ {
@@ -40,6 +40,7 @@ func seedCompanies(db *gorm.DB, logger *slog.Logger) {
 				company_status AS (
 					SELECT 
 						c.id,
+						c.status AS old_status,
 						CASE
 							WHEN c.is_active = false THEN '%s'
 							WHEN COALESCE(ca.total_count, 0) = 0 THEN '%s'
@@ -56,7 +57,8 @@ func seedCompanies(db *gorm.DB, logger *slog.Logger) {
 				SET status = cs.status
 				FROM company_status cs
 				WHERE cp.id = cs.id
-				AND cp.status IS DISTINCT FROM cs.status;
+				AND cp.status IS DISTINCT FROM cs.status
+				RETURNING cp.id AS record_id, cs.old_status::text AS old_value, cs.status::text AS new_value;
 			`,
 				constants.InvoiceStatusOverdue, constants.TEMP_TABLE_NAME, constants.ApprovalApproved,
 				constants.TEMP_TABLE_NAME, constants.CompanyStatusInactive, constants.CompanyStatusNoApproval,
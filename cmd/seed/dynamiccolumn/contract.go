@@ -43,7 +43,7 @@ func seedContracts(db *gorm.DB, logger *slog.Logger) {
 					JOIN company cp ON cp.id = ct.company_id AND cp.is_deleted = false
 				),
 				contract_status AS (
-					SELECT ct.id,
+					SELECT ct.id, ct.status AS old_status,
 						CASE
 							WHEN ct.is_cancelled = true THEN '%s'
 							WHEN cp.status <> '%s' THEN '%s'
@@ -69,6 +69,7 @@ func seedContracts(db *gorm.DB, logger *slog.Logger) {
 				SET status = cs.status
 				FROM contract_status cs
 				WHERE c.id = cs.id AND c.status IS DISTINCT FROM cs.status
+				RETURNING c.id AS record_id, cs.old_status::text AS old_value, cs.status::text AS new_value
 			`,
 				constants.InvoiceStatusOverdue, constants.TEMP_TABLE_NAME, constants.DeploymentStatusCompleted,
 				constants.TEMP_TABLE_NAME, constants.TEMP_TABLE_NAME, constants.ContractStatusCanceled,
@@ -19,7 +19,7 @@ func seedDeployments(db *gorm.DB, logger *slog.Logger) {
 			Type:      "string",
 			Formula: fmt.Sprintf(`
 			WITH deployment_status AS (
-				SELECT dpl.id,
+				SELECT dpl.id, dpl.status AS old_status,
 					CASE
 						WHEN dpl.is_cancelled = true THEN '%s'
 						WHEN dpl.employee_id IS NULL OR (dpl.checkin_at IS NULL AND dpl.checkout_at IS NULL) THEN '%s'
@@ -34,6 +34,7 @@ func seedDeployments(db *gorm.DB, logger *slog.Logger) {
 			SET status = ds.status
 			FROM deployment_status ds
 			WHERE ds.id = d.id AND d.status IS DISTINCT FROM ds.status
+			RETURNING d.id AS record_id, ds.old_status::text AS old_value, ds.status::text AS new_value
 			`,
 				constants.DeploymentStatusCanceled, constants.DeploymentStatusPending, constants.DeploymentStatusInProgress,
 				constants.DeploymentStatusCompleted, constants.TEMP_TABLE_NAME),
@@ -19,7 +19,7 @@ func seedInvoices(db *gorm.DB, logger *slog.Logger) {
 			Type:      "float",
 			Formula: fmt.Sprintf(`
 			WITH invoice_payments AS (
-				SELECT inv.id,
+				SELECT inv.id, inv.pending_amount AS old_pending_amount,
 					COALESCE(inv.total_amount - SUM(pmt.amount), inv.total_amount) AS pending_amount
 				FROM invoice inv
 				JOIN %s p ON inv.id = p.id
@@ -31,6 +31,7 @@ func seedInvoices(db *gorm.DB, logger *slog.Logger) {
 			SET pending_amount = ip.pending_amount
 			FROM invoice_payments ip
 			WHERE i.id = ip.id AND i.pending_amount IS DISTINCT FROM ip.pending_amount
+			RETURNING i.id AS record_id, ip.old_pending_amount::text AS old_value, ip.pending_amount::text AS new_value
 			`, constants.TEMP_TABLE_NAME),
 			Dependencies: map[constants.TableName]dynamiccolumn.Dependency{
 				constants.TableNameInvoice: {
@@ -47,12 +48,23 @@ func seedInvoices(db *gorm.DB, logger *slog.Logger) {
 			Name:      "status",
 			Type:      "string",
 			Formula: fmt.Sprintf(`
-				CASE 
-					WHEN pending_amount <= 0 THEN '%s'
-					WHEN CURRENT_DATE - created_at > payment_terms * INTERVAL '1 day' THEN '%s'
-					ELSE '%s' 
-				END
-			`, constants.InvoiceStatusPaid, constants.InvoiceStatusOverdue, constants.InvoiceStatusPending),
+			WITH invoice_status AS (
+				SELECT inv.id, inv.status AS old_status,
+					CASE 
+						WHEN inv.pending_amount <= 0 THEN '%s'
+						WHEN CURRENT_DATE - inv.created_at > inv.payment_terms * INTERVAL '1 day' THEN '%s'
+						ELSE '%s' 
+					END AS status
+				FROM invoice inv
+				JOIN %s p ON inv.id = p.id
+				WHERE inv.is_deleted = false
+			)
+			UPDATE invoice i
+			SET status = ist.status
+			FROM invoice_status ist
+			WHERE i.id = ist.id AND i.status IS DISTINCT FROM ist.status
+			RETURNING i.id AS record_id, ist.old_status::text AS old_value, ist.status::text AS new_value
+			`, constants.InvoiceStatusPaid, constants.InvoiceStatusOverdue, constants.InvoiceStatusPending, constants.TEMP_TABLE_NAME),
 			Dependencies: map[constants.TableName]domainDynamicColumn.Dependency{
 				constants.TableNameInvoice: {
 					Columns: []string{"pending_amount", "created_at", "payment_terms"},
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"gin-demo/internal/application/config"
 	"gin-demo/internal/application/container"
 	"gin-demo/internal/application/middlewares"
+	"gin-demo/internal/shared/constants"
 	"gin-demo/internal/shared/utils"
 )
 
@@ -14,13 +16,55 @@ func main() {
 	dbPool := config.NewDB(configEnv)
 	app := config.NewServer(
 		configEnv, middlewares.LogMiddleware(logger),
-		middlewares.DbMiddleware(dbPool),
+		middlewares.RequestIdMiddleware(),
 	)
+	// Routes registered before a middleware do not run it, scrapes of the metrics open no database transaction
+	SetupMetricsRoute(app)
+	app.Use(middlewares.DbMiddleware(dbPool))
 
 	// Start Dependency Injection and Route Setup
 	container := container.NewContainer()
 	SetupRoutes(app, container)
 
+	workerCtx := context.WithValue(context.Background(), config.ContextKeyDB, dbPool)
+
+	// Formulas compiled by an older version miss the changes the history, the webhooks and the refresh results are built from
+	recompiled, err := container.DynamicColumnService.RecompileFormulas(workerCtx)
+	if err != nil {
+		panic(err)
+	}
+	if len(recompiled.Recompiled) > 0 {
+		logger.Info("dynamic column formulas recompiled", "columns", recompiled.Recompiled)
+	}
+	if len(recompiled.Stale) > 0 {
+		logger.Warn("hand written dynamic column formulas do not return their changes, their history is not recorded", "columns", recompiled.Stale)
+	}
+
+	// Refresh time dependent dynamic columns, and the views of view mode columns, in the background, the advisory lock lets a single instance run each schedule
+	err = container.RefreshScheduleService.SetDefaultCron(configEnv.RefreshCron)
+	if err != nil {
+		panic(err)
+	}
+	go container.RefreshScheduleService.Run(workerCtx, logger)
+
+	// Compute the columns created or updated on tables too large to recompute in the request transaction
+	go container.BackfillService.Run(workerCtx, constants.BACKFILL_POLL_INTERVAL, logger)
+
+	// Refresh the rows whose next transition has passed, e.g. invoices reaching their payment deadline
+	go container.TransitionService.Run(workerCtx, constants.TRANSITION_POLL_INTERVAL, logger)
+
+	// Writes made outside the application are notified by triggers on the dependencies, kept in sync with the definitions.
+	// The worker started with -capture listens to them.
+	if configEnv.ChangeCapture {
+		container.DynamicColumnService.SetChangeCapture(container.ChangeCaptureService)
+		err = container.ChangeCaptureService.SyncTriggers(workerCtx)
+	} else {
+		err = container.ChangeCaptureService.DropTriggers(workerCtx)
+	}
+	if err != nil {
+		panic(err)
+	}
+
 	utils.PrettyPrintRoutes(app.Routes())
 
 	app.Run(fmt.Sprintf(":%s", app.Port))
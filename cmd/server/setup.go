@@ -11,8 +11,24 @@ import (
 	"gin-demo/internal/domain/invoice"
 	"gin-demo/internal/domain/payment"
 	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/metrics"
+	"gin-demo/internal/system/backfill"
+	"gin-demo/internal/system/changecapture"
+	"gin-demo/internal/system/dynamiccolumn"
+	"gin-demo/internal/system/history"
+	"gin-demo/internal/system/outbox"
+	"gin-demo/internal/system/refreshschedule"
+	"gin-demo/internal/system/transition"
+	"gin-demo/internal/system/webhook"
+
+	"github.com/gin-gonic/gin"
 )
 
+// SetupMetricsRoute exposes the Prometheus metrics, it is registered before DbMiddleware
+func SetupMetricsRoute(app *config.App) {
+	app.GET("/metrics", gin.WrapH(metrics.Handler()))
+}
+
 func SetupRoutes(app *config.App, c *container.Container) {
 	invoice.RegisterRoutes("v1", app, []base.HandlerConfig{
 		{Method: "GET", Path: "", Handler: c.InvoiceHandler.GetAll},
@@ -63,4 +79,54 @@ func SetupRoutes(app *config.App, c *container.Container) {
 		{Method: "PUT", Path: "/:id", Handler: c.DeploymentHandler.Update},
 		{Method: "DELETE", Path: "/:id", Handler: c.DeploymentHandler.Delete},
 	})
+	dynamiccolumn.RegisterRoutes("v1", app, []base.HandlerConfig{
+		{Method: "GET", Path: "", Handler: c.DynamicColumnHandler.GetAll},
+		{Method: "GET", Path: "/ddl-logs", Handler: c.DynamicColumnHandler.GetAllDdlLogs},
+		{Method: "GET", Path: "/refresh-modes", Handler: c.DynamicColumnHandler.GetRefreshModes},
+		{Method: "POST", Path: "/triggers/sync", Handler: c.DynamicColumnHandler.SyncDbTriggers},
+		{Method: "GET", Path: "/:id", Handler: c.DynamicColumnHandler.GetById},
+		{Method: "POST", Path: "", Handler: c.DynamicColumnHandler.Create},
+		{Method: "POST", Path: "/validate", Handler: c.DynamicColumnHandler.Validate},
+		{Method: "POST", Path: "/preview", Handler: c.DynamicColumnHandler.Preview},
+		{Method: "POST", Path: "/refresh-plan", Handler: c.DynamicColumnHandler.RefreshPlan},
+		{Method: "POST", Path: "/:id/view/refresh", Handler: c.DynamicColumnHandler.RefreshView},
+		{Method: "PUT", Path: "/:id", Handler: c.DynamicColumnHandler.Update},
+		{Method: "DELETE", Path: "/:id", Handler: c.DynamicColumnHandler.Delete},
+	})
+	backfill.RegisterRoutes("v1", app, []base.HandlerConfig{
+		{Method: "GET", Path: "", Handler: c.BackfillHandler.GetAll},
+		{Method: "GET", Path: "/:id", Handler: c.BackfillHandler.GetById},
+		{Method: "POST", Path: "", Handler: c.BackfillHandler.Create},
+		{Method: "POST", Path: "/:id/resume", Handler: c.BackfillHandler.Resume},
+	})
+	refreshschedule.RegisterRoutes("v1", app, []base.HandlerConfig{
+		{Method: "GET", Path: "", Handler: c.RefreshScheduleHandler.GetAll},
+		{Method: "GET", Path: "/runs", Handler: c.RefreshScheduleHandler.GetAllRuns},
+		{Method: "POST", Path: "/run", Handler: c.RefreshScheduleHandler.Run},
+	})
+	transition.RegisterRoutes("v1", app, []base.HandlerConfig{
+		{Method: "GET", Path: "", Handler: c.TransitionHandler.GetUpcoming},
+	})
+	outbox.RegisterRoutes("v1", app, []base.HandlerConfig{
+		{Method: "GET", Path: "", Handler: c.OutboxHandler.GetAll},
+		{Method: "POST", Path: "/:id/retry", Handler: c.OutboxHandler.Retry},
+	})
+	changecapture.RegisterRoutes("v1", app, []base.HandlerConfig{
+		{Method: "GET", Path: "", Handler: c.ChangeCaptureHandler.GetAll},
+		{Method: "POST", Path: "/sync", Handler: c.ChangeCaptureHandler.Sync},
+	})
+	history.RegisterRoutes("v1", app, []base.HandlerConfig{
+		{Method: "GET", Path: "", Handler: c.HistoryHandler.GetAll},
+		{Method: "GET", Path: "/timeline", Handler: c.HistoryHandler.GetTimeline},
+	})
+	webhook.RegisterRoutes("v1", app, []base.HandlerConfig{
+		{Method: "GET", Path: "", Handler: c.WebhookHandler.GetAll},
+		{Method: "GET", Path: "/deliveries", Handler: c.WebhookHandler.GetAllDeliveries},
+		{Method: "GET", Path: "/dead-letters", Handler: c.WebhookHandler.GetDeadLetters},
+		{Method: "POST", Path: "/deliveries/:id/replay", Handler: c.WebhookHandler.Replay},
+		{Method: "GET", Path: "/:id", Handler: c.WebhookHandler.GetById},
+		{Method: "POST", Path: "", Handler: c.WebhookHandler.Create},
+		{Method: "PUT", Path: "/:id", Handler: c.WebhookHandler.Update},
+		{Method: "DELETE", Path: "/:id", Handler: c.WebhookHandler.Delete},
+	})
 }
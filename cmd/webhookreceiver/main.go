@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/webhook"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// A stand-in receiver for the webhooks, to try the deliveries locally: it verifies the signature of every
+// request and logs its payload. -status makes it answer a failing status to try the retries and the dead-letter list.
+func main() {
+	logger := config.NewLogger()
+
+	addr := flag.String("addr", ":9090", "Address to listen on, subscribe http://localhost:9090/ to deliver here")
+	secret := flag.String("secret", "", "Secret of the subscriptions delivered here, the signatures are not checked when empty")
+	status := flag.Int("status", http.StatusOK, "Status answered to every valid delivery")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		delivery := r.Header.Get(constants.WEBHOOK_DELIVERY_HEADER)
+
+		if *secret != "" {
+			timestamp, err := strconv.ParseInt(r.Header.Get(constants.WEBHOOK_TIMESTAMP_HEADER), 10, 64)
+			if err != nil || !webhook.Verify(*secret, timestamp, body, r.Header.Get(constants.WEBHOOK_SIGNATURE_HEADER)) {
+				logger.Warn("webhook rejected, invalid signature", "delivery", delivery)
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		logger.Info("webhook received", "delivery", delivery, "event", r.Header.Get(constants.WEBHOOK_EVENT_HEADER),
+			"status", *status, "payload", string(body))
+		w.WriteHeader(*status)
+	})
+
+	fmt.Println("Webhook receiver listening on", *addr)
+	err := http.ListenAndServe(*addr, nil)
+	if err != nil {
+		fmt.Println("Webhook receiver failed:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/application/container"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/metrics"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	// Load config
+	configEnv := config.LoadEnv()
+	logger := config.NewLogger()
+
+	interval := flag.Duration("interval", constants.OUTBOX_POLL_INTERVAL, "Interval between two polls of the refresh outbox")
+	batchSize := flag.Int("batch", constants.OUTBOX_BATCH_SIZE, "Number of outbox changes claimed and coalesced per transaction")
+	once := flag.Bool("once", false, "Drain the pending changes once and exit")
+	capture := flag.Bool("capture", configEnv.ChangeCapture, "Refresh the writes made outside the application notified by the change capture triggers")
+	metricsAddr := flag.String("metrics", "", "Address to serve the refresh metrics on, e.g. :9091, not served when empty")
+	webhooks := flag.Bool("webhooks", true, "Deliver the webhooks of the changed dynamic column values")
+	flag.Parse()
+
+	// Connect to database
+	db := config.NewDB(configEnv)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = context.WithValue(ctx, config.ContextKeyDB, db)
+	c := container.NewContainer()
+
+	if *once {
+		result, err := c.OutboxService.RunPending(ctx, *batchSize)
+		fmt.Printf("Refresh outbox: %d processed, %d failed\n", result.Processed, result.Failed)
+		if err != nil {
+			fmt.Println("Refresh outbox failed:", err)
+			os.Exit(1)
+		}
+		if *webhooks {
+			deliveries, err := c.WebhookService.RunPending(ctx, constants.WEBHOOK_BATCH_SIZE)
+			fmt.Printf("Webhooks: %d delivered, %d retried, %d dead\n", deliveries.Delivered, deliveries.Retried, deliveries.Dead)
+			if err != nil {
+				fmt.Println("Webhook delivery failed:", err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	// The refreshes of the worker are not seen by the /metrics of the server, every worker serves its own
+	if *metricsAddr != "" {
+		go func() {
+			err := http.ListenAndServe(*metricsAddr, metrics.Handler())
+			if err != nil {
+				logger.Error("worker metrics stopped", "error", err.Error())
+			}
+		}()
+	}
+
+	// A single worker should capture the changes, every listener refreshes every change
+	if *capture {
+		go c.ChangeCaptureService.Run(ctx, logger)
+	}
+
+	// Deliveries are claimed with SKIP LOCKED, any number of workers can deliver them
+	if *webhooks {
+		go c.WebhookService.Run(ctx, constants.WEBHOOK_POLL_INTERVAL, constants.WEBHOOK_BATCH_SIZE, logger)
+	}
+
+	// Async dynamic columns are refreshed until the worker is stopped
+	c.OutboxService.Run(ctx, *interval, *batchSize, logger)
+}
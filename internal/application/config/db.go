@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"gin-demo/internal/shared/constants"
 	"log"
 	"os"
 	"time"
@@ -15,12 +16,14 @@ import (
 const ContextKeyDB = "db"
 
 func NewDB(configEnv *ConfigEnv) *gorm.DB {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+	// The application refreshes the dynamic columns of its own writes, the change capture triggers skip its sessions
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable %s=off",
 		configEnv.DbHost,
 		configEnv.DbUsername,
 		configEnv.DbPassword,
 		configEnv.DbDatabase,
-		configEnv.DbPort)
+		configEnv.DbPort,
+		constants.CAPTURE_SETTING)
 	fmt.Println(dsn)
 
 	// Configure logger to print all SQL queries
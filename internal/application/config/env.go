@@ -1,6 +1,7 @@
 package config
 
 import (
+	"gin-demo/internal/shared/constants"
 	"os"
 
 	"github.com/joho/godotenv"
@@ -13,17 +14,23 @@ type ConfigEnv struct {
 	DbDatabase string
 	DbUsername string
 	DbPassword string
+	// RefreshCron is the default schedule of time dependent dynamic columns
+	RefreshCron string
+	// ChangeCapture installs the triggers notifying the writes made outside the application
+	ChangeCapture bool
 }
 
 func LoadEnv() *ConfigEnv {
 	godotenv.Load()
 	return &ConfigEnv{
-		AppPort:    getenv("APP_PORT", "8000"),
-		DbPort:     getenv("DB_PORT", "5432"),
-		DbDatabase: getenv("DB_DATABASE", "gin"),
-		DbUsername: getenv("DB_USERNAME", "admin"),
-		DbPassword: getenv("DB_PASSWORD", "adminpw"),
-		DbHost:     getenv("DB_HOST", "localhost"),
+		AppPort:       getenv("APP_PORT", "8000"),
+		DbPort:        getenv("DB_PORT", "5432"),
+		DbDatabase:    getenv("DB_DATABASE", "gin"),
+		DbUsername:    getenv("DB_USERNAME", "admin"),
+		DbPassword:    getenv("DB_PASSWORD", "adminpw"),
+		DbHost:        getenv("DB_HOST", "localhost"),
+		RefreshCron:   getenv("DYNAMIC_COLUMN_REFRESH_CRON", constants.DEFAULT_REFRESH_CRON),
+		ChangeCapture: getenv("DYNAMIC_COLUMN_CHANGE_CAPTURE", "false") == "true",
 	}
 }
 
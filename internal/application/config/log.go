@@ -9,6 +9,10 @@ type LogPayload map[string]any
 
 const LogPayloadKey = "log_payload"
 
+// ContextKeyRequestId holds the id of the request, read from or written to the RequestIdHeader
+const ContextKeyRequestId = "request_id"
+const RequestIdHeader = "X-Request-Id"
+
 func NewLogger() *slog.Logger {
 	return slog.New(slog.NewJSONHandler(
 		os.Stdout,
@@ -11,14 +11,44 @@ import (
 	"gin-demo/internal/shared/constants"
 	"gin-demo/internal/shared/types"
 	"gin-demo/internal/shared/utils"
+	"gin-demo/internal/system/backfill"
+	"gin-demo/internal/system/changecapture"
 	"gin-demo/internal/system/dynamiccolumn"
+	"gin-demo/internal/system/history"
+	"gin-demo/internal/system/outbox"
+	"gin-demo/internal/system/refreshschedule"
+	"gin-demo/internal/system/transition"
+	"gin-demo/internal/system/webhook"
+	"slices"
 )
 
 type Container struct {
 
 	// Shared Dependencies can be added here
-	DynamicColumnRepository dynamiccolumn.DynamicColumnRepository
-	DynamicColumnService    dynamiccolumn.DynamicColumnService
+	DynamicColumnRepository   dynamiccolumn.DynamicColumnRepository
+	DynamicColumnService      dynamiccolumn.DynamicColumnService
+	DynamicColumnHandler      dynamiccolumn.DynamicColumnHandler
+	BackfillRepository        backfill.BackfillRepository
+	BackfillService           backfill.BackfillService
+	BackfillHandler           backfill.BackfillHandler
+	RefreshScheduleRepository refreshschedule.RefreshScheduleRepository
+	RefreshScheduleService    refreshschedule.RefreshScheduleService
+	RefreshScheduleHandler    refreshschedule.RefreshScheduleHandler
+	TransitionRepository      transition.TransitionRepository
+	TransitionService         transition.TransitionService
+	TransitionHandler         transition.TransitionHandler
+	OutboxRepository          outbox.OutboxRepository
+	OutboxService             outbox.OutboxService
+	OutboxHandler             outbox.OutboxHandler
+	ChangeCaptureRepository   changecapture.ChangeCaptureRepository
+	ChangeCaptureService      changecapture.ChangeCaptureService
+	ChangeCaptureHandler      changecapture.ChangeCaptureHandler
+	HistoryRepository         history.HistoryRepository
+	HistoryService            history.HistoryService
+	HistoryHandler            history.HistoryHandler
+	WebhookRepository         webhook.WebhookRepository
+	WebhookService            webhook.WebhookService
+	WebhookHandler            webhook.WebhookHandler
 
 	// Invoice Domain
 	InvoiceRepository invoice.InvoiceRepository
@@ -69,48 +99,90 @@ func NewModelsMap() types.ModelsMap {
 	}
 }
 
+// NewRelations collects the relations declared by the domains
+func NewRelations() []types.Relation {
+	//insert table relations here
+	return slices.Concat(
+		invoice.Relations,
+		contract.Relations,
+		payment.Relations,
+		approval.Relations,
+		deployment.Relations,
+	)
+}
+
 func NewContainer() *Container {
 	c := &Container{}
 
 	// Shared Dependencies can be initialized here
 	modelsMap := NewModelsMap()
-	modelRelationsMap := utils.BuildRelationMap(modelsMap)
+	relationRegistry, err := utils.BuildRelationRegistry(modelsMap, NewRelations())
+	if err != nil {
+		panic(err)
+	}
+	modelRelationsMap := utils.BuildRelationMap(relationRegistry)
 
 	c.DynamicColumnRepository = dynamiccolumn.NewDynamicColumnRepository(modelsMap, modelRelationsMap)
-	c.DynamicColumnService = dynamiccolumn.NewDynamicColumnService(c.DynamicColumnRepository, modelsMap, modelRelationsMap)
+	c.DynamicColumnService = dynamiccolumn.NewDynamicColumnService(c.DynamicColumnRepository, modelsMap, modelRelationsMap, relationRegistry)
+	c.DynamicColumnHandler = dynamiccolumn.NewDynamicColumnHandler(c.DynamicColumnService)
+	c.BackfillRepository = backfill.NewBackfillRepository()
+	c.BackfillService = backfill.NewBackfillService(c.BackfillRepository, c.DynamicColumnRepository, c.DynamicColumnService)
+	c.BackfillHandler = backfill.NewBackfillHandler(c.BackfillService)
+	c.DynamicColumnService.SetBackfillScheduler(c.BackfillService)
+	c.RefreshScheduleRepository = refreshschedule.NewRefreshScheduleRepository()
+	c.RefreshScheduleService = refreshschedule.NewRefreshScheduleService(c.RefreshScheduleRepository, c.DynamicColumnRepository, c.DynamicColumnService)
+	c.RefreshScheduleHandler = refreshschedule.NewRefreshScheduleHandler(c.RefreshScheduleService)
+	c.TransitionRepository = transition.NewTransitionRepository()
+	c.TransitionService = transition.NewTransitionService(c.TransitionRepository, c.DynamicColumnService)
+	c.TransitionHandler = transition.NewTransitionHandler(c.TransitionService)
+	c.OutboxRepository = outbox.NewOutboxRepository()
+	c.OutboxService = outbox.NewOutboxService(c.OutboxRepository, c.DynamicColumnService)
+	c.OutboxHandler = outbox.NewOutboxHandler(c.OutboxService)
+	c.DynamicColumnService.SetRefreshQueue(c.OutboxService)
+	c.ChangeCaptureRepository = changecapture.NewChangeCaptureRepository()
+	c.ChangeCaptureService = changecapture.NewChangeCaptureService(c.ChangeCaptureRepository, c.DynamicColumnRepository, c.DynamicColumnService)
+	c.ChangeCaptureHandler = changecapture.NewChangeCaptureHandler(c.ChangeCaptureService)
+	c.HistoryRepository = history.NewHistoryRepository()
+	c.HistoryService = history.NewHistoryService(c.HistoryRepository)
+	c.HistoryHandler = history.NewHistoryHandler(c.HistoryService)
+	c.DynamicColumnService.SetHistoryRecorder(c.HistoryService)
+	c.WebhookRepository = webhook.NewWebhookRepository()
+	c.WebhookService = webhook.NewWebhookService(c.WebhookRepository, c.DynamicColumnRepository)
+	c.WebhookHandler = webhook.NewWebhookHandler(c.WebhookService)
+	c.DynamicColumnService.SetChangePublisher(c.WebhookService)
 
 	// Invoice
-	c.InvoiceRepository = invoice.NewInvoiceRepository()
+	c.InvoiceRepository = invoice.NewInvoiceRepository(c.DynamicColumnRepository)
 	c.InvoiceService = invoice.NewInvoiceService(c.InvoiceRepository, c.DynamicColumnService)
 	c.InvoiceHandler = invoice.NewInvoiceHandler(c.InvoiceService)
 
 	// Approval
-	c.ApprovalRepository = approval.NewApprovalRepository()
+	c.ApprovalRepository = approval.NewApprovalRepository(c.DynamicColumnRepository)
 	c.ApprovalService = approval.NewApprovalService(c.ApprovalRepository, c.DynamicColumnService)
 	c.ApprovalHandler = approval.NewApprovalHandler(c.ApprovalService)
 
 	// Employee
-	c.EmployeeRepository = employee.NewEmployeeRepository()
+	c.EmployeeRepository = employee.NewEmployeeRepository(c.DynamicColumnRepository)
 	c.EmployeeService = employee.NewEmployeeService(c.EmployeeRepository, c.DynamicColumnService)
 	c.EmployeeHandler = employee.NewEmployeeHandler(c.EmployeeService)
 
 	// Deployment
-	c.DeploymentRepository = deployment.NewDeploymentRepository()
+	c.DeploymentRepository = deployment.NewDeploymentRepository(c.DynamicColumnRepository)
 	c.DeploymentService = deployment.NewDeploymentService(c.DeploymentRepository, c.DynamicColumnService)
 	c.DeploymentHandler = deployment.NewDeploymentHandler(c.DeploymentService)
 
 	// Contract
-	c.ContractRepository = contract.NewContractRepository()
+	c.ContractRepository = contract.NewContractRepository(c.DynamicColumnRepository)
 	c.ContractService = contract.NewContractService(c.ContractRepository, c.DynamicColumnService)
 	c.ContractHandler = contract.NewContractHandler(c.ContractService)
 
 	// Company
-	c.CompanyRepository = company.NewCompanyRepository()
+	c.CompanyRepository = company.NewCompanyRepository(c.DynamicColumnRepository)
 	c.CompanyService = company.NewCompanyService(c.CompanyRepository, c.DynamicColumnService)
 	c.CompanyHandler = company.NewCompanyHandler(c.CompanyService)
 
 	// Payment
-	c.PaymentRepository = payment.NewPaymentRepository()
+	c.PaymentRepository = payment.NewPaymentRepository(c.DynamicColumnRepository)
 	c.PaymentService = payment.NewPaymentService(c.PaymentRepository, c.DynamicColumnService)
 	c.PaymentHandler = payment.NewPaymentHandler(c.PaymentService)
 
@@ -0,0 +1,108 @@
+package container
+
+import (
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/utils"
+	"gin-demo/internal/system/dynamiccolumn"
+	"strings"
+	"testing"
+)
+
+func newTestDynamicColumnService(t *testing.T) dynamiccolumn.DynamicColumnService {
+	modelsMap := NewModelsMap()
+	registry, err := utils.BuildRelationRegistry(modelsMap, NewRelations())
+	if err != nil {
+		t.Fatalf("BuildRelationRegistry() returned %v", err)
+	}
+	return dynamiccolumn.NewDynamicColumnService(nil, modelsMap, utils.BuildRelationMap(registry), registry)
+}
+
+func TestRelationRegistryApprovalEmployees(t *testing.T) {
+	registry, err := utils.BuildRelationRegistry(NewModelsMap(), NewRelations())
+	if err != nil {
+		t.Fatalf("BuildRelationRegistry() returned %v", err)
+	}
+	relations := utils.FindRelations(registry, constants.TableNameApproval, constants.TableNameEmployee)
+	got := make([]string, 0, len(relations))
+	for _, relation := range relations {
+		got = append(got, relation.Name+":"+relation.Column)
+	}
+	want := "approver:approver_employee_id requester:requester_employee_id"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("relations of approval to employee = %v, want %s", got, want)
+	}
+}
+
+func TestBuildFormulaApprovalEmployees(t *testing.T) {
+	tests := []struct {
+		name     string
+		formula  string
+		wantJoin string
+		wantErr  []string
+	}{
+		{
+			name:     "approver",
+			formula:  "{{approval.approver}}.name",
+			wantJoin: "LEFT JOIN employee approval_approver ON approval.approver_employee_id = approval_approver.id",
+		},
+		{
+			name:     "requester",
+			formula:  "{{approval.requester}}.name",
+			wantJoin: "LEFT JOIN employee approval_requester ON approval.requester_employee_id = approval_requester.id",
+		},
+		{
+			name:     "via relation",
+			formula:  "{{employee via approval.requester}}.name",
+			wantJoin: "LEFT JOIN employee employee_via_approval_requester ON approval.requester_employee_id = employee_via_approval_requester.id",
+		},
+		{
+			name:    "ambiguous",
+			formula: "{{employee}}.name",
+			wantErr: []string{"approval -> employee (approval.approver)", "approval -> employee (approval.requester)"},
+		},
+	}
+	service := newTestDynamicColumnService(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.BuildFormula(&dynamiccolumn.DynamicColumnCreateRequest{
+				TableName: constants.TableNameApproval,
+				Name:      "employee_name",
+				Formula:   tt.formula,
+			})
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("BuildFormula(%q) = %q, want an error", tt.formula, got)
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Fatalf("BuildFormula(%q) error = %v, want it to list %q", tt.formula, err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildFormula(%q) returned %v", tt.formula, err)
+			}
+			if !strings.Contains(got, tt.wantJoin) {
+				t.Fatalf("BuildFormula(%q) = %s, want it to contain %q", tt.formula, got, tt.wantJoin)
+			}
+		})
+	}
+}
+
+func TestResolveTablesRelationLinkEmployeeApprovals(t *testing.T) {
+	service := newTestDynamicColumnService(t)
+
+	_, err := service.ResolveTablesRelationLink(constants.TableNameEmployee, constants.TableNameApproval, nil)
+	if err == nil || !strings.Contains(err.Error(), "approval.approver") || !strings.Contains(err.Error(), "approval.requester") {
+		t.Fatalf("ResolveTablesRelationLink(employee, approval) error = %v, want the approver and requester routes", err)
+	}
+
+	links, err := service.ResolveTablesRelationLink(constants.TableNameEmployee, constants.TableNameApproval, []constants.TableName{"approval.requester"})
+	if err != nil {
+		t.Fatalf("ResolveTablesRelationLink(employee, approval via approval.requester) returned %v", err)
+	}
+	if len(links) != 1 || links[0].Relation != constants.TableRelationOneToMany || links[0].Foreign.Column != "requester_employee_id" {
+		t.Fatalf("ResolveTablesRelationLink(employee, approval via approval.requester) = %+v, want one link on requester_employee_id", links)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"gin-demo/internal/application/config"
+	"gin-demo/internal/shared/constants"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -19,6 +20,17 @@ func DbMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// The triggers of trigger mode dynamic columns record the request id in the history of the values they change
+		if requestId, ok := c.Get(config.ContextKeyRequestId); ok {
+			err := tx.Exec("SELECT set_config(?, ?, true)", constants.HISTORY_REQUEST_ID_SETTING, requestId).Error
+			if err != nil {
+				tx.Rollback()
+				fmt.Println("Error setting request id:", err)
+				c.AbortWithStatusJSON(500, gin.H{"error": "database transaction error"})
+				return
+			}
+		}
+
 		// Store the transaction in both gin context and request context
 		c.Set(config.ContextKeyDB, tx)
 		ctx := c.Request.Context()
@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"gin-demo/internal/application/config"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A client id is recorded with the dynamic column history, anything else is replaced by a new id
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// RequestIdMiddleware identifies every request by the X-Request-Id header it came with, or by a new random id
+// when the header is missing or is not up to 64 letters, digits, '-' or '_', and echoes it in the response so a change recorded in the dynamic column history can be traced back to its request
+func RequestIdMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(config.RequestIdHeader)
+		if !requestIdPattern.MatchString(requestId) {
+			requestId = newRequestId()
+		}
+		c.Header(config.RequestIdHeader, requestId)
+
+		// Store the request id in both gin context and request context
+		c.Set(config.ContextKeyRequestId, requestId)
+		ctx := c.Request.Context()
+		ctx = context.WithValue(ctx, config.ContextKeyRequestId, requestId)
+		c.Request = c.Request.WithContext(ctx)
+
+		if logPayload, ok := ctx.Value(config.LogPayloadKey).(*config.LogPayload); ok {
+			(*logPayload)["request_id"] = requestId
+		}
+
+		c.Next()
+	}
+}
+
+// fallbackRequestIds numbers the ids generated when the random source fails, so two requests of the same instant differ
+var fallbackRequestIds atomic.Uint64
+
+// newRequestId returns a random id, or an id made of the current time and a sequence number when no random bytes can be read
+func newRequestId() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(fallbackRequestIds.Add(1), 36)
+	}
+	return hex.EncodeToString(bytes)
+}
@@ -21,7 +21,11 @@ func NewApprovalHandler(approvalService ApprovalService) ApprovalHandler {
 }
 
 func (h *approvalHandler) GetAll(c *gin.Context) {
-	entities := h.approvalService.GetAll(c.Request.Context())
+	entities, err := h.approvalService.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get approvals", err.Error()))
+		return
+	}
 	c.JSON(200, types.NewListResponse(entities, nil, ""))
 }
 
@@ -48,13 +52,13 @@ func (h *approvalHandler) Create(c *gin.Context) {
 		return
 	}
 
-	created, err := h.approvalService.Create(c.Request.Context(), &entity)
+	created, result, err := h.approvalService.Create(c.Request.Context(), &entity)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Internal Server Error", err.Error()))
 		return
 	}
 
-	c.JSON(201, types.NewSingleResponse[Approval](created, "Created successfully"))
+	c.JSON(201, types.NewSingleResponse[Approval](created, "Created successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *approvalHandler) Update(c *gin.Context) {
@@ -70,13 +74,13 @@ func (h *approvalHandler) Update(c *gin.Context) {
 		return
 	}
 
-	updated, err := h.approvalService.Update(c.Request.Context(), id, &updatePayload)
+	updated, result, err := h.approvalService.Update(c.Request.Context(), id, &updatePayload)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Internal Server Error", err.Error()))
 		return
 	}
 
-	c.JSON(200, types.NewSingleResponse[Approval](updated, "Updated successfully"))
+	c.JSON(200, types.NewSingleResponse[Approval](updated, "Updated successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *approvalHandler) Delete(c *gin.Context) {
@@ -86,11 +90,11 @@ func (h *approvalHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	err = h.approvalService.Delete(c.Request.Context(), id)
+	result, err := h.approvalService.Delete(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Internal Server Error", err.Error()))
 		return
 	}
 
-	c.JSON(200, types.NewSingleResponse[Approval](nil, "Deleted successfully"))
+	c.JSON(200, types.NewSingleResponse[Approval](nil, "Deleted successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
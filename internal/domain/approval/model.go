@@ -3,21 +3,55 @@ package approval
 import (
 	"gin-demo/internal/shared/constants"
 	"gin-demo/internal/shared/types"
+	"gin-demo/internal/system/dynamiccolumn"
 	"time"
 )
 
 type Approval struct {
 	types.GormModel
-	CompanyId    int64      `json:"company_id" gorm:"column:company_id" binding:"required"`
-	ApproverName string     `json:"approver_name" gorm:"column:approver_name" binding:"required"`
-	Status       string     `json:"status" gorm:"column:status;default:pending"` // pending, approved, rejected
-	Comments     string     `json:"comments" gorm:"column:comments"`
-	ReviewedAt   *time.Time `json:"reviewed_at" gorm:"column:reviewed_at"`
+	dynamiccolumn.ComputedValues
+	CompanyId           int64      `json:"company_id" gorm:"column:company_id" binding:"required"`
+	ApproverName        string     `json:"approver_name" gorm:"column:approver_name" binding:"required"`
+	ApproverEmployeeId  *int64     `json:"approver_employee_id" gorm:"column:approver_employee_id"`
+	RequesterEmployeeId *int64     `json:"requester_employee_id" gorm:"column:requester_employee_id"`
+	Status              string     `json:"status" gorm:"column:status;default:pending"` // pending, approved, rejected
+	Comments            string     `json:"comments" gorm:"column:comments"`
+	ReviewedAt          *time.Time `json:"reviewed_at" gorm:"column:reviewed_at"`
+}
+
+// Relations declares the foreign keys of the approval table
+var Relations = []types.Relation{
+	{
+		Name:        "company",
+		Table:       constants.TableNameApproval,
+		Column:      "company_id",
+		TargetTable: constants.TableNameCompany,
+		TargetKey:   "id",
+		Cardinality: constants.TableRelationManyToOne,
+	},
+	{
+		Name:        "approver",
+		Table:       constants.TableNameApproval,
+		Column:      "approver_employee_id",
+		TargetTable: constants.TableNameEmployee,
+		TargetKey:   "id",
+		Cardinality: constants.TableRelationManyToOne,
+	},
+	{
+		Name:        "requester",
+		Table:       constants.TableNameApproval,
+		Column:      "requester_employee_id",
+		TargetTable: constants.TableNameEmployee,
+		TargetKey:   "id",
+		Cardinality: constants.TableRelationManyToOne,
+	},
 }
 
 type ApprovalUpdateRequest struct {
-	ApproverName *string                   `json:"approver_name,omitempty"`
-	Status       *constants.ApprovalStatus `json:"status,omitempty"`
-	Comments     *string                   `json:"comments,omitempty"`
-	ReviewedAt   *time.Time                `json:"reviewed_at,omitempty"`
+	ApproverName        *string                   `json:"approver_name,omitempty"`
+	ApproverEmployeeId  *int64                    `json:"approver_employee_id,omitempty"`
+	RequesterEmployeeId *int64                    `json:"requester_employee_id,omitempty"`
+	Status              *constants.ApprovalStatus `json:"status,omitempty"`
+	Comments            *string                   `json:"comments,omitempty"`
+	ReviewedAt          *time.Time                `json:"reviewed_at,omitempty"`
 }
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn"
 )
 
 type ApprovalRepository interface {
 	GetById(ctx context.Context, id int64) (*Approval, error)
-	GetAll(ctx context.Context) []Approval
+	GetAll(ctx context.Context) ([]Approval, error)
 	Create(ctx context.Context, entity *Approval) (*Approval, error)
 	Update(ctx context.Context, id int64, updatePayload *ApprovalUpdateRequest) error
 	Delete(ctx context.Context, id int64) error
@@ -16,27 +18,32 @@ type ApprovalRepository interface {
 
 type approvalRepository struct {
 	base.BaseHelper
+	dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository
 }
 
-func NewApprovalRepository() ApprovalRepository {
-	return &approvalRepository{}
+func NewApprovalRepository(dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository) ApprovalRepository {
+	return &approvalRepository{dynamiccolumnRepo: dynamiccolumnRepo}
 }
 
+// GetById and GetAll read the view mode and virtual dynamic columns of approval through a join
 func (r *approvalRepository) GetById(ctx context.Context, id int64) (*Approval, error) {
 	tx := r.GetDbTx(ctx)
 	var entity Approval
-	err := tx.First(&entity, id).Error
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameApproval)).First(&entity, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &entity, nil
 }
 
-func (r *approvalRepository) GetAll(ctx context.Context) []Approval {
+func (r *approvalRepository) GetAll(ctx context.Context) ([]Approval, error) {
 	tx := r.GetDbTx(ctx)
 	var entities []Approval
-	tx.Find(&entities)
-	return entities
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameApproval)).Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
 }
 
 func (r *approvalRepository) Create(ctx context.Context, entity *Approval) (*Approval, error) {
@@ -7,11 +7,11 @@ import (
 )
 
 type ApprovalService interface {
-	GetAll(ctx context.Context) []Approval
+	GetAll(ctx context.Context) ([]Approval, error)
 	GetById(ctx context.Context, id int64) (*Approval, error)
-	Create(ctx context.Context, entity *Approval) (*Approval, error)
-	Update(ctx context.Context, id int64, updatePayload *ApprovalUpdateRequest) (*Approval, error)
-	Delete(ctx context.Context, id int64) error
+	Create(ctx context.Context, entity *Approval) (*Approval, *dynamiccolumn.RefreshResult, error)
+	Update(ctx context.Context, id int64, updatePayload *ApprovalUpdateRequest) (*Approval, *dynamiccolumn.RefreshResult, error)
+	Delete(ctx context.Context, id int64) (*dynamiccolumn.RefreshResult, error)
 }
 
 type approvalService struct {
@@ -26,7 +26,7 @@ func NewApprovalService(approvalRepo ApprovalRepository, dynamicColumnService dy
 	}
 }
 
-func (s *approvalService) GetAll(ctx context.Context) []Approval {
+func (s *approvalService) GetAll(ctx context.Context) ([]Approval, error) {
 	return s.approvalRepo.GetAll(ctx)
 }
 
@@ -34,65 +34,68 @@ func (s *approvalService) GetById(ctx context.Context, id int64) (*Approval, err
 	return s.approvalRepo.GetById(ctx, id)
 }
 
-func (s *approvalService) Create(ctx context.Context, entity *Approval) (*Approval, error) {
+func (s *approvalService) Create(ctx context.Context, entity *Approval) (*Approval, *dynamiccolumn.RefreshResult, error) {
 	entity, err := s.approvalRepo.Create(ctx, entity)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameApproval, []int64{entity.Id}, constants.ActionCreate, nil, entity)
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameApproval, []int64{entity.Id}, constants.ActionCreate, nil, entity)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record with dynamic columns
 	refreshedEntity, err := s.approvalRepo.GetById(ctx, entity.Id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return refreshedEntity, nil
+	return refreshedEntity, result, nil
 }
 
-func (s *approvalService) Update(ctx context.Context, id int64, updatePayload *ApprovalUpdateRequest) (*Approval, error) {
+func (s *approvalService) Update(ctx context.Context, id int64, updatePayload *ApprovalUpdateRequest) (*Approval, *dynamiccolumn.RefreshResult, error) {
 	originalEntity, err := s.approvalRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	err = s.approvalRepo.Update(ctx, id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameApproval, []int64{id}, constants.ActionUpdate, &originalEntity.Id, updatePayload)
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameApproval, []int64{id}, constants.ActionUpdate, &originalEntity.Id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record
 	refreshedEntity, err := s.approvalRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return refreshedEntity, nil
+	return refreshedEntity, result, nil
 }
 
-func (s *approvalService) Delete(ctx context.Context, id int64) error {
+func (s *approvalService) Delete(ctx context.Context, id int64) (*dynamiccolumn.RefreshResult, error) {
 	originalEntity, err := s.approvalRepo.GetById(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = s.approvalRepo.Delete(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Refresh dynamic columns after deletion
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameApproval, []int64{id}, constants.ActionDelete, &originalEntity.Id, nil)
-	return err
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameApproval, []int64{id}, constants.ActionDelete, &originalEntity.Id, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
@@ -21,7 +21,11 @@ func NewCompanyHandler(companyService CompanyService) CompanyHandler {
 }
 
 func (h *companyHandler) GetAll(c *gin.Context) {
-	companies := h.companyService.GetAllCompanies(c.Request.Context())
+	companies, err := h.companyService.GetAllCompanies(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get companies", err.Error()))
+		return
+	}
 	c.JSON(200, types.NewListResponse(companies, nil, ""))
 }
 
@@ -49,13 +53,13 @@ func (h *companyHandler) Create(c *gin.Context) {
 		return
 	}
 
-	createdCompany, err := h.companyService.Create(c.Request.Context(), &company)
+	createdCompany, result, err := h.companyService.Create(c.Request.Context(), &company)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Failed to create company", err.Error()))
 		return
 	}
 
-	c.JSON(201, types.NewSingleResponse(createdCompany, "Company created successfully"))
+	c.JSON(201, types.NewSingleResponse(createdCompany, "Company created successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *companyHandler) Update(c *gin.Context) {
@@ -72,13 +76,13 @@ func (h *companyHandler) Update(c *gin.Context) {
 		return
 	}
 
-	err = h.companyService.Update(c.Request.Context(), idInt64, &payload)
+	result, err := h.companyService.Update(c.Request.Context(), idInt64, &payload)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Failed to update company", err.Error()))
 		return
 	}
 
-	c.JSON(200, types.NewSingleResponse[Company](nil, "Company updated successfully"))
+	c.JSON(200, types.NewSingleResponse[Company](nil, "Company updated successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *companyHandler) Delete(c *gin.Context) {
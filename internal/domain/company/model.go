@@ -1,9 +1,13 @@
 package company
 
-import "gin-demo/internal/shared/types"
+import (
+	"gin-demo/internal/shared/types"
+	"gin-demo/internal/system/dynamiccolumn"
+)
 
 type Company struct {
 	types.GormModel
+	dynamiccolumn.ComputedValues
 	Name     string `json:"name" gorm:"column:name" binding:"required"`
 	IsActive bool   `json:"is_active" gorm:"column:is_active;default:true"`
 	Status   string `json:"status" gorm:"column:status"` // Approval Pending, Active, Inactive, At Risk, Suspended (dynamic)
@@ -3,11 +3,12 @@ package company
 import (
 	"context"
 	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
 	"gin-demo/internal/system/dynamiccolumn"
 )
 
 type CompanyRepository interface {
-	GetAll(ctx context.Context) []Company
+	GetAll(ctx context.Context) ([]Company, error)
 	GetById(ctx context.Context, id int64) (*Company, error)
 	Create(ctx context.Context, company *Company) (*Company, error)
 	Update(ctx context.Context, id int64, companyUpdate *CompanyUpdateRequest) error
@@ -18,15 +19,19 @@ type companyRepository struct {
 	dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository
 }
 
-func NewCompanyRepository() CompanyRepository {
-	return &companyRepository{}
+func NewCompanyRepository(dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository) CompanyRepository {
+	return &companyRepository{dynamiccolumnRepo: dynamiccolumnRepo}
 }
 
-func (r *companyRepository) GetAll(ctx context.Context) []Company {
+// GetAll and GetById read the view mode and virtual dynamic columns of company through a join
+func (r *companyRepository) GetAll(ctx context.Context) ([]Company, error) {
 	tx := r.GetDbTx(ctx)
 	var companies []Company
-	tx.Find(&companies)
-	return companies
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameCompany)).Find(&companies).Error
+	if err != nil {
+		return nil, err
+	}
+	return companies, nil
 }
 
 func (r *companyRepository) Create(ctx context.Context, company *Company) (*Company, error) {
@@ -43,7 +48,7 @@ func (r *companyRepository) Create(ctx context.Context, company *Company) (*Comp
 func (r *companyRepository) GetById(ctx context.Context, id int64) (*Company, error) {
 	tx := r.GetDbTx(ctx)
 	var company Company
-	err := tx.First(&company, id).Error
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameCompany)).First(&company, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -8,10 +8,10 @@ import (
 )
 
 type CompanyService interface {
-	GetAllCompanies(ctx context.Context) []Company
+	GetAllCompanies(ctx context.Context) ([]Company, error)
 	GetById(ctx context.Context, id int64) (*Company, error)
-	Create(ctx context.Context, company *Company) (*Company, error)
-	Update(ctx context.Context, id int64, companyUpdate *CompanyUpdateRequest) error
+	Create(ctx context.Context, company *Company) (*Company, *dynamiccolumn.RefreshResult, error)
+	Update(ctx context.Context, id int64, companyUpdate *CompanyUpdateRequest) (*dynamiccolumn.RefreshResult, error)
 }
 
 type companyService struct {
@@ -23,7 +23,7 @@ func NewCompanyService(companyRepo CompanyRepository, dynamiccolumnService dynam
 	return &companyService{companyRepo: companyRepo, dynamiccolumnService: dynamiccolumnService}
 }
 
-func (s *companyService) GetAllCompanies(ctx context.Context) []Company {
+func (s *companyService) GetAllCompanies(ctx context.Context) ([]Company, error) {
 	return s.companyRepo.GetAll(ctx)
 }
 
@@ -31,42 +31,46 @@ func (s *companyService) GetById(ctx context.Context, id int64) (*Company, error
 	return s.companyRepo.GetById(ctx, id)
 }
 
-func (s *companyService) Create(ctx context.Context, company *Company) (*Company, error) {
+func (s *companyService) Create(ctx context.Context, company *Company) (*Company, *dynamiccolumn.RefreshResult, error) {
 	createdCompany, err := s.companyRepo.Create(ctx, company)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns in database
-	err = s.dynamiccolumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameCompany, []int64{createdCompany.Id}, constants.ActionCreate, nil, company)
+	result, err := s.dynamiccolumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameCompany, []int64{createdCompany.Id}, constants.ActionCreate, nil, company)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record with computed dynamic columns
-	return s.companyRepo.GetById(ctx, createdCompany.Id)
+	refreshedCompany, err := s.companyRepo.GetById(ctx, createdCompany.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return refreshedCompany, result, nil
 }
 
-func (s *companyService) Update(ctx context.Context, id int64, companyUpdate *CompanyUpdateRequest) error {
+func (s *companyService) Update(ctx context.Context, id int64, companyUpdate *CompanyUpdateRequest) (*dynamiccolumn.RefreshResult, error) {
 	originalCompany, err := s.companyRepo.GetById(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if originalCompany == nil {
-		return errors.New("company not found")
+		return nil, errors.New("company not found")
 	}
 
 	err = s.companyRepo.Update(ctx, id, companyUpdate)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Refresh dynamic columns in database
-	err = s.dynamiccolumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameCompany, []int64{id}, constants.ActionUpdate, &originalCompany.Id, companyUpdate)
+	result, err := s.dynamiccolumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameCompany, []int64{id}, constants.ActionUpdate, &originalCompany.Id, companyUpdate)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return result, nil
 }
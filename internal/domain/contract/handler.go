@@ -21,7 +21,11 @@ func NewContractHandler(contractService ContractService) ContractHandler {
 }
 
 func (h *contractHandler) GetAll(c *gin.Context) {
-	entities := h.contractService.GetAll(c.Request.Context())
+	entities, err := h.contractService.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get contracts", err.Error()))
+		return
+	}
 	c.JSON(200, types.NewListResponse(entities, nil, ""))
 }
 
@@ -48,13 +52,13 @@ func (h *contractHandler) Create(c *gin.Context) {
 		return
 	}
 
-	created, err := h.contractService.Create(c.Request.Context(), &entity)
+	created, result, err := h.contractService.Create(c.Request.Context(), &entity)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Internal Server Error", err.Error()))
 		return
 	}
 
-	c.JSON(201, types.NewSingleResponse(created, "Created successfully"))
+	c.JSON(201, types.NewSingleResponse(created, "Created successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *contractHandler) Update(c *gin.Context) {
@@ -70,13 +74,13 @@ func (h *contractHandler) Update(c *gin.Context) {
 		return
 	}
 
-	updated, err := h.contractService.Update(c.Request.Context(), id, &updatePayload)
+	updated, result, err := h.contractService.Update(c.Request.Context(), id, &updatePayload)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Internal Server Error", err.Error()))
 		return
 	}
 
-	c.JSON(200, types.NewSingleResponse(updated, "Updated successfully"))
+	c.JSON(200, types.NewSingleResponse(updated, "Updated successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *contractHandler) Delete(c *gin.Context) {
@@ -86,11 +90,11 @@ func (h *contractHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	err = h.contractService.Delete(c.Request.Context(), id)
+	result, err := h.contractService.Delete(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Internal Server Error", err.Error()))
 		return
 	}
 
-	c.JSON(200, types.NewSingleResponse[Contract](nil, "Deleted successfully"))
+	c.JSON(200, types.NewSingleResponse[Contract](nil, "Deleted successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
@@ -1,12 +1,15 @@
 package contract
 
 import (
+	"gin-demo/internal/shared/constants"
 	"gin-demo/internal/shared/types"
+	"gin-demo/internal/system/dynamiccolumn"
 	"time"
 )
 
 type Contract struct {
 	types.GormModel
+	dynamiccolumn.ComputedValues
 	Name        string    `json:"name" gorm:"column:name" binding:"required"`
 	Description string    `json:"description" gorm:"column:description"`
 	CompanyId   int64     `json:"company_id" gorm:"column:company_id" binding:"required"`
@@ -17,6 +20,18 @@ type Contract struct {
 	EndDate     time.Time `json:"end_date" gorm:"column:end_date" binding:"required"`
 }
 
+// Relations declares the foreign keys of the contract table
+var Relations = []types.Relation{
+	{
+		Name:        "company",
+		Table:       constants.TableNameContract,
+		Column:      "company_id",
+		TargetTable: constants.TableNameCompany,
+		TargetKey:   "id",
+		Cardinality: constants.TableRelationManyToOne,
+	},
+}
+
 type ContractUpdateRequest struct {
 	Name        *string    `json:"name,omitempty"`
 	Description *string    `json:"description,omitempty"`
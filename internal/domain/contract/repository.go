@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn"
 )
 
 type ContractRepository interface {
 	GetById(ctx context.Context, id int64) (*Contract, error)
-	GetAll(ctx context.Context) []Contract
+	GetAll(ctx context.Context) ([]Contract, error)
 	Create(ctx context.Context, entity *Contract) (*Contract, error)
 	CreateMultiple(ctx context.Context, contracts []Contract) ([]Contract, error)
 	Update(ctx context.Context, id int64, updatePayload *ContractUpdateRequest) error
@@ -17,27 +19,32 @@ type ContractRepository interface {
 
 type contractRepository struct {
 	base.BaseHelper
+	dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository
 }
 
-func NewContractRepository() ContractRepository {
-	return &contractRepository{}
+func NewContractRepository(dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository) ContractRepository {
+	return &contractRepository{dynamiccolumnRepo: dynamiccolumnRepo}
 }
 
+// GetById and GetAll read the view mode and virtual dynamic columns of contract through a join
 func (r *contractRepository) GetById(ctx context.Context, id int64) (*Contract, error) {
 	tx := r.GetDbTx(ctx)
 	var entity Contract
-	err := tx.First(&entity, id).Error
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameContract)).First(&entity, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &entity, nil
 }
 
-func (r *contractRepository) GetAll(ctx context.Context) []Contract {
+func (r *contractRepository) GetAll(ctx context.Context) ([]Contract, error) {
 	tx := r.GetDbTx(ctx)
 	var entities []Contract
-	tx.Limit(100).Where("company_id = ?", 1).Find(&entities)
-	return entities
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameContract)).Limit(100).Where("company_id = ?", 1).Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
 }
 
 func (r *contractRepository) Create(ctx context.Context, entity *Contract) (*Contract, error) {
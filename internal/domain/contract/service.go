@@ -7,12 +7,12 @@ import (
 )
 
 type ContractService interface {
-	GetAll(ctx context.Context) []Contract
+	GetAll(ctx context.Context) ([]Contract, error)
 	GetById(ctx context.Context, id int64) (*Contract, error)
-	Create(ctx context.Context, entity *Contract) (*Contract, error)
+	Create(ctx context.Context, entity *Contract) (*Contract, *dynamiccolumn.RefreshResult, error)
 	CreateMultiple(ctx context.Context, contracts []Contract) ([]Contract, error)
-	Update(ctx context.Context, id int64, updatePayload *ContractUpdateRequest) (*Contract, error)
-	Delete(ctx context.Context, id int64) error
+	Update(ctx context.Context, id int64, updatePayload *ContractUpdateRequest) (*Contract, *dynamiccolumn.RefreshResult, error)
+	Delete(ctx context.Context, id int64) (*dynamiccolumn.RefreshResult, error)
 }
 
 type contractService struct {
@@ -27,7 +27,7 @@ func NewContractService(contractRepo ContractRepository, dynamicColumnService dy
 	}
 }
 
-func (s *contractService) GetAll(ctx context.Context) []Contract {
+func (s *contractService) GetAll(ctx context.Context) ([]Contract, error) {
 	return s.contractRepo.GetAll(ctx)
 }
 
@@ -35,67 +35,70 @@ func (s *contractService) GetById(ctx context.Context, id int64) (*Contract, err
 	return s.contractRepo.GetById(ctx, id)
 }
 
-func (s *contractService) Create(ctx context.Context, entity *Contract) (*Contract, error) {
+func (s *contractService) Create(ctx context.Context, entity *Contract) (*Contract, *dynamiccolumn.RefreshResult, error) {
 	entity, err := s.contractRepo.Create(ctx, entity)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameContract, []int64{entity.Id}, constants.ActionCreate, nil, entity)
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameContract, []int64{entity.Id}, constants.ActionCreate, nil, entity)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record with dynamic columns
 	refreshedEntity, err := s.contractRepo.GetById(ctx, entity.Id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return refreshedEntity, nil
+	return refreshedEntity, result, nil
 }
 
-func (s *contractService) Update(ctx context.Context, id int64, updatePayload *ContractUpdateRequest) (*Contract, error) {
+func (s *contractService) Update(ctx context.Context, id int64, updatePayload *ContractUpdateRequest) (*Contract, *dynamiccolumn.RefreshResult, error) {
 	originalEntity, err := s.contractRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	err = s.contractRepo.Update(ctx, id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameContract, []int64{id}, constants.ActionUpdate, &originalEntity.Id, updatePayload)
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameContract, []int64{id}, constants.ActionUpdate, &originalEntity.Id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record
 	refreshedEntity, err := s.contractRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return refreshedEntity, nil
+	return refreshedEntity, result, nil
 }
 
-func (s *contractService) Delete(ctx context.Context, id int64) error {
+func (s *contractService) Delete(ctx context.Context, id int64) (*dynamiccolumn.RefreshResult, error) {
 	originalEntity, err := s.contractRepo.GetById(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = s.contractRepo.Delete(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Refresh dynamic columns after deletion
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameContract, []int64{id}, constants.ActionDelete, &originalEntity.Id, nil)
-	return err
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameContract, []int64{id}, constants.ActionDelete, &originalEntity.Id, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func (s *contractService) CreateMultiple(ctx context.Context, contracts []Contract) ([]Contract, error) {
@@ -107,7 +110,7 @@ func (s *contractService) CreateMultiple(ctx context.Context, contracts []Contra
 	for _, contract := range createdContracts {
 		ids = append(ids, contract.Id)
 	}
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameContract, ids, constants.ActionCreate, nil, nil)
+	_, err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameContract, ids, constants.ActionCreate, nil, nil)
 	if err != nil {
 		return nil, err
 	}
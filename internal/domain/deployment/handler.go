@@ -21,7 +21,11 @@ func NewDeploymentHandler(deploymentService DeploymentService) DeploymentHandler
 }
 
 func (h *deploymentHandler) GetAll(c *gin.Context) {
-	entities := h.deploymentService.GetAll(c.Request.Context())
+	entities, err := h.deploymentService.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get deployments", err.Error()))
+		return
+	}
 	c.JSON(200, types.NewListResponse(entities, nil, ""))
 }
 
@@ -48,13 +52,13 @@ func (h *deploymentHandler) Create(c *gin.Context) {
 		return
 	}
 
-	created, err := h.deploymentService.Create(c.Request.Context(), &entity)
+	created, result, err := h.deploymentService.Create(c.Request.Context(), &entity)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Internal Server Error", err.Error()))
 		return
 	}
 
-	c.JSON(201, types.NewSingleResponse[Deployment](created, "Created successfully"))
+	c.JSON(201, types.NewSingleResponse[Deployment](created, "Created successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *deploymentHandler) Update(c *gin.Context) {
@@ -70,13 +74,13 @@ func (h *deploymentHandler) Update(c *gin.Context) {
 		return
 	}
 
-	updated, err := h.deploymentService.Update(c.Request.Context(), id, &updatePayload)
+	updated, result, err := h.deploymentService.Update(c.Request.Context(), id, &updatePayload)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Internal Server Error", err.Error()))
 		return
 	}
 
-	c.JSON(200, types.NewSingleResponse[Deployment](updated, "Updated successfully"))
+	c.JSON(200, types.NewSingleResponse[Deployment](updated, "Updated successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *deploymentHandler) Delete(c *gin.Context) {
@@ -86,11 +90,11 @@ func (h *deploymentHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	err = h.deploymentService.Delete(c.Request.Context(), id)
+	result, err := h.deploymentService.Delete(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Internal Server Error", err.Error()))
 		return
 	}
 
-	c.JSON(200, types.NewSingleResponse[Deployment](nil, "Deleted successfully"))
+	c.JSON(200, types.NewSingleResponse[Deployment](nil, "Deleted successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
@@ -1,12 +1,15 @@
 package deployment
 
 import (
+	"gin-demo/internal/shared/constants"
 	"gin-demo/internal/shared/types"
+	"gin-demo/internal/system/dynamiccolumn"
 	"time"
 )
 
 type Deployment struct {
 	types.GormModel
+	dynamiccolumn.ComputedValues
 	Name        string     `json:"name" gorm:"column:name" binding:"required"`
 	Description string     `json:"description" gorm:"column:description"`
 	ContractId  int64      `json:"contract_id" gorm:"column:contract_id" binding:"required"`
@@ -20,6 +23,26 @@ type Deployment struct {
 	CanStart    bool       `json:"can_start" gorm:"column:can_start;default:false"`
 }
 
+// Relations declares the foreign keys of the deployment table
+var Relations = []types.Relation{
+	{
+		Name:        "contract",
+		Table:       constants.TableNameDeployment,
+		Column:      "contract_id",
+		TargetTable: constants.TableNameContract,
+		TargetKey:   "id",
+		Cardinality: constants.TableRelationManyToOne,
+	},
+	{
+		Name:        "employee",
+		Table:       constants.TableNameDeployment,
+		Column:      "employee_id",
+		TargetTable: constants.TableNameEmployee,
+		TargetKey:   "id",
+		Cardinality: constants.TableRelationOneToOne,
+	},
+}
+
 type DeploymentUpdateRequest struct {
 	Name        *string    `json:"name,omitempty"`
 	Description *string    `json:"description,omitempty"`
@@ -3,11 +3,13 @@ package deployment
 import (
 	"context"
 	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn"
 )
 
 type DeploymentRepository interface {
 	GetById(ctx context.Context, id int64) (*Deployment, error)
-	GetAll(ctx context.Context) []Deployment
+	GetAll(ctx context.Context) ([]Deployment, error)
 	Create(ctx context.Context, entity *Deployment) (*Deployment, error)
 	Update(ctx context.Context, id int64, updatePayload *DeploymentUpdateRequest) error
 	Delete(ctx context.Context, id int64) error
@@ -15,27 +17,32 @@ type DeploymentRepository interface {
 
 type deploymentRepository struct {
 	base.BaseHelper
+	dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository
 }
 
-func NewDeploymentRepository() DeploymentRepository {
-	return &deploymentRepository{}
+func NewDeploymentRepository(dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository) DeploymentRepository {
+	return &deploymentRepository{dynamiccolumnRepo: dynamiccolumnRepo}
 }
 
+// GetById and GetAll read the view mode and virtual dynamic columns of deployment through a join
 func (r *deploymentRepository) GetById(ctx context.Context, id int64) (*Deployment, error) {
 	tx := r.GetDbTx(ctx)
 	var entity Deployment
-	err := tx.First(&entity, id).Error
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameDeployment)).First(&entity, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &entity, nil
 }
 
-func (r *deploymentRepository) GetAll(ctx context.Context) []Deployment {
+func (r *deploymentRepository) GetAll(ctx context.Context) ([]Deployment, error) {
 	tx := r.GetDbTx(ctx)
 	var entities []Deployment
-	tx.Find(&entities)
-	return entities
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameDeployment)).Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
 }
 
 func (r *deploymentRepository) Create(ctx context.Context, entity *Deployment) (*Deployment, error) {
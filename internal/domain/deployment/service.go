@@ -7,11 +7,11 @@ import (
 )
 
 type DeploymentService interface {
-	GetAll(ctx context.Context) []Deployment
+	GetAll(ctx context.Context) ([]Deployment, error)
 	GetById(ctx context.Context, id int64) (*Deployment, error)
-	Create(ctx context.Context, entity *Deployment) (*Deployment, error)
-	Update(ctx context.Context, id int64, updatePayload *DeploymentUpdateRequest) (*Deployment, error)
-	Delete(ctx context.Context, id int64) error
+	Create(ctx context.Context, entity *Deployment) (*Deployment, *dynamiccolumn.RefreshResult, error)
+	Update(ctx context.Context, id int64, updatePayload *DeploymentUpdateRequest) (*Deployment, *dynamiccolumn.RefreshResult, error)
+	Delete(ctx context.Context, id int64) (*dynamiccolumn.RefreshResult, error)
 }
 
 type deploymentService struct {
@@ -26,7 +26,7 @@ func NewDeploymentService(deploymentRepo DeploymentRepository, dynamicColumnServ
 	}
 }
 
-func (s *deploymentService) GetAll(ctx context.Context) []Deployment {
+func (s *deploymentService) GetAll(ctx context.Context) ([]Deployment, error) {
 	return s.deploymentRepo.GetAll(ctx)
 }
 
@@ -34,65 +34,68 @@ func (s *deploymentService) GetById(ctx context.Context, id int64) (*Deployment,
 	return s.deploymentRepo.GetById(ctx, id)
 }
 
-func (s *deploymentService) Create(ctx context.Context, entity *Deployment) (*Deployment, error) {
+func (s *deploymentService) Create(ctx context.Context, entity *Deployment) (*Deployment, *dynamiccolumn.RefreshResult, error) {
 	entity, err := s.deploymentRepo.Create(ctx, entity)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameDeployment, []int64{entity.Id}, constants.ActionCreate, nil, entity)
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameDeployment, []int64{entity.Id}, constants.ActionCreate, nil, entity)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record with dynamic columns
 	refreshedEntity, err := s.deploymentRepo.GetById(ctx, entity.Id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return refreshedEntity, nil
+	return refreshedEntity, result, nil
 }
 
-func (s *deploymentService) Update(ctx context.Context, id int64, updatePayload *DeploymentUpdateRequest) (*Deployment, error) {
+func (s *deploymentService) Update(ctx context.Context, id int64, updatePayload *DeploymentUpdateRequest) (*Deployment, *dynamiccolumn.RefreshResult, error) {
 	originalEntity, err := s.deploymentRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	err = s.deploymentRepo.Update(ctx, id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameDeployment, []int64{id}, constants.ActionUpdate, &originalEntity.Id, updatePayload)
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameDeployment, []int64{id}, constants.ActionUpdate, &originalEntity.Id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record
 	refreshedEntity, err := s.deploymentRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return refreshedEntity, nil
+	return refreshedEntity, result, nil
 }
 
-func (s *deploymentService) Delete(ctx context.Context, id int64) error {
+func (s *deploymentService) Delete(ctx context.Context, id int64) (*dynamiccolumn.RefreshResult, error) {
 	originalEntity, err := s.deploymentRepo.GetById(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = s.deploymentRepo.Delete(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Refresh dynamic columns after deletion
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameDeployment, []int64{id}, constants.ActionDelete, &originalEntity.Id, nil)
-	return err
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameDeployment, []int64{id}, constants.ActionDelete, &originalEntity.Id, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
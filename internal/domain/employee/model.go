@@ -1,9 +1,13 @@
 package employee
 
-import "gin-demo/internal/shared/types"
+import (
+	"gin-demo/internal/shared/types"
+	"gin-demo/internal/system/dynamiccolumn"
+)
 
 type Employee struct {
 	types.GormModel
+	dynamiccolumn.ComputedValues
 	Name     string `json:"name" gorm:"column:name" binding:"required"`
 	Email    string `json:"email" gorm:"column:email;uniqueIndex"`
 	Position string `json:"position" gorm:"column:position"`
@@ -3,11 +3,13 @@ package employee
 import (
 	"context"
 	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn"
 )
 
 type EmployeeRepository interface {
 	GetById(ctx context.Context, id int64) (*Employee, error)
-	GetAll(ctx context.Context) []Employee
+	GetAll(ctx context.Context) ([]Employee, error)
 	Create(ctx context.Context, entity *Employee) (*Employee, error)
 	Update(ctx context.Context, id int64, updatePayload *EmployeeUpdateRequest) error
 	Delete(ctx context.Context, id int64) error
@@ -15,27 +17,32 @@ type EmployeeRepository interface {
 
 type employeeRepository struct {
 	base.BaseHelper
+	dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository
 }
 
-func NewEmployeeRepository() EmployeeRepository {
-	return &employeeRepository{}
+func NewEmployeeRepository(dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository) EmployeeRepository {
+	return &employeeRepository{dynamiccolumnRepo: dynamiccolumnRepo}
 }
 
+// GetById and GetAll read the view mode and virtual dynamic columns of employee through a join
 func (r *employeeRepository) GetById(ctx context.Context, id int64) (*Employee, error) {
 	tx := r.GetDbTx(ctx)
 	var entity Employee
-	err := tx.First(&entity, id).Error
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameEmployee)).First(&entity, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &entity, nil
 }
 
-func (r *employeeRepository) GetAll(ctx context.Context) []Employee {
+func (r *employeeRepository) GetAll(ctx context.Context) ([]Employee, error) {
 	tx := r.GetDbTx(ctx)
 	var entities []Employee
-	tx.Find(&entities)
-	return entities
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameEmployee)).Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
 }
 
 func (r *employeeRepository) Create(ctx context.Context, entity *Employee) (*Employee, error) {
@@ -7,11 +7,11 @@ import (
 )
 
 type EmployeeService interface {
-	GetAll(ctx context.Context) []Employee
+	GetAll(ctx context.Context) ([]Employee, error)
 	GetById(ctx context.Context, id int64) (*Employee, error)
-	Create(ctx context.Context, entity *Employee) (*Employee, error)
-	Update(ctx context.Context, id int64, updatePayload *EmployeeUpdateRequest) (*Employee, error)
-	Delete(ctx context.Context, id int64) error
+	Create(ctx context.Context, entity *Employee) (*Employee, *dynamiccolumn.RefreshResult, error)
+	Update(ctx context.Context, id int64, updatePayload *EmployeeUpdateRequest) (*Employee, *dynamiccolumn.RefreshResult, error)
+	Delete(ctx context.Context, id int64) (*dynamiccolumn.RefreshResult, error)
 }
 
 type employeeService struct {
@@ -26,7 +26,7 @@ func NewEmployeeService(employeeRepo EmployeeRepository, dynamicColumnService dy
 	}
 }
 
-func (s *employeeService) GetAll(ctx context.Context) []Employee {
+func (s *employeeService) GetAll(ctx context.Context) ([]Employee, error) {
 	return s.employeeRepo.GetAll(ctx)
 }
 
@@ -34,65 +34,68 @@ func (s *employeeService) GetById(ctx context.Context, id int64) (*Employee, err
 	return s.employeeRepo.GetById(ctx, id)
 }
 
-func (s *employeeService) Create(ctx context.Context, entity *Employee) (*Employee, error) {
+func (s *employeeService) Create(ctx context.Context, entity *Employee) (*Employee, *dynamiccolumn.RefreshResult, error) {
 	entity, err := s.employeeRepo.Create(ctx, entity)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameEmployee, []int64{entity.Id}, constants.ActionCreate, nil, entity)
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameEmployee, []int64{entity.Id}, constants.ActionCreate, nil, entity)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record with dynamic columns
 	refreshedEntity, err := s.employeeRepo.GetById(ctx, entity.Id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return refreshedEntity, nil
+	return refreshedEntity, result, nil
 }
 
-func (s *employeeService) Update(ctx context.Context, id int64, updatePayload *EmployeeUpdateRequest) (*Employee, error) {
+func (s *employeeService) Update(ctx context.Context, id int64, updatePayload *EmployeeUpdateRequest) (*Employee, *dynamiccolumn.RefreshResult, error) {
 	originalEntity, err := s.employeeRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	err = s.employeeRepo.Update(ctx, id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameEmployee, []int64{id}, constants.ActionUpdate, &originalEntity.Id, updatePayload)
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameEmployee, []int64{id}, constants.ActionUpdate, &originalEntity.Id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record
 	refreshedEntity, err := s.employeeRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return refreshedEntity, nil
+	return refreshedEntity, result, nil
 }
 
-func (s *employeeService) Delete(ctx context.Context, id int64) error {
+func (s *employeeService) Delete(ctx context.Context, id int64) (*dynamiccolumn.RefreshResult, error) {
 	originalEntity, err := s.employeeRepo.GetById(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = s.employeeRepo.Delete(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Refresh dynamic columns after deletion
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameEmployee, []int64{id}, constants.ActionDelete, &originalEntity.Id, nil)
-	return err
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameEmployee, []int64{id}, constants.ActionDelete, &originalEntity.Id, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
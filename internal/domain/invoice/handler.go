@@ -21,7 +21,11 @@ func NewInvoiceHandler(invoiceService InvoiceService) InvoiceHandler {
 }
 
 func (h *invoiceHandler) GetAll(c *gin.Context) {
-	invoices := h.invoiceService.GetAll(c.Request.Context())
+	invoices, err := h.invoiceService.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get invoices", err.Error()))
+		return
+	}
 	c.JSON(200, types.NewListResponse(invoices, nil, ""))
 }
 
@@ -47,12 +51,12 @@ func (h *invoiceHandler) Create(c *gin.Context) {
 		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
 		return
 	}
-	invoice, err := h.invoiceService.Create(c.Request.Context(), &payload)
+	invoice, result, err := h.invoiceService.Create(c.Request.Context(), &payload)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Failed to create invoice", err.Error()))
 		return
 	}
-	c.JSON(200, types.NewSingleResponse(invoice, "Invoice created successfully"))
+	c.JSON(200, types.NewSingleResponse(invoice, "Invoice created successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *invoiceHandler) Update(c *gin.Context) {
@@ -68,12 +72,12 @@ func (h *invoiceHandler) Update(c *gin.Context) {
 		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
 		return
 	}
-	invoice, err := h.invoiceService.Update(c.Request.Context(), idInt64, &updatePayload)
+	invoice, result, err := h.invoiceService.Update(c.Request.Context(), idInt64, &updatePayload)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Failed to update invoice", err.Error()))
 		return
 	}
-	c.JSON(200, types.NewSingleResponse(invoice, "Invoice updated successfully"))
+	c.JSON(200, types.NewSingleResponse(invoice, "Invoice updated successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *invoiceHandler) Delete(c *gin.Context) {
@@ -83,10 +87,10 @@ func (h *invoiceHandler) Delete(c *gin.Context) {
 		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
 		return
 	}
-	err = h.invoiceService.Delete(c.Request.Context(), idInt64)
+	result, err := h.invoiceService.Delete(c.Request.Context(), idInt64)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Failed to delete invoice", err.Error()))
 		return
 	}
-	c.JSON(200, types.NewSingleResponse[Invoice](nil, "Invoice deleted successfully"))
+	c.JSON(200, types.NewSingleResponse[Invoice](nil, "Invoice deleted successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
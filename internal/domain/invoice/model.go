@@ -1,12 +1,15 @@
 package invoice
 
 import (
+	"gin-demo/internal/shared/constants"
 	"gin-demo/internal/shared/types"
+	"gin-demo/internal/system/dynamiccolumn"
 	"time"
 )
 
 type Invoice struct {
 	types.GormModel
+	dynamiccolumn.ComputedValues
 	InvoiceNumber string     `json:"invoice_number" gorm:"column:invoice_number;uniqueIndex"`
 	Description   string     `json:"description" gorm:"column:description"`
 	TotalAmount   float64    `json:"total_amount" gorm:"column:total_amount"`
@@ -18,6 +21,18 @@ type Invoice struct {
 	ContractId    int64      `json:"contract_id" gorm:"column:contract_id" binding:"required"`
 }
 
+// Relations declares the foreign keys of the invoice table
+var Relations = []types.Relation{
+	{
+		Name:        "contract",
+		Table:       constants.TableNameInvoice,
+		Column:      "contract_id",
+		TargetTable: constants.TableNameContract,
+		TargetKey:   "id",
+		Cardinality: constants.TableRelationManyToOne,
+	},
+}
+
 type InvoiceUpdateRequest struct {
 	InvoiceNumber *string    `json:"invoice_number,omitempty"`
 	Description   *string    `json:"description,omitempty"`
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn"
 )
 
 type InvoiceRepository interface {
 	GetById(ctx context.Context, id int64) (*Invoice, error)
-	GetAll(ctx context.Context) []Invoice
+	GetAll(ctx context.Context) ([]Invoice, error)
 	Create(ctx context.Context, entity *Invoice) (*Invoice, error)
 	Update(ctx context.Context, id int64, invoice *InvoiceUpdateRequest) error
 	Delete(ctx context.Context, id int64) error
@@ -18,27 +20,32 @@ type InvoiceRepository interface {
 
 type invoiceRepository struct {
 	base.BaseHelper
+	dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository
 }
 
-func NewInvoiceRepository() InvoiceRepository {
-	return &invoiceRepository{}
+func NewInvoiceRepository(dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository) InvoiceRepository {
+	return &invoiceRepository{dynamiccolumnRepo: dynamiccolumnRepo}
 }
 
+// GetById and GetAll read the view mode and virtual dynamic columns of invoice through a join
 func (r *invoiceRepository) GetById(ctx context.Context, id int64) (*Invoice, error) {
 	tx := r.GetDbTx(ctx)
 	var invoice Invoice
-	err := tx.First(&invoice, id).Error
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameInvoice)).First(&invoice, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &invoice, nil
 }
 
-func (r *invoiceRepository) GetAll(ctx context.Context) []Invoice {
+func (r *invoiceRepository) GetAll(ctx context.Context) ([]Invoice, error) {
 	tx := r.GetDbTx(ctx)
 	var invoices []Invoice
-	tx.Find(&invoices)
-	return invoices
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNameInvoice)).Find(&invoices).Error
+	if err != nil {
+		return nil, err
+	}
+	return invoices, nil
 }
 
 func (r *invoiceRepository) Create(ctx context.Context, invoice *Invoice) (*Invoice, error) {
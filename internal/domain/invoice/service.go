@@ -8,11 +8,11 @@ import (
 )
 
 type InvoiceService interface {
-	GetAll(ctx context.Context) []Invoice
+	GetAll(ctx context.Context) ([]Invoice, error)
 	GetById(ctx context.Context, id int64) (*Invoice, error)
-	Create(ctx context.Context, invoice *Invoice) (*Invoice, error)
-	Update(ctx context.Context, id int64, updatePayload *InvoiceUpdateRequest) (*Invoice, error)
-	Delete(ctx context.Context, id int64) error
+	Create(ctx context.Context, invoice *Invoice) (*Invoice, *dynamiccolumn.RefreshResult, error)
+	Update(ctx context.Context, id int64, updatePayload *InvoiceUpdateRequest) (*Invoice, *dynamiccolumn.RefreshResult, error)
+	Delete(ctx context.Context, id int64) (*dynamiccolumn.RefreshResult, error)
 	CreateMultiple(ctx context.Context, invoices []Invoice) ([]Invoice, error)
 }
 
@@ -25,7 +25,7 @@ func NewInvoiceService(invoiceRepo InvoiceRepository, dynamicColumnService dynam
 	return &invoiceService{invoiceRepo: invoiceRepo, dynamicColumnService: dynamicColumnService}
 }
 
-func (s *invoiceService) GetAll(ctx context.Context) []Invoice {
+func (s *invoiceService) GetAll(ctx context.Context) ([]Invoice, error) {
 	return s.invoiceRepo.GetAll(ctx)
 }
 
@@ -33,56 +33,59 @@ func (s *invoiceService) GetById(ctx context.Context, id int64) (*Invoice, error
 	return s.invoiceRepo.GetById(ctx, id)
 }
 
-func (s *invoiceService) Create(ctx context.Context, invoice *Invoice) (*Invoice, error) {
+func (s *invoiceService) Create(ctx context.Context, invoice *Invoice) (*Invoice, *dynamiccolumn.RefreshResult, error) {
 	invoice, err := s.invoiceRepo.Create(ctx, invoice)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameInvoice, []int64{invoice.Id}, constants.ActionCreate, nil, invoice)
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameInvoice, []int64{invoice.Id}, constants.ActionCreate, nil, invoice)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	refreshedInvoice, err := s.invoiceRepo.GetById(ctx, invoice.Id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return refreshedInvoice, nil
+	return refreshedInvoice, result, nil
 }
 
-func (s *invoiceService) Update(ctx context.Context, id int64, updatePayload *InvoiceUpdateRequest) (*Invoice, error) {
+func (s *invoiceService) Update(ctx context.Context, id int64, updatePayload *InvoiceUpdateRequest) (*Invoice, *dynamiccolumn.RefreshResult, error) {
 	originalInvoice, err := s.invoiceRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	err = s.invoiceRepo.Update(ctx, id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameInvoice, []int64{id}, constants.ActionUpdate, &originalInvoice.Id, updatePayload)
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameInvoice, []int64{id}, constants.ActionUpdate, &originalInvoice.Id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	refreshedInvoice, err := s.invoiceRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return refreshedInvoice, nil
+	return refreshedInvoice, result, nil
 }
 
-func (s *invoiceService) Delete(ctx context.Context, id int64) error {
+func (s *invoiceService) Delete(ctx context.Context, id int64) (*dynamiccolumn.RefreshResult, error) {
 	originalInvoice, err := s.invoiceRepo.GetById(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if originalInvoice == nil {
-		return errors.New("invoice not found")
+		return nil, errors.New("invoice not found")
 	}
 	err = s.invoiceRepo.Delete(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameInvoice, []int64{id}, constants.ActionDelete, &originalInvoice.Id, nil)
-	return err
+	result, err := s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameInvoice, []int64{id}, constants.ActionDelete, &originalInvoice.Id, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func (s *invoiceService) CreateMultiple(ctx context.Context, invoices []Invoice) ([]Invoice, error) {
@@ -94,11 +97,9 @@ func (s *invoiceService) CreateMultiple(ctx context.Context, invoices []Invoice)
 	for _, invoice := range createdInvoices {
 		ids = append(ids, invoice.Id)
 	}
-	err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameInvoice, ids, constants.ActionCreate, nil, nil)
+	_, err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNameInvoice, ids, constants.ActionCreate, nil, nil)
 	if err != nil {
 		return nil, err
 	}
-	refreshedInvoices := s.invoiceRepo.GetAll(ctx)
-
-	return refreshedInvoices, nil
+	return s.invoiceRepo.GetAll(ctx)
 }
@@ -21,7 +21,11 @@ func NewPaymentHandler(paymentService PaymentService) PaymentHandler {
 }
 
 func (h *paymentHandler) GetAll(c *gin.Context) {
-	entities := h.paymentService.GetAllPayments(c.Request.Context())
+	entities, err := h.paymentService.GetAllPayments(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get payments", err.Error()))
+		return
+	}
 	c.JSON(200, types.NewListResponse(entities, nil, ""))
 }
 
@@ -48,13 +52,13 @@ func (h *paymentHandler) Create(c *gin.Context) {
 		return
 	}
 
-	created, err := h.paymentService.Create(c.Request.Context(), &entity)
+	created, result, err := h.paymentService.Create(c.Request.Context(), &entity)
 	if err != nil {
 		c.JSON(500, types.NewErrorResponse("Failed to create payment", err.Error()))
 		return
 	}
 
-	c.JSON(201, types.NewSingleResponse(created, "Created successfully"))
+	c.JSON(201, types.NewSingleResponse(created, "Created successfully").WithChangedDynamicFields(result.ChangedDynamicFields()))
 }
 
 func (h *paymentHandler) Update(c *gin.Context) {
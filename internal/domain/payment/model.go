@@ -1,18 +1,33 @@
 package payment
 
 import (
+	"gin-demo/internal/shared/constants"
 	"gin-demo/internal/shared/types"
+	"gin-demo/internal/system/dynamiccolumn"
 	"time"
 )
 
 type Payment struct {
 	types.GormModel
+	dynamiccolumn.ComputedValues
 	Description string    `json:"description" gorm:"column:description"`
 	Amount      float64   `json:"amount" gorm:"column:amount" binding:"required"`
 	PaidAt      time.Time `json:"paid_at" gorm:"column:paid_at"`
 	InvoiceId   int64     `json:"invoice_id" gorm:"column:invoice_id" binding:"required"`
 }
 
+// Relations declares the foreign keys of the payment table
+var Relations = []types.Relation{
+	{
+		Name:        "invoice",
+		Table:       constants.TableNamePayment,
+		Column:      "invoice_id",
+		TargetTable: constants.TableNameInvoice,
+		TargetKey:   "id",
+		Cardinality: constants.TableRelationManyToOne,
+	},
+}
+
 type PaymentUpdateRequest struct {
 	Description *string    `json:"description,omitempty"`
 	PaidAt      *time.Time `json:"paid_at,omitempty"`
@@ -3,38 +3,45 @@ package payment
 import (
 	"context"
 	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn"
 )
 
 type PaymentRepository interface {
 	GetById(ctx context.Context, id int64) (*Payment, error)
-	GetAll(ctx context.Context) []Payment
+	GetAll(ctx context.Context) ([]Payment, error)
 	Create(ctx context.Context, entity *Payment) (*Payment, error)
 	Update(ctx context.Context, id int64, updatePayload *PaymentUpdateRequest) error
 }
 
 type paymentRepository struct {
 	base.BaseHelper
+	dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository
 }
 
-func NewPaymentRepository() PaymentRepository {
-	return &paymentRepository{}
+func NewPaymentRepository(dynamiccolumnRepo dynamiccolumn.DynamicColumnRepository) PaymentRepository {
+	return &paymentRepository{dynamiccolumnRepo: dynamiccolumnRepo}
 }
 
+// GetById and GetAll read the view mode and virtual dynamic columns of payment through a join
 func (r *paymentRepository) GetById(ctx context.Context, id int64) (*Payment, error) {
 	tx := r.GetDbTx(ctx)
 	var entity Payment
-	err := tx.First(&entity, id).Error
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNamePayment)).First(&entity, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &entity, nil
 }
 
-func (r *paymentRepository) GetAll(ctx context.Context) []Payment {
+func (r *paymentRepository) GetAll(ctx context.Context) ([]Payment, error) {
 	tx := r.GetDbTx(ctx)
 	var entities []Payment
-	tx.Find(&entities)
-	return entities
+	err := tx.Scopes(r.dynamiccolumnRepo.JoinComputedColumns(ctx, constants.TableNamePayment)).Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
 }
 
 func (r *paymentRepository) Create(ctx context.Context, entity *Payment) (*Payment, error) {
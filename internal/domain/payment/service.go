@@ -7,10 +7,10 @@ import (
 )
 
 type PaymentService interface {
-	GetAllPayments(ctx context.Context) []Payment
+	GetAllPayments(ctx context.Context) ([]Payment, error)
 	GetById(ctx context.Context, id int64) (*Payment, error)
-	Create(ctx context.Context, entity *Payment) (*Payment, error)
-	Update(ctx context.Context, id int64, updatePayload *PaymentUpdateRequest) (*Payment, error)
+	Create(ctx context.Context, entity *Payment) (*Payment, *dynamiccolumn.RefreshResult, error)
+	Update(ctx context.Context, id int64, updatePayload *PaymentUpdateRequest) (*Payment, *dynamiccolumn.RefreshResult, error)
 }
 
 type paymentService struct {
@@ -22,7 +22,7 @@ func NewPaymentService(paymentRepo PaymentRepository, dynamiccolumnService dynam
 	return &paymentService{paymentRepo: paymentRepo, dynamiccolumnService: dynamiccolumnService}
 }
 
-func (s *paymentService) GetAllPayments(ctx context.Context) []Payment {
+func (s *paymentService) GetAllPayments(ctx context.Context) ([]Payment, error) {
 	return s.paymentRepo.GetAll(ctx)
 }
 
@@ -30,39 +30,47 @@ func (s *paymentService) GetById(ctx context.Context, id int64) (*Payment, error
 	return s.paymentRepo.GetById(ctx, id)
 }
 
-func (s *paymentService) Create(ctx context.Context, entity *Payment) (*Payment, error) {
+func (s *paymentService) Create(ctx context.Context, entity *Payment) (*Payment, *dynamiccolumn.RefreshResult, error) {
 	created, err := s.paymentRepo.Create(ctx, entity)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns
-	err = s.dynamiccolumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNamePayment, []int64{created.Id}, constants.ActionCreate, nil, entity)
+	result, err := s.dynamiccolumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNamePayment, []int64{created.Id}, constants.ActionCreate, nil, entity)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record
-	return s.paymentRepo.GetById(ctx, created.Id)
+	refreshed, err := s.paymentRepo.GetById(ctx, created.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return refreshed, result, nil
 }
 
-func (s *paymentService) Update(ctx context.Context, id int64, updatePayload *PaymentUpdateRequest) (*Payment, error) {
+func (s *paymentService) Update(ctx context.Context, id int64, updatePayload *PaymentUpdateRequest) (*Payment, *dynamiccolumn.RefreshResult, error) {
 	original, err := s.paymentRepo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	err = s.paymentRepo.Update(ctx, id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Refresh dynamic columns
-	err = s.dynamiccolumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNamePayment, []int64{id}, constants.ActionUpdate, &original.Id, updatePayload)
+	result, err := s.dynamiccolumnService.RefreshDynamicColumnsOfRecordIds(ctx, constants.TableNamePayment, []int64{id}, constants.ActionUpdate, &original.Id, updatePayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Fetch updated record
-	return s.paymentRepo.GetById(ctx, id)
+	refreshed, err := s.paymentRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return refreshed, result, nil
 }
@@ -13,6 +13,18 @@ func (r *BaseHelper) GetDbTx(ctx context.Context) *gorm.DB {
 	return ctx.Value(config.ContextKeyDB).(*gorm.DB)
 }
 
+// GetRequestId returns the id of the request, empty outside of a request (CLI, workers)
+func (r *BaseHelper) GetRequestId(ctx context.Context) string {
+	requestId, _ := ctx.Value(config.ContextKeyRequestId).(string)
+	return requestId
+}
+
+// GetLogPayload returns the request log payload.
+// Outside of a request (CLI, workers) a throwaway payload is returned so callers can write to it safely.
 func (r *BaseHelper) GetLogPayload(ctx context.Context) *config.LogPayload {
-	return ctx.Value(config.LogPayloadKey).(*config.LogPayload)
+	logPayload, ok := ctx.Value(config.LogPayloadKey).(*config.LogPayload)
+	if !ok {
+		return &config.LogPayload{}
+	}
+	return logPayload
 }
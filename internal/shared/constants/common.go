@@ -7,6 +7,9 @@ const (
 	ActionUpdate  Action = "UPDATE"
 	ActionDelete  Action = "DELETE"
 	ActionRefresh Action = "REFRESH"
+
+	ActionCapture  Action = "CAPTURE"  // write made outside the application, notified by the change capture triggers
+	ActionDeferred Action = "DEFERRED" // change whose async dynamic columns are refreshed by the refresh outbox worker
 )
 
 type ApprovalStatus string
@@ -65,5 +68,70 @@ const (
 	TableRelationOneToMany  TableRelation = "one_to_many"
 	TableRelationManyToOne  TableRelation = "many_to_one"
 	TableRelationManyToMany TableRelation = "many_to_many"
+	TableRelationOneToOne   TableRelation = "one_to_one" // foreign key with a unique index, joined like many_to_one
 	TableRelationNotRelated TableRelation = "not_related"
 )
+
+type BackfillJobStatus string
+
+const (
+	BackfillJobStatusPending   BackfillJobStatus = "pending"
+	BackfillJobStatusRunning   BackfillJobStatus = "running"
+	BackfillJobStatusCompleted BackfillJobStatus = "completed"
+	BackfillJobStatusFailed    BackfillJobStatus = "failed"
+)
+
+type RefreshMode string
+
+const (
+	RefreshModeSync    RefreshMode = "sync"    // refreshed in the transaction of the change
+	RefreshModeAsync   RefreshMode = "async"   // refreshed by the worker draining the refresh outbox
+	RefreshModeTrigger RefreshMode = "trigger" // refreshed by statement level triggers inside Postgres, for every writer
+	RefreshModeView    RefreshMode = "view"    // served from a materialized view refreshed on a schedule, not stored in its table
+	RefreshModeVirtual RefreshMode = "virtual" // computed by the query reading its table, never stored nor refreshed
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "pending"
+	OutboxStatusProcessed OutboxStatus = "processed"
+	OutboxStatusFailed    OutboxStatus = "failed"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryStatusDead      WebhookDeliveryStatus = "dead" // out of attempts, kept in the dead-letter list until replayed
+)
+
+type RefreshRunStatus string
+
+const (
+	RefreshRunStatusRunning   RefreshRunStatus = "running"
+	RefreshRunStatusCompleted RefreshRunStatus = "completed"
+	RefreshRunStatusFailed    RefreshRunStatus = "failed"
+)
+
+type DynamicColumnType string
+
+const (
+	DynamicColumnTypeString    DynamicColumnType = "string"
+	DynamicColumnTypeInt       DynamicColumnType = "int"
+	DynamicColumnTypeFloat     DynamicColumnType = "float"
+	DynamicColumnTypeBool      DynamicColumnType = "bool"
+	DynamicColumnTypeDate      DynamicColumnType = "date"
+	DynamicColumnTypeTimestamp DynamicColumnType = "timestamp"
+)
+
+type DdlOperation string
+
+const (
+	DdlOperationAddColumn   DdlOperation = "add_column"
+	DdlOperationCreateIndex DdlOperation = "create_index"
+	DdlOperationDropColumn  DdlOperation = "drop_column"
+	DdlOperationCreateView  DdlOperation = "create_view"
+	DdlOperationDropView    DdlOperation = "drop_view"
+)
@@ -1,23 +1,232 @@
 package constants
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 const TEMP_TABLE_NAME = "tmp_dynamiccolumn_ids"
 
+// BACKFILL_CHUNK_SIZE is the default number of rows refreshed per backfill transaction
+const BACKFILL_CHUNK_SIZE = 10000
+
+// BACKFILL_SYNC_MAX_ROWS is the largest table a created or updated dynamic column is recomputed for in the request transaction,
+// larger tables are backfilled in the background
+const BACKFILL_SYNC_MAX_ROWS = 10000
+
+// BACKFILL_POLL_INTERVAL paces the server looking for pending backfill jobs
+const BACKFILL_POLL_INTERVAL = 10 * time.Second
+
+// DEFAULT_REFRESH_CRON is the schedule of time dependent dynamic columns declaring none, every day at midnight
+const DEFAULT_REFRESH_CRON = "0 0 * * *"
+
+// TRANSITION_TABLE_NAME is the side table holding the next instant each row of a time dependent dynamic column may change at
+const TRANSITION_TABLE_NAME = "dynamic_column_transition"
+
+// TRANSITION_POLL_INTERVAL and TRANSITION_BATCH_SIZE pace the worker refreshing the rows whose transition has passed
+const TRANSITION_POLL_INTERVAL = time.Minute
+const TRANSITION_BATCH_SIZE = 1000
+
+// OUTBOX_POLL_INTERVAL, OUTBOX_BATCH_SIZE and OUTBOX_MAX_ATTEMPTS pace the worker draining the refresh outbox.
+// A failed change is retried after OUTBOX_BACKOFF_BASE, doubled after every attempt up to OUTBOX_BACKOFF_MAX,
+// and is marked failed and left for a manual retry after OUTBOX_MAX_ATTEMPTS attempts.
+const OUTBOX_POLL_INTERVAL = 5 * time.Second
+const OUTBOX_BATCH_SIZE = 500
+const OUTBOX_MAX_ATTEMPTS = 5
+const OUTBOX_BACKOFF_BASE = 5 * time.Second
+const OUTBOX_BACKOFF_MAX = 5 * time.Minute
+
+// CAPTURE_CHANNEL is the channel the change capture triggers notify the writes made outside the application on.
+// CAPTURE_TRIGGER_NAME names the trigger on every watched table, its function is CAPTURE_TRIGGER_NAME_<table>.
+// CAPTURE_SETTING set to off on a session keeps its writes from being notified, the application connections set it
+// since the application refreshes its own writes.
+const CAPTURE_CHANNEL = "dynamic_column_change"
+const CAPTURE_TRIGGER_NAME = "dynamic_column_capture"
+const CAPTURE_SETTING = "dynamic_column.capture"
+
+// CAPTURE_FLUSH_INTERVAL and CAPTURE_BATCH_SIZE pace the listener refreshing the captured changes,
+// CAPTURE_RETRY_INTERVAL is the wait before listening again after the connection was lost
+const CAPTURE_FLUSH_INTERVAL = time.Second
+const CAPTURE_BATCH_SIZE = 1000
+const CAPTURE_RETRY_INTERVAL = 5 * time.Second
+
+// DB_TRIGGER_PREFIX names the triggers of trigger mode dynamic columns, DB_TRIGGER_PREFIX_<id>_<event> on every dependency,
+// and their functions, DB_TRIGGER_PREFIX_<id>_<table>_<event>.
+// DB_TRIGGER_NEW_TABLE and DB_TRIGGER_OLD_TABLE are the transition tables of the rows a statement wrote.
+const DB_TRIGGER_PREFIX = "dynamic_column_trigger"
+const DB_TRIGGER_NEW_TABLE = "dynamic_column_new"
+const DB_TRIGGER_OLD_TABLE = "dynamic_column_old"
+
+// HISTORY_TABLE_NAME records every value a refresh changed, with the change the refresh was caused by.
+// HISTORY_ORIGIN_IDS_LIMIT bounds the ids of the originating change stored with every value change, a backfill chunk holds thousands.
+// HISTORY_REQUEST_ID_SETTING holds the request id of the transaction, for the triggers of trigger mode columns to record.
+const HISTORY_TABLE_NAME = "dynamic_column_history"
+const HISTORY_ORIGIN_IDS_LIMIT = 100
+const HISTORY_REQUEST_ID_SETTING = "dynamic_column.request_id"
+
+// HISTORY_DEFAULT_LIMIT and HISTORY_MAX_LIMIT bound the number of history rows a query returns
+const HISTORY_DEFAULT_LIMIT = 100
+const HISTORY_MAX_LIMIT = 1000
+
+// WEBHOOK_SUBSCRIPTION_TABLE_NAME registers the webhooks notified of the changes of a column,
+// WEBHOOK_DELIVERY_TABLE_NAME is the outbox of their deliveries, written in the transaction of the refresh.
+// WEBHOOK_EVENT is the event of every payload.
+const WEBHOOK_SUBSCRIPTION_TABLE_NAME = "webhook_subscription"
+const WEBHOOK_DELIVERY_TABLE_NAME = "webhook_delivery"
+const WEBHOOK_EVENT = "dynamic_column.changed"
+
+// WEBHOOK_POLL_INTERVAL, WEBHOOK_BATCH_SIZE and WEBHOOK_TIMEOUT pace the worker delivering the webhooks.
+// A failed delivery is retried after WEBHOOK_BACKOFF_BASE, doubled after every attempt up to WEBHOOK_BACKOFF_MAX,
+// and moved to the dead-letter list after WEBHOOK_MAX_ATTEMPTS attempts.
+const WEBHOOK_POLL_INTERVAL = 5 * time.Second
+const WEBHOOK_BATCH_SIZE = 50
+const WEBHOOK_TIMEOUT = 10 * time.Second
+const WEBHOOK_BACKOFF_BASE = 30 * time.Second
+const WEBHOOK_BACKOFF_MAX = 6 * time.Hour
+const WEBHOOK_MAX_ATTEMPTS = 10
+
+// WEBHOOK_SIGNATURE_HEADER holds sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">, keyed by the secret of the subscription.
+// WEBHOOK_TIMESTAMP_HEADER holds the unix time the delivery was signed at, for receivers to reject replayed requests.
+const WEBHOOK_SIGNATURE_HEADER = "X-Webhook-Signature"
+const WEBHOOK_TIMESTAMP_HEADER = "X-Webhook-Timestamp"
+const WEBHOOK_DELIVERY_HEADER = "X-Webhook-Delivery"
+const WEBHOOK_EVENT_HEADER = "X-Webhook-Event"
+
+// REFRESH_RESULT_VALUES_LIMIT bounds the changed rows of a column whose old and new values a refresh result reports,
+// a column changing more rows reports their ids only
+const REFRESH_RESULT_VALUES_LIMIT = 20
+
+// VIEW_PREFIX names the materialized views of view mode dynamic columns, VIEW_PREFIX_<table>_<column>
+const VIEW_PREFIX = "dynamic_column_view"
+
+// PREVIEW_DEFAULT_LIMIT and PREVIEW_MAX_LIMIT bound the number of rows evaluated by a formula preview
+const PREVIEW_DEFAULT_LIMIT = 100
+const PREVIEW_MAX_LIMIT = 1000
+
+// MAX_CYCLE_ITERATIONS bounds the max_iterations a dynamic column may declare to take part in a dependency cycle
+const MAX_CYCLE_ITERATIONS = 10
+
+// DYNAMIC_COLUMN_SQL_TYPES maps a dynamic column type to the SQL type its formula result is cast to
+var DYNAMIC_COLUMN_SQL_TYPES = map[DynamicColumnType]string{
+	DynamicColumnTypeString:    "TEXT",
+	DynamicColumnTypeInt:       "BIGINT",
+	DynamicColumnTypeFloat:     "DOUBLE PRECISION",
+	DynamicColumnTypeBool:      "BOOLEAN",
+	DynamicColumnTypeDate:      "DATE",
+	DynamicColumnTypeTimestamp: "TIMESTAMP",
+}
+
+// DYNAMIC_COLUMN_DATA_TYPES lists the information_schema data types of the columns a dynamic column type can be stored in
+var DYNAMIC_COLUMN_DATA_TYPES = map[DynamicColumnType][]string{
+	DynamicColumnTypeString:    {"text", "character varying", "character"},
+	DynamicColumnTypeInt:       {"smallint", "integer", "bigint", "numeric"},
+	DynamicColumnTypeFloat:     {"real", "double precision", "numeric"},
+	DynamicColumnTypeBool:      {"boolean"},
+	DynamicColumnTypeDate:      {"date", "timestamp without time zone", "timestamp with time zone"},
+	DynamicColumnTypeTimestamp: {"timestamp without time zone", "timestamp with time zone"},
+}
+
+// FORMULA_TEMPLATE computes a formula for the rows of the temp ids table and updates the rows whose value changed.
+// The updated rows are returned with their old and new values as text, by FORMULA_CHANGES_SELECT, to be recorded in the history.
 var FORMULA_TEMPLATE = fmt.Sprintf(`
 WITH {{cte}}
 {{t_name}}_{{c_name}} AS (
     SELECT 
         {{t_name}}.id,
+        {{t_name}}.{{c_name}} AS dynamic_column_old_value,
         {{formula}} AS {{c_name}}
     FROM {{t_name}}
     JOIN %s tdi ON {{t_name}}.id = tdi.id
 	{{cte_joins}}
+),
+{{t_name}}_{{c_name}}_changes AS (
+    UPDATE {{t_name}}
+    SET {{c_name}} = ct.{{c_name}}
+    FROM {{t_name}}_{{c_name}} ct
+    WHERE {{t_name}}.id = ct.id AND {{t_name}}.{{c_name}} IS DISTINCT FROM ct.{{c_name}}
+    RETURNING {{t_name}}.id AS record_id, ct.dynamic_column_old_value::text AS old_value, ct.{{c_name}}::text AS new_value
 )
-UPDATE {{t_name}}
-SET {{c_name}} = ct.{{c_name}}
+%s
+`, TEMP_TABLE_NAME, FORMULA_CHANGES_SELECT)
+
+// FORMULA_CHANGES_SELECT ends FORMULA_TEMPLATE, the triggers of trigger mode columns replace it with an insert into the history
+const FORMULA_CHANGES_SELECT = "SELECT record_id, old_value, new_value FROM {{t_name}}_{{c_name}}_changes"
+
+// FORMULA_PREVIEW_TEMPLATE computes a formula like FORMULA_TEMPLATE but selects the current and new values instead of updating them.
+// {{current_value}} is the stored column, or NULL when the column does not exist yet.
+var FORMULA_PREVIEW_TEMPLATE = fmt.Sprintf(`
+WITH {{cte}}
+{{t_name}}_{{c_name}} AS (
+    SELECT 
+        {{t_name}}.id,
+        {{formula}} AS {{c_name}}
+    FROM {{t_name}}
+    JOIN %s tdi ON {{t_name}}.id = tdi.id
+	{{cte_joins}}
+)
+SELECT
+    {{t_name}}.id,
+    {{current_value}} AS current_value,
+    ct.{{c_name}} AS new_value,
+    {{current_value}} IS DISTINCT FROM ct.{{c_name}} AS changed
+FROM {{t_name}}
+JOIN {{t_name}}_{{c_name}} ct ON {{t_name}}.id = ct.id
+ORDER BY {{t_name}}.id
+`, TEMP_TABLE_NAME)
+
+// TRANSITION_TEMPLATE computes the next transition of the rows like FORMULA_TEMPLATE computes their value,
+// and stores it in the transition table. Rows without any upcoming transition are removed from the table.
+var TRANSITION_TEMPLATE = fmt.Sprintf(`
+WITH {{cte}}
+{{t_name}}_{{c_name}} AS (
+    SELECT 
+        {{t_name}}.id,
+        {{formula}} AS transition_at
+    FROM {{t_name}}
+    JOIN %s tdi ON {{t_name}}.id = tdi.id
+	{{cte_joins}}
+),
+{{t_name}}_{{c_name}}_passed AS (
+    DELETE FROM %s dct
+    USING {{t_name}}_{{c_name}} ct
+    WHERE dct.table_name = '{{t_name}}' AND dct.column_name = '{{c_name}}' AND dct.record_id = ct.id AND ct.transition_at IS NULL
+)
+INSERT INTO %s (table_name, column_name, record_id, transition_at)
+SELECT '{{t_name}}', '{{c_name}}', ct.id, ct.transition_at
 FROM {{t_name}}_{{c_name}} ct
-WHERE {{t_name}}.id = ct.id AND {{t_name}}.{{c_name}} IS DISTINCT FROM ct.{{c_name}}
+WHERE ct.transition_at IS NOT NULL
+ON CONFLICT (table_name, column_name, record_id) DO UPDATE SET transition_at = EXCLUDED.transition_at
+`, TEMP_TABLE_NAME, TRANSITION_TABLE_NAME, TRANSITION_TABLE_NAME)
+
+// VIEW_TEMPLATE computes a formula like FORMULA_TEMPLATE for every row of the table, it is the definition of the materialized view
+// of a view mode column. The CTEs are not joined to the temp ids table either, see buildFormulaFromTemplate.
+const VIEW_TEMPLATE = `
+WITH {{cte}}
+{{t_name}}_{{c_name}} AS (
+    SELECT 
+        {{t_name}}.id,
+        {{formula}} AS {{c_name}}
+    FROM {{t_name}}
+	{{cte_joins}}
+    WHERE {{t_name}}.is_deleted = false
+)
+SELECT id, {{c_name}} FROM {{t_name}}_{{c_name}}
+`
+
+// VIRTUAL_TEMPLATE computes a formula like VIEW_TEMPLATE for the rows of the temp ids table, it is the subquery a virtual column
+// is joined from when its table is read. The temp ids table is then replaced by the ids of the rows read, see JoinComputedColumns.
+var VIRTUAL_TEMPLATE = fmt.Sprintf(`
+WITH {{cte}}
+{{t_name}}_{{c_name}} AS (
+    SELECT 
+        {{t_name}}.id,
+        {{formula}} AS {{c_name}}
+    FROM {{t_name}}
+    JOIN %s tdi ON {{t_name}}.id = tdi.id
+	{{cte_joins}}
+    WHERE {{t_name}}.is_deleted = false
+)
+SELECT id, {{c_name}} FROM {{t_name}}_{{c_name}}
 `, TEMP_TABLE_NAME)
 
 const SAMPLE_VARIABLES_1 = `
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds the metrics of the refresh pipeline, with the Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+// ChangeRefreshes counts the refreshes of the dependants of a change, by changed table, action and outcome (ok or error)
+var ChangeRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "dynamic_column_change_refreshes_total",
+	Help: "Refreshes of the dynamic columns depending on a change, by changed table, action and outcome.",
+}, []string{"table", "action", "outcome"})
+
+// ChangeRefreshDuration times the refreshes of the dependants of a change, planning included
+var ChangeRefreshDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "dynamic_column_change_refresh_duration_seconds",
+	Help:    "Duration of the refreshes of the dynamic columns depending on a change, by changed table and action.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"table", "action"})
+
+// RefreshDuration times the formula of a dynamic column over the rows of the temp ids table, its count is the number of refreshes
+var RefreshDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "dynamic_column_refresh_duration_seconds",
+	Help:    "Duration of the formula of a dynamic column over the rows to refresh, by table and column.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"table", "column"})
+
+// RowsExamined and RowsUpdated count the rows a formula computed and the rows whose value it changed,
+// a column examining far more rows than it updates refreshes more rows than its changes need
+var RowsExamined = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "dynamic_column_rows_examined_total",
+	Help: "Rows computed by the formula of a dynamic column, by table and column.",
+}, []string{"table", "column"})
+
+var RowsUpdated = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "dynamic_column_rows_updated_total",
+	Help: "Rows whose value the formula of a dynamic column changed, by table and column.",
+}, []string{"table", "column"})
+
+// RefreshFailures counts the formulas that failed, by table and column
+var RefreshFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "dynamic_column_refresh_failures_total",
+	Help: "Failed formulas of dynamic columns, by table and column.",
+}, []string{"table", "column"})
+
+// TempTableCopyRows sizes the copies of ids to the temp ids table
+var TempTableCopyRows = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "dynamic_column_temp_table_copy_rows",
+	Help:    "Ids copied to the temp ids table at once.",
+	Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+})
+
+// SelectorDuration times the record selectors mapping changed rows to the rows depending on them
+var SelectorDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "dynamic_column_selector_duration_seconds",
+	Help:    "Duration of the record selector queries resolving the rows to refresh.",
+	Buckets: prometheus.DefBuckets,
+})
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ChangeRefreshes,
+		ChangeRefreshDuration,
+		RefreshDuration,
+		RowsExamined,
+		RowsUpdated,
+		RefreshFailures,
+		TempTableCopyRows,
+		SelectorDuration,
+	)
+}
+
+// Handler serves the metrics of Registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Since returns the seconds elapsed since start, for the histograms
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
@@ -5,3 +5,20 @@ import "gin-demo/internal/shared/constants"
 type ModelsMap map[constants.TableName]interface{}
 
 type ModelRelationsMap map[constants.TableRelation]map[constants.TableName][]constants.TableName
+
+// ChangedDynamicField is a dynamic column whose values a refresh changed, with the ids of the changed rows.
+// Values holds the old and new value of every changed row, in the order of ChangedIds, only when few rows changed.
+type ChangedDynamicField struct {
+	TableName    constants.TableName   `json:"table_name"`
+	Column       string                `json:"column"`
+	RowsAffected int                   `json:"rows_affected"`
+	ChangedIds   []int64               `json:"changed_ids"`
+	Values       []ChangedDynamicValue `json:"values,omitempty"`
+}
+
+// ChangedDynamicValue is the value of a dynamic column of a row before and after a refresh, as text
+type ChangedDynamicValue struct {
+	RecordId int64   `json:"record_id"`
+	OldValue *string `json:"old_value"`
+	NewValue *string `json:"new_value"`
+}
@@ -0,0 +1,18 @@
+package types
+
+import "gin-demo/internal/shared/constants"
+
+// Relation is a foreign key: Table.Column references TargetTable.TargetKey.
+// Domains declare their relations, a <table>_id column no declaration covers falls back to the naming convention.
+type Relation struct {
+	Name        string // used in formula placeholders, e.g. {{deployment.employee}}
+	Table       constants.TableName
+	Column      string
+	TargetTable constants.TableName
+	TargetKey   string
+	Cardinality constants.TableRelation // many_to_one, or one_to_one when the column is unique
+	Junction    bool                    // Table only links two tables, e.g. employee_skill(employee_id, skill_id), set on both of its relations
+}
+
+// RelationRegistry holds the relations of every table, sorted by table and name
+type RelationRegistry []Relation
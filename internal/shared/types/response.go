@@ -19,7 +19,8 @@ type BaseResponse struct {
 // SingleResponse for single item responses
 type SingleResponse[T any] struct {
 	BaseResponse
-	Data *T `json:"data,omitempty"`
+	Data                 *T                    `json:"data,omitempty"`
+	ChangedDynamicFields []ChangedDynamicField `json:"changed_dynamic_fields,omitempty"`
 }
 
 // ListResponse for list/collection responses
@@ -52,6 +53,12 @@ func NewSingleResponse[T any](data *T, message string) *SingleResponse[T] {
 	}
 }
 
+// WithChangedDynamicFields reports the dynamic column values changed by the write the response answers
+func (r *SingleResponse[T]) WithChangedDynamicFields(fields []ChangedDynamicField) *SingleResponse[T] {
+	r.ChangedDynamicFields = fields
+	return r
+}
+
 func NewListResponse[T any](data []T, pagination *Pagination, message string) ListResponse[T] {
 	return ListResponse[T]{
 		BaseResponse: BaseResponse{Success: true, Message: message},
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed standard cron expression: minute hour day-of-month month day-of-week
+type CronSchedule struct {
+	minutes  []bool
+	hours    []bool
+	days     []bool
+	months   []bool
+	weekdays []bool
+	// When both day fields are restricted a day matches either of them, like in crontab.
+	// A field starting with *, like */2, is not restricted.
+	anyDay     bool
+	anyWeekday bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a 5 field cron expression, or one of the @hourly, @daily, @weekly, @monthly, @yearly macros.
+// Every field accepts *, a value, a range a-b, steps */n or a-b/n, and comma separated lists of them.
+// Day of week is 0-7 where 0 and 7 are Sunday.
+func ParseCron(expr string) (*CronSchedule, error) {
+	if macro, exists := cronMacros[strings.ToLower(strings.TrimSpace(expr))]; exists {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+
+	schedule := &CronSchedule{anyDay: strings.HasPrefix(fields[2], "*"), anyWeekday: strings.HasPrefix(fields[4], "*")}
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron expression %q, minute: %w", expr, err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron expression %q, hour: %w", expr, err)
+	}
+	if schedule.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron expression %q, day of month: %w", expr, err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron expression %q, month: %w", expr, err)
+	}
+	if schedule.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron expression %q, day of week: %w", expr, err)
+	}
+	if schedule.weekdays[7] {
+		schedule.weekdays[0] = true
+	}
+	return schedule, nil
+}
+
+// parseCronField returns the allowed values of a field, indexed by value
+func parseCronField(field string, minValue int, maxValue int) ([]bool, error) {
+	allowed := make([]bool, maxValue+1)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		from, to := minValue, maxValue
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			from, err = strconv.Atoi(startPart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", startPart)
+			}
+			to = from
+			if isRange {
+				to, err = strconv.Atoi(endPart)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q", endPart)
+				}
+			} else if hasStep {
+				to = maxValue
+			}
+		}
+		if from < minValue || to > maxValue || from > to {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, minValue, maxValue)
+		}
+
+		for value := from; value <= to; value += step {
+			allowed[value] = true
+		}
+	}
+	return allowed, nil
+}
+
+// Matches reports whether the minute of t is an occurrence of the schedule
+func (s *CronSchedule) Matches(t time.Time) bool {
+	return s.minutes[t.Minute()] && s.hours[t.Hour()] && s.months[int(t.Month())] && s.matchesDay(t)
+}
+
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	day := s.days[t.Day()]
+	weekday := s.weekdays[int(t.Weekday())]
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// Next returns the first occurrence of the schedule strictly after t, or the zero time when there is none within 5 years
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(5, 0, 0)
+	for next.Before(limit) {
+		switch {
+		case !s.months[int(next.Month())]:
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+		case !s.matchesDay(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+		case !s.hours[next.Hour()]:
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+		case !s.minutes[next.Minute()]:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
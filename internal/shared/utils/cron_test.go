@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"too few fields", "0 0 * *"},
+		{"too many fields", "0 0 * * * *"},
+		{"unknown macro", "@often"},
+		{"minute out of range", "60 * * * *"},
+		{"hour out of range", "0 24 * * *"},
+		{"day of month zero", "0 0 0 * *"},
+		{"month out of range", "0 0 1 13 *"},
+		{"day of week out of range", "0 0 * * 8"},
+		{"reversed range", "0 0 * * 5-1"},
+		{"zero step", "*/0 * * * *"},
+		{"not a number", "a * * * *"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCron(tt.expr); err == nil {
+				t.Fatalf("ParseCron(%q) accepted an invalid expression", tt.expr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// Thursday
+	from := time.Date(2026, time.January, 1, 10, 30, 15, 0, time.UTC)
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2026, time.January, 1, 10, 31, 0, 0, time.UTC)},
+		{"step of minutes", "*/15 * * * *", time.Date(2026, time.January, 1, 10, 45, 0, 0, time.UTC)},
+		{"hourly macro", "@hourly", time.Date(2026, time.January, 1, 11, 0, 0, 0, time.UTC)},
+		{"daily macro", "@daily", time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"weekly macro", "@weekly", time.Date(2026, time.January, 4, 0, 0, 0, 0, time.UTC)},
+		{"monthly macro", "@monthly", time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"yearly macro", "@yearly", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"list and range", "0 8,12-14 * * *", time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)},
+		{"range with step", "0 9-17/4 * * *", time.Date(2026, time.January, 1, 13, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2026, time.January, 4, 0, 0, 0, 0, time.UTC)},
+		{"day of month and day of week", "0 0 15 * 1", time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		{"day of month with day of week step", "0 0 15 * */2", time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		{"day of month step with day of week", "0 0 */2 * 1", time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		{"february 29", "0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"never", "0 0 31 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) returned %v", tt.expr, err)
+			}
+			got := schedule.Next(from)
+			if !got.Equal(tt.want) {
+				t.Fatalf("Next(%s) of %q = %s, want %s", from, tt.expr, got, tt.want)
+			}
+			if !got.IsZero() && !schedule.Matches(got) {
+				t.Fatalf("Matches(%s) of %q = false", got, tt.expr)
+			}
+		})
+	}
+}
@@ -1,48 +1,237 @@
 package utils
 
 import (
+	"cmp"
+	"fmt"
 	"gin-demo/internal/shared/constants"
 	"gin-demo/internal/shared/types"
+	"slices"
 )
 
-type TableMapItem struct {
-	tableId string
-	model   interface{}
-}
-
-func BuildRelationMap(modelsMap types.ModelsMap) types.ModelRelationsMap {
-	result := types.ModelRelationsMap{
-		constants.TableRelationOneToMany: {},
-		constants.TableRelationManyToOne: {},
-	}
+/*
+* BuildRelationRegistry validates the declared relations and completes them with the relations of the <table>_id naming convention.
+* A convention relation is only added for a column no declared relation uses, so a declaration always wins.
+* Declared relations default to the target table as name, id as target key and many_to_one cardinality.
+* The relations of junction tables are marked, see markJunctionTables.
+ */
+func BuildRelationRegistry(modelsMap types.ModelsMap, declared []types.Relation) (types.RelationRegistry, error) {
+	result := make(types.RelationRegistry, 0, len(declared))
 
-	tablesMap := make(map[constants.TableName]TableMapItem)
+	for _, relation := range declared {
+		if relation.Name == "" {
+			relation.Name = string(relation.TargetTable)
+		}
+		if relation.TargetKey == "" {
+			relation.TargetKey = "id"
+		}
+		if relation.Cardinality == "" {
+			relation.Cardinality = constants.TableRelationManyToOne
+		}
 
-	for tableName, tableModel := range modelsMap {
-		tablesMap[tableName] = TableMapItem{
-			tableId: string(tableName) + "_id",
-			model:   tableModel,
+		model, exists := modelsMap[relation.Table]
+		if !exists {
+			return nil, fmt.Errorf("relation %s: unknown table %q", relation.Name, relation.Table)
 		}
+		if _, exists := FindFieldByGormColumn(model, relation.Column); !exists {
+			return nil, fmt.Errorf("relation %s.%s: unknown column %q", relation.Table, relation.Name, relation.Column)
+		}
+		targetModel, exists := modelsMap[relation.TargetTable]
+		if !exists {
+			return nil, fmt.Errorf("relation %s.%s: unknown target table %q", relation.Table, relation.Name, relation.TargetTable)
+		}
+		if _, exists := FindFieldByGormColumn(targetModel, relation.TargetKey); !exists {
+			return nil, fmt.Errorf("relation %s.%s: unknown target key %q", relation.Table, relation.Name, relation.TargetKey)
+		}
+		if relation.Cardinality != constants.TableRelationManyToOne && relation.Cardinality != constants.TableRelationOneToOne {
+			return nil, fmt.Errorf("relation %s.%s: cardinality must be %s or %s", relation.Table, relation.Name,
+				constants.TableRelationManyToOne, constants.TableRelationOneToOne)
+		}
+		if _, exists := FindRelationByName(result, relation.Table, relation.Name); exists {
+			return nil, fmt.Errorf("relation %s.%s is declared twice", relation.Table, relation.Name)
+		}
+		result = append(result, relation)
 	}
 
-	for tableName, tableMap := range tablesMap {
-		for relatedTableName, relatedTableMap := range tablesMap {
+	for tableName, tableModel := range modelsMap {
+		for relatedTableName := range modelsMap {
 			if tableName == relatedTableName {
 				continue
 			}
+			column := string(relatedTableName) + "_id"
+			if _, exists := FindFieldByGormColumn(tableModel, column); !exists {
+				continue
+			}
+			declared := slices.ContainsFunc(result, func(relation types.Relation) bool {
+				return relation.Table == tableName && relation.Column == column
+			})
+			if declared {
+				continue
+			}
+			if _, exists := FindRelationByName(result, tableName, string(relatedTableName)); exists {
+				continue
+			}
+			result = append(result, types.Relation{
+				Name:        string(relatedTableName),
+				Table:       tableName,
+				Column:      column,
+				TargetTable: relatedTableName,
+				TargetKey:   "id",
+				Cardinality: constants.TableRelationManyToOne,
+			})
+		}
+	}
+
+	// Sort relations so relation paths are resolved the same way on every start
+	slices.SortFunc(result, func(a, b types.Relation) int {
+		return cmp.Or(cmp.Compare(a.Table, b.Table), cmp.Compare(a.Name, b.Name))
+	})
+
+	err := markJunctionTables(modelsMap, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-			_, isOneToMany := FindFieldByGormColumn(relatedTableMap.model, tableMap.tableId)
-			if isOneToMany {
-				result[constants.TableRelationOneToMany][tableName] = append(result[constants.TableRelationOneToMany][tableName], relatedTableName)
+// junctionAuditColumns are the columns of types.GormModel a junction table may hold besides its two foreign keys
+var junctionAuditColumns = []string{"id", "created_at", "updated_at", "is_deleted"}
+
+/*
+* markJunctionTables marks the relations of the junction tables, the tables only linking two other tables.
+* A table is a junction table when its relations declare it, or when it relates two different tables
+* and holds no column but their foreign keys and the audit columns, e.g. employee_skill(id, employee_id, skill_id, created_at).
+* A table with columns of its own, like deployment, is an entity whose relations are joined one by one.
+ */
+func markJunctionTables(modelsMap types.ModelsMap, registry types.RelationRegistry) error {
+	for _, tableName := range relationTables(registry) {
+		indexes := make([]int, 0, 2)
+		declared := false
+		for i, relation := range registry {
+			if relation.Table == tableName {
+				indexes = append(indexes, i)
+				declared = declared || relation.Junction
 			}
+		}
+
+		linksTwoTables := len(indexes) == 2 &&
+			registry[indexes[0]].TargetTable != registry[indexes[1]].TargetTable &&
+			registry[indexes[0]].TargetTable != tableName && registry[indexes[1]].TargetTable != tableName
+		if declared && !linksTwoTables {
+			return fmt.Errorf("junction table %q must have exactly two relations, to two other tables", tableName)
+		}
+		if !linksTwoTables {
+			continue
+		}
+		if !declared {
+			allowed := append(slices.Clone(junctionAuditColumns), registry[indexes[0]].Column, registry[indexes[1]].Column)
+			declared = !slices.ContainsFunc(GormColumns(modelsMap[tableName]), func(column string) bool {
+				return !slices.Contains(allowed, column)
+			})
+		}
+		for _, i := range indexes {
+			registry[i].Junction = declared
+		}
+	}
+	return nil
+}
+
+// BuildRelationMap lists the related tables of every table and relation type from the relation registry
+func BuildRelationMap(registry types.RelationRegistry) types.ModelRelationsMap {
+	result := types.ModelRelationsMap{
+		constants.TableRelationOneToMany:  {},
+		constants.TableRelationManyToOne:  {},
+		constants.TableRelationManyToMany: {},
+	}
+
+	// One to one relations are joined like many to one relations, and the other way round like one to many relations
+	for _, relation := range registry {
+		if relation.Table == relation.TargetTable {
+			continue
+		}
+		manyToOne := result[constants.TableRelationManyToOne]
+		manyToOne[relation.Table] = AppendUnique(manyToOne[relation.Table], relation.TargetTable)
+		oneToMany := result[constants.TableRelationOneToMany]
+		oneToMany[relation.TargetTable] = AppendUnique(oneToMany[relation.TargetTable], relation.Table)
+	}
+
+	// A junction table relates the two tables it holds the keys of, e.g. employee_skill(employee_id, skill_id) relates employees and skills
+	for _, junctionName := range relationTables(registry) {
+		linked := junctionLinkedTables(registry, junctionName)
+		if len(linked) != 2 {
+			continue
+		}
+		m2m := result[constants.TableRelationManyToMany]
+		m2m[linked[0]] = AppendUnique(m2m[linked[0]], linked[1])
+		m2m[linked[1]] = AppendUnique(m2m[linked[1]], linked[0])
+	}
+
+	// Sort related tables so relation paths are resolved the same way on every start
+	for _, relations := range result {
+		for _, relatedTables := range relations {
+			slices.Sort(relatedTables)
+		}
+	}
+	return result
+}
+
+// relationTables returns the tables declaring at least one relation, sorted by name
+func relationTables(registry types.RelationRegistry) []constants.TableName {
+	tables := make([]constants.TableName, 0)
+	for _, relation := range registry {
+		tables = AppendUnique(tables, relation.Table)
+	}
+	slices.Sort(tables)
+	return tables
+}
 
-			_, isManyToOne := FindFieldByGormColumn(tableMap.model, relatedTableMap.tableId)
-			if isManyToOne {
-				result[constants.TableRelationManyToOne][tableName] = append(result[constants.TableRelationManyToOne][tableName], relatedTableName)
+// junctionLinkedTables returns the two tables a junction table relates, sorted by name, or nil when the table is not a junction table
+func junctionLinkedTables(registry types.RelationRegistry, tableName constants.TableName) []constants.TableName {
+	linked := make([]constants.TableName, 0)
+	for _, relation := range registry {
+		if relation.Table == tableName {
+			if !relation.Junction {
+				return nil
 			}
+			linked = append(linked, relation.TargetTable)
+		}
+	}
+	if len(linked) != 2 {
+		return nil
+	}
+	slices.Sort(linked)
+	return linked
+}
 
+// FindJunctionTables returns the junction tables relating two tables, sorted by name
+func FindJunctionTables(registry types.RelationRegistry, table constants.TableName, relatedTable constants.TableName) []constants.TableName {
+	result := make([]constants.TableName, 0)
+	for _, junctionName := range relationTables(registry) {
+		linked := junctionLinkedTables(registry, junctionName)
+		if slices.Equal(linked, []constants.TableName{min(table, relatedTable), max(table, relatedTable)}) {
+			result = append(result, junctionName)
 		}
+	}
+	return result
+}
 
+// FindRelations returns the relations of a table referencing the target table, sorted by name.
+// Two tables may be related by several foreign keys, e.g. the approver and the requester of an approval.
+func FindRelations(registry types.RelationRegistry, table constants.TableName, targetTable constants.TableName) []types.Relation {
+	result := make([]types.Relation, 0)
+	for _, relation := range registry {
+		if relation.Table == table && relation.TargetTable == targetTable {
+			result = append(result, relation)
+		}
 	}
 	return result
 }
+
+// FindRelationByName returns the relation of a table with the given name
+func FindRelationByName(registry types.RelationRegistry, table constants.TableName, name string) (types.Relation, bool) {
+	for _, relation := range registry {
+		if relation.Table == table && relation.Name == name {
+			return relation, true
+		}
+	}
+	return types.Relation{}, false
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/types"
+	"slices"
+	"testing"
+)
+
+type testEmployee struct {
+	types.GormModel
+	Name string `gorm:"column:name"`
+}
+
+type testSkill struct {
+	types.GormModel
+	Name string `gorm:"column:name"`
+}
+
+type testEmployeeSkill struct {
+	types.GormModel
+	EmployeeId int64 `gorm:"column:employee_id"`
+	SkillId    int64 `gorm:"column:skill_id"`
+}
+
+type testAssignment struct {
+	types.GormModel
+	EmployeeId int64  `gorm:"column:employee_id"`
+	SkillId    int64  `gorm:"column:skill_id"`
+	Status     string `gorm:"column:status"`
+}
+
+func TestBuildRelationMapJunctionTables(t *testing.T) {
+	tests := []struct {
+		name     string
+		junction any
+		declared []types.Relation
+		want     []constants.TableName
+	}{
+		{
+			name:     "only foreign keys and audit columns",
+			junction: testEmployeeSkill{},
+			want:     []constants.TableName{"skill"},
+		},
+		{
+			name:     "columns of its own",
+			junction: testAssignment{},
+			want:     nil,
+		},
+		{
+			name:     "declared junction",
+			junction: testAssignment{},
+			declared: []types.Relation{
+				{Table: "link", Column: "employee_id", TargetTable: "employee", Junction: true},
+				{Table: "link", Column: "skill_id", TargetTable: "skill", Junction: true},
+			},
+			want: []constants.TableName{"skill"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modelsMap := types.ModelsMap{"employee": testEmployee{}, "skill": testSkill{}, "link": tt.junction}
+			registry, err := BuildRelationRegistry(modelsMap, tt.declared)
+			if err != nil {
+				t.Fatalf("BuildRelationRegistry() returned %v", err)
+			}
+			got := BuildRelationMap(registry)[constants.TableRelationManyToMany]["employee"]
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("many to many relations of employee = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRelationRegistryInvalidJunction(t *testing.T) {
+	modelsMap := types.ModelsMap{"employee": testEmployee{}, "link": testEmployeeSkill{}}
+	declared := []types.Relation{{Table: "link", Column: "employee_id", TargetTable: "employee", Junction: true}}
+	if _, err := BuildRelationRegistry(modelsMap, declared); err == nil {
+		t.Fatal("BuildRelationRegistry() accepted a junction table with one relation")
+	}
+}
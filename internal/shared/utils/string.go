@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 )
@@ -165,6 +166,38 @@ func normalizeFormulaString(formula string) string {
 	return strings.TrimSpace(normalized)
 }
 
+// GormColumns lists the columns stored by a model, embedded models included.
+// Read only fields (gorm:"->") are computed by the query reading them and are skipped.
+func GormColumns(model any) []string {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	columns := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			columns = append(columns, GormColumns(reflect.New(field.Type).Elem().Interface())...)
+			continue
+		}
+
+		tags := strings.Split(field.Tag.Get("gorm"), ";")
+		if slices.Contains(tags, "->") {
+			continue
+		}
+		for _, tag := range tags {
+			if strings.HasPrefix(tag, "column:") {
+				columns = append(columns, strings.TrimPrefix(tag, "column:"))
+			}
+		}
+	}
+	return columns
+}
+
 func FindFieldByGormColumn(model any, column string) (reflect.StructField, bool) {
 	t := reflect.TypeOf(model)
 
@@ -179,6 +212,14 @@ func FindFieldByGormColumn(model any, column string) (reflect.StructField, bool)
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 
+		// Columns of embedded models like types.GormModel
+		if field.Anonymous {
+			if embedded, exists := FindFieldByGormColumn(reflect.New(field.Type).Elem().Interface(), column); exists {
+				return embedded, true
+			}
+			continue
+		}
+
 		gormTag := field.Tag.Get("gorm")
 		if gormTag == "" {
 			continue
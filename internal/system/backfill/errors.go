@@ -0,0 +1,6 @@
+package backfill
+
+import "errors"
+
+// ErrJobCompleted is returned when resuming a job that has nothing left to process
+var ErrJobCompleted = errors.New("backfill job is already completed")
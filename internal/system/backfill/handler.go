@@ -0,0 +1,89 @@
+package backfill
+
+import (
+	"errors"
+	"gin-demo/internal/shared/types"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type BackfillHandler interface {
+	GetAll(c *gin.Context)
+	GetById(c *gin.Context)
+	Create(c *gin.Context)
+	Resume(c *gin.Context)
+}
+
+type backfillHandler struct {
+	backfillService BackfillService
+}
+
+func NewBackfillHandler(backfillService BackfillService) BackfillHandler {
+	return &backfillHandler{backfillService: backfillService}
+}
+
+func (h *backfillHandler) GetAll(c *gin.Context) {
+	jobs := h.backfillService.GetAll(c.Request.Context())
+	res := make([]BackfillJobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		res = append(res, BackfillJobResponse{DynamicColumnBackfillJob: job, Progress: job.Progress()})
+	}
+	c.JSON(200, types.NewListResponse(res, nil, ""))
+}
+
+func (h *backfillHandler) GetById(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	job, err := h.backfillService.GetById(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(404, types.NewErrorResponse("Not found", err.Error()))
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(&BackfillJobResponse{DynamicColumnBackfillJob: *job, Progress: job.Progress()}, ""))
+}
+
+func (h *backfillHandler) Create(c *gin.Context) {
+	var payload BackfillJobCreateRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	job, err := h.backfillService.Enqueue(c.Request.Context(), &payload)
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to enqueue backfill job", err.Error()))
+		return
+	}
+
+	c.JSON(201, types.NewSingleResponse(job, "Backfill job enqueued successfully"))
+}
+
+func (h *backfillHandler) Resume(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	job, err := h.backfillService.Resume(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(404, types.NewErrorResponse("Not found", err.Error()))
+		case errors.Is(err, ErrJobCompleted):
+			c.JSON(409, types.NewErrorResponse("Failed to resume backfill job", err.Error()))
+		default:
+			c.JSON(500, types.NewErrorResponse("Failed to resume backfill job", err.Error()))
+		}
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(job, "Backfill job resumed successfully"))
+}
@@ -0,0 +1,46 @@
+package backfill
+
+import (
+	"gin-demo/internal/shared/constants"
+	"time"
+)
+
+// DynamicColumnBackfillJob tracks the chunked computation of a dynamic column over a whole table.
+// LastId is the checkpoint: every row with an id lower or equal to it has been refreshed.
+type DynamicColumnBackfillJob struct {
+	ID              int64                       `json:"id" gorm:"primaryKey;column:id"`
+	DynamicColumnId int64                       `json:"dynamic_column_id" gorm:"column:dynamic_column_id"`
+	TableName       constants.TableName         `json:"table_name" gorm:"column:table_name"`
+	ColumnName      string                      `json:"column_name" gorm:"column:column_name"`
+	Status          constants.BackfillJobStatus `json:"status" gorm:"column:status"`
+	ChunkSize       int                         `json:"chunk_size" gorm:"column:chunk_size"`
+	LastId          int64                       `json:"last_id" gorm:"column:last_id"`
+	TotalRows       int64                       `json:"total_rows" gorm:"column:total_rows"`
+	ProcessedRows   int64                       `json:"processed_rows" gorm:"column:processed_rows"`
+	Error           string                      `json:"error,omitempty" gorm:"column:error"`
+	StartedAt       *time.Time                  `json:"started_at" gorm:"column:started_at"`
+	CompletedAt     *time.Time                  `json:"completed_at" gorm:"column:completed_at"`
+	CreatedAt       time.Time                   `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt       time.Time                   `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// Progress returns the processed percentage of the job
+func (j *DynamicColumnBackfillJob) Progress() float64 {
+	if j.Status == constants.BackfillJobStatusCompleted {
+		return 100
+	}
+	if j.TotalRows == 0 {
+		return 0
+	}
+	return float64(j.ProcessedRows) * 100 / float64(j.TotalRows)
+}
+
+type BackfillJobResponse struct {
+	DynamicColumnBackfillJob
+	Progress float64 `json:"progress"`
+}
+
+type BackfillJobCreateRequest struct {
+	DynamicColumnId int64 `json:"dynamic_column_id" binding:"required"`
+	ChunkSize       int   `json:"chunk_size"`
+}
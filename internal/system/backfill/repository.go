@@ -0,0 +1,105 @@
+package backfill
+
+import (
+	"context"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+
+	"gorm.io/gorm/clause"
+)
+
+type BackfillRepository interface {
+	GetAll(ctx context.Context) []DynamicColumnBackfillJob
+	GetById(ctx context.Context, id int64) (*DynamicColumnBackfillJob, error)
+	GetByIdForUpdate(ctx context.Context, id int64) (*DynamicColumnBackfillJob, error)
+	GetResumable(ctx context.Context) []DynamicColumnBackfillJob
+	Create(ctx context.Context, job *DynamicColumnBackfillJob) (*DynamicColumnBackfillJob, error)
+	Update(ctx context.Context, job *DynamicColumnBackfillJob) error
+	CountRecords(ctx context.Context, table constants.TableName) (int64, error)
+	GetNextChunkIds(ctx context.Context, table constants.TableName, afterId int64, limit int) ([]int64, error)
+}
+
+type backfillRepository struct {
+	base.BaseHelper
+}
+
+func NewBackfillRepository() BackfillRepository {
+	return &backfillRepository{}
+}
+
+func (r *backfillRepository) GetAll(ctx context.Context) []DynamicColumnBackfillJob {
+	tx := r.GetDbTx(ctx)
+	var jobs []DynamicColumnBackfillJob
+	tx.Order("id DESC").Find(&jobs)
+	return jobs
+}
+
+func (r *backfillRepository) GetById(ctx context.Context, id int64) (*DynamicColumnBackfillJob, error) {
+	tx := r.GetDbTx(ctx)
+	var job DynamicColumnBackfillJob
+	err := tx.First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetByIdForUpdate locks the job row until the end of the transaction so two runners never process the same chunk
+func (r *backfillRepository) GetByIdForUpdate(ctx context.Context, id int64) (*DynamicColumnBackfillJob, error) {
+	tx := r.GetDbTx(ctx)
+	var job DynamicColumnBackfillJob
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetResumable returns pending jobs and jobs interrupted while running, oldest first
+func (r *backfillRepository) GetResumable(ctx context.Context) []DynamicColumnBackfillJob {
+	tx := r.GetDbTx(ctx)
+	var jobs []DynamicColumnBackfillJob
+	tx.Where("status IN ?", []constants.BackfillJobStatus{constants.BackfillJobStatusPending, constants.BackfillJobStatusRunning}).
+		Order("id").
+		Find(&jobs)
+	return jobs
+}
+
+func (r *backfillRepository) Create(ctx context.Context, job *DynamicColumnBackfillJob) (*DynamicColumnBackfillJob, error) {
+	tx := r.GetDbTx(ctx)
+	err := tx.Create(job).Error
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *backfillRepository) Update(ctx context.Context, job *DynamicColumnBackfillJob) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Save(job).Error
+}
+
+func (r *backfillRepository) CountRecords(ctx context.Context, table constants.TableName) (int64, error) {
+	tx := r.GetDbTx(ctx)
+	var count int64
+	err := tx.Table(string(table)).Where("is_deleted = false").Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetNextChunkIds returns at most limit ids of the table greater than afterId, in id order
+func (r *backfillRepository) GetNextChunkIds(ctx context.Context, table constants.TableName, afterId int64, limit int) ([]int64, error) {
+	tx := r.GetDbTx(ctx)
+	var ids []int64
+	err := tx.Table(string(table)).
+		Where("id > ? AND is_deleted = false", afterId).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,271 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type BackfillService interface {
+	GetAll(ctx context.Context) []DynamicColumnBackfillJob
+	GetById(ctx context.Context, id int64) (*DynamicColumnBackfillJob, error)
+	Enqueue(ctx context.Context, payload *BackfillJobCreateRequest) (*DynamicColumnBackfillJob, error)
+	ScheduleBackfill(ctx context.Context, col dynamiccolumn.DynamicColumn) error
+	Resume(ctx context.Context, id int64) (*DynamicColumnBackfillJob, error)
+	RunJob(ctx context.Context, id int64, onProgress func(job *DynamicColumnBackfillJob)) error
+	RunPendingJobs(ctx context.Context, onProgress func(job *DynamicColumnBackfillJob)) error
+	Run(ctx context.Context, interval time.Duration, logger *slog.Logger)
+}
+
+type backfillService struct {
+	backfillRepo         BackfillRepository
+	dynamicColumnRepo    dynamiccolumn.DynamicColumnRepository
+	dynamicColumnService dynamiccolumn.DynamicColumnService
+	base.BaseHelper
+}
+
+func NewBackfillService(
+	backfillRepo BackfillRepository,
+	dynamicColumnRepo dynamiccolumn.DynamicColumnRepository,
+	dynamicColumnService dynamiccolumn.DynamicColumnService,
+) BackfillService {
+	return &backfillService{
+		backfillRepo:         backfillRepo,
+		dynamicColumnRepo:    dynamicColumnRepo,
+		dynamicColumnService: dynamicColumnService,
+	}
+}
+
+func (s *backfillService) GetAll(ctx context.Context) []DynamicColumnBackfillJob {
+	return s.backfillRepo.GetAll(ctx)
+}
+
+func (s *backfillService) GetById(ctx context.Context, id int64) (*DynamicColumnBackfillJob, error) {
+	return s.backfillRepo.GetById(ctx, id)
+}
+
+func (s *backfillService) Enqueue(ctx context.Context, payload *BackfillJobCreateRequest) (*DynamicColumnBackfillJob, error) {
+	col, err := s.dynamicColumnRepo.GetById(ctx, payload.DynamicColumnId)
+	if err != nil {
+		return nil, err
+	}
+	return s.enqueue(ctx, *col, payload.ChunkSize)
+}
+
+// ScheduleBackfill implements dynamiccolumn.BackfillScheduler
+func (s *backfillService) ScheduleBackfill(ctx context.Context, col dynamiccolumn.DynamicColumn) error {
+	_, err := s.enqueue(ctx, col, 0)
+	return err
+}
+
+// enqueue creates a pending job for the column.
+// An unfinished job of the same column is restarted from the beginning instead of being duplicated.
+func (s *backfillService) enqueue(ctx context.Context, col dynamiccolumn.DynamicColumn, chunkSize int) (*DynamicColumnBackfillJob, error) {
+	if chunkSize <= 0 {
+		chunkSize = constants.BACKFILL_CHUNK_SIZE
+	}
+
+	for _, job := range s.backfillRepo.GetResumable(ctx) {
+		if job.DynamicColumnId != col.ID {
+			continue
+		}
+		job.Status = constants.BackfillJobStatusPending
+		job.ChunkSize = chunkSize
+		job.LastId = 0
+		job.ProcessedRows = 0
+		job.StartedAt = nil
+		job.Error = ""
+		err := s.backfillRepo.Update(ctx, &job)
+		if err != nil {
+			return nil, err
+		}
+		return &job, nil
+	}
+
+	return s.backfillRepo.Create(ctx, &DynamicColumnBackfillJob{
+		DynamicColumnId: col.ID,
+		TableName:       col.TableName,
+		ColumnName:      col.Name,
+		Status:          constants.BackfillJobStatusPending,
+		ChunkSize:       chunkSize,
+	})
+}
+
+// Resume puts a failed job back in the queue, keeping its checkpoint
+func (s *backfillService) Resume(ctx context.Context, id int64) (*DynamicColumnBackfillJob, error) {
+	job, err := s.backfillRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == constants.BackfillJobStatusCompleted {
+		return nil, fmt.Errorf("%w: backfill job %d", ErrJobCompleted, id)
+	}
+	job.Status = constants.BackfillJobStatusPending
+	job.Error = ""
+	err = s.backfillRepo.Update(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// RunPendingJobs runs every pending or interrupted job one after another.
+// ctx must carry the root database connection, not a request transaction.
+func (s *backfillService) RunPendingJobs(ctx context.Context, onProgress func(job *DynamicColumnBackfillJob)) error {
+	for _, job := range s.backfillRepo.GetResumable(ctx) {
+		err := s.RunJob(ctx, job.ID, onProgress)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+* Run runs the pending jobs every interval until ctx is done, so the columns created or updated on large tables
+* get their values without waiting for a manual run of cmd/backfill.
+* Several instances may run the same job, every chunk locks the job row and starts from its committed checkpoint.
+* ctx must carry the root database connection, not a request transaction.
+ */
+func (s *backfillService) Run(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.RunPendingJobs(ctx, func(job *DynamicColumnBackfillJob) {
+			if job.Status == constants.BackfillJobStatusCompleted {
+				logger.Info("dynamic column backfill completed", "job", job.ID, "table", job.TableName, "column", job.ColumnName, "rows", job.ProcessedRows)
+			}
+		})
+		if err != nil {
+			logger.Error("dynamic column backfill failed", "error", err.Error())
+		}
+	}
+}
+
+// RunJob processes a job chunk by chunk from its checkpoint until the table is exhausted.
+// Every chunk commits in its own transaction together with the new checkpoint,
+// so an interrupted run resumes from the last committed chunk.
+// ctx must carry the root database connection, not a request transaction.
+func (s *backfillService) RunJob(ctx context.Context, id int64, onProgress func(job *DynamicColumnBackfillJob)) error {
+	job, err := s.start(ctx, id)
+	if err != nil {
+		return err
+	}
+	if onProgress != nil {
+		onProgress(job)
+	}
+
+	for job.Status == constants.BackfillJobStatusRunning {
+		job, err = s.runChunk(ctx, id)
+		if err != nil {
+			return s.fail(ctx, id, err)
+		}
+		if onProgress != nil {
+			onProgress(job)
+		}
+	}
+	return nil
+}
+
+// start marks the job as running and counts the rows to process on the first run
+func (s *backfillService) start(ctx context.Context, id int64) (*DynamicColumnBackfillJob, error) {
+	job, err := s.backfillRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == constants.BackfillJobStatusCompleted {
+		return job, nil
+	}
+
+	if job.StartedAt == nil {
+		now := time.Now()
+		job.StartedAt = &now
+		total, err := s.backfillRepo.CountRecords(ctx, job.TableName)
+		if err != nil {
+			return nil, err
+		}
+		job.TotalRows = total
+	}
+	job.Status = constants.BackfillJobStatusRunning
+	job.Error = ""
+	err = s.backfillRepo.Update(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// runChunk refreshes the next chunk of ids after the checkpoint and moves the checkpoint forward in the same transaction
+func (s *backfillService) runChunk(ctx context.Context, id int64) (*DynamicColumnBackfillJob, error) {
+	var job *DynamicColumnBackfillJob
+	err := s.GetDbTx(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := context.WithValue(ctx, config.ContextKeyDB, tx)
+
+		var err error
+		job, err = s.backfillRepo.GetByIdForUpdate(txCtx, id)
+		if err != nil {
+			return err
+		}
+		if job.Status != constants.BackfillJobStatusRunning {
+			return nil
+		}
+
+		col, err := s.dynamicColumnRepo.GetById(txCtx, job.DynamicColumnId)
+		if err != nil {
+			return fmt.Errorf("dynamic column %d not found: %w", job.DynamicColumnId, err)
+		}
+
+		ids, err := s.backfillRepo.GetNextChunkIds(txCtx, job.TableName, job.LastId, job.ChunkSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			now := time.Now()
+			job.Status = constants.BackfillJobStatusCompleted
+			job.CompletedAt = &now
+			return s.backfillRepo.Update(txCtx, job)
+		}
+
+		err = s.dynamicColumnService.RefreshDynamicColumnOfRecordIds(txCtx, *col, ids)
+		if err != nil {
+			return err
+		}
+
+		job.LastId = ids[len(ids)-1]
+		job.ProcessedRows += int64(len(ids))
+		return s.backfillRepo.Update(txCtx, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// fail records the error on the job and returns it, together with the error recording it when the job is left running
+func (s *backfillService) fail(ctx context.Context, id int64, cause error) error {
+	job, err := s.backfillRepo.GetById(ctx, id)
+	if err != nil {
+		return errors.Join(cause, fmt.Errorf("recording the failure of backfill job %d: %w", id, err))
+	}
+	job.Status = constants.BackfillJobStatusFailed
+	job.Error = cause.Error()
+	err = s.backfillRepo.Update(ctx, job)
+	if err != nil {
+		return errors.Join(cause, fmt.Errorf("recording the failure of backfill job %d: %w", id, err))
+	}
+	return cause
+}
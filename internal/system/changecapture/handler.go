@@ -0,0 +1,45 @@
+package changecapture
+
+import (
+	"gin-demo/internal/shared/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChangeCaptureHandler interface {
+	GetAll(c *gin.Context)
+	Sync(c *gin.Context)
+}
+
+type changeCaptureHandler struct {
+	changeCaptureService ChangeCaptureService
+}
+
+func NewChangeCaptureHandler(changeCaptureService ChangeCaptureService) ChangeCaptureHandler {
+	return &changeCaptureHandler{changeCaptureService: changeCaptureService}
+}
+
+// GetAll lists the watched columns of every table next to the ones its installed trigger watches
+func (h *changeCaptureHandler) GetAll(c *gin.Context) {
+	triggers, err := h.changeCaptureService.GetTriggers(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get change capture triggers", err.Error()))
+		return
+	}
+	c.JSON(200, types.NewListResponse(triggers, nil, ""))
+}
+
+// Sync installs the missing triggers and replaces or drops the outdated ones
+func (h *changeCaptureHandler) Sync(c *gin.Context) {
+	err := h.changeCaptureService.SyncTriggers(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to sync change capture triggers", err.Error()))
+		return
+	}
+	triggers, err := h.changeCaptureService.GetTriggers(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get change capture triggers", err.Error()))
+		return
+	}
+	c.JSON(200, types.NewListResponse(triggers, nil, "Change capture triggers synced"))
+}
@@ -0,0 +1,33 @@
+package changecapture
+
+import "gin-demo/internal/shared/constants"
+
+// CaptureTrigger is the change capture trigger of a table.
+// Columns are the watched columns derived from the dependencies of the dynamic columns,
+// InstalledColumns the ones the trigger in the database watches.
+type CaptureTrigger struct {
+	TableName        constants.TableName `json:"table_name"`
+	Columns          []string            `json:"columns"`
+	InstalledColumns []string            `json:"installed_columns"`
+}
+
+// CapturedChange is the payload a trigger notifies for a row written outside the application
+type CapturedChange struct {
+	TableName constants.TableName `json:"table"`
+	Operation string              `json:"op"`
+	RecordId  int64               `json:"id"`
+	Columns   []string            `json:"columns"` // changed watched columns, every watched column on insert and delete
+}
+
+// installedTrigger is a change capture trigger read from the catalog, Columns is the comment of its function
+type installedTrigger struct {
+	TableName constants.TableName `gorm:"column:table_name"`
+	Columns   string              `gorm:"column:columns"`
+}
+
+// coalescedChange merges the captured changes of a table, every changed column is refreshed for every changed record
+type coalescedChange struct {
+	table     constants.TableName
+	recordIds []int64
+	columns   []string
+}
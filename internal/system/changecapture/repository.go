@@ -0,0 +1,127 @@
+package changecapture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"hash/fnv"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+type ChangeCaptureRepository interface {
+	Lock(ctx context.Context) error
+	GetInstalledTriggers(ctx context.Context) ([]installedTrigger, error)
+	ExecStatements(ctx context.Context, statements []string) error
+	Listen(ctx context.Context, flushInterval time.Duration, batchSize int, flush func(payloads []string) error) error
+}
+
+type changeCaptureRepository struct {
+	base.BaseHelper
+}
+
+func NewChangeCaptureRepository() ChangeCaptureRepository {
+	return &changeCaptureRepository{}
+}
+
+// Lock waits for the advisory lock of the change capture triggers until the end of the transaction,
+// so instances syncing at the same time replace the triggers one after the other
+func (r *changeCaptureRepository) Lock(ctx context.Context) error {
+	tx := r.GetDbTx(ctx)
+	hash := fnv.New64a()
+	hash.Write([]byte(constants.CAPTURE_TRIGGER_NAME))
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", int64(hash.Sum64())).Error
+}
+
+// GetInstalledTriggers returns the change capture triggers of the tables of the search path
+func (r *changeCaptureRepository) GetInstalledTriggers(ctx context.Context) ([]installedTrigger, error) {
+	tx := r.GetDbTx(ctx)
+	var triggers []installedTrigger
+	err := tx.Raw(`SELECT c.relname AS table_name, COALESCE(obj_description(p.oid, 'pg_proc'), '') AS columns
+		FROM pg_trigger t
+		JOIN pg_class c ON c.oid = t.tgrelid
+		JOIN pg_proc p ON p.oid = t.tgfoid
+		WHERE t.tgname = ? AND NOT t.tgisinternal AND pg_table_is_visible(c.oid)
+		ORDER BY c.relname`, constants.CAPTURE_TRIGGER_NAME).Scan(&triggers).Error
+	if err != nil {
+		return nil, err
+	}
+	return triggers, nil
+}
+
+func (r *changeCaptureRepository) ExecStatements(ctx context.Context, statements []string) error {
+	tx := r.GetDbTx(ctx)
+	for _, statement := range statements {
+		err := tx.Exec(statement).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+* Listen holds a connection of the pool listening on CAPTURE_CHANNEL until ctx is done or flush fails.
+* The payloads received are buffered and flushed once batchSize of them are buffered,
+* or flushInterval after the first of them was received.
+* ctx must carry the root database connection, not a request transaction.
+ */
+func (r *changeCaptureRepository) Listen(ctx context.Context, flushInterval time.Duration, batchSize int, flush func(payloads []string) error) error {
+	sqlDB, err := r.GetDbTx(ctx).DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("change capture needs a pgx connection, got %T", driverConn)
+		}
+		pgxConn := stdlibConn.Conn()
+		_, err := pgxConn.Exec(ctx, "LISTEN "+constants.CAPTURE_CHANNEL)
+		if err != nil {
+			return err
+		}
+		// The connection goes back to the pool, it must not keep receiving the notifications
+		defer pgxConn.Exec(context.Background(), "UNLISTEN "+constants.CAPTURE_CHANNEL)
+
+		payloads := make([]string, 0, batchSize)
+		var deadline time.Time
+		for {
+			waitCtx, cancel := ctx, context.CancelFunc(func() {})
+			if len(payloads) > 0 {
+				waitCtx, cancel = context.WithDeadline(ctx, deadline)
+			}
+			notification, err := pgxConn.WaitForNotification(waitCtx)
+			cancel()
+
+			switch {
+			case err == nil:
+				if len(payloads) == 0 {
+					deadline = time.Now().Add(flushInterval)
+				}
+				payloads = append(payloads, notification.Payload)
+				if len(payloads) < batchSize {
+					continue
+				}
+			case ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded):
+			default:
+				return err
+			}
+
+			err = flush(payloads)
+			if err != nil {
+				return err
+			}
+			payloads = payloads[:0]
+		}
+	})
+}
@@ -0,0 +1,237 @@
+package changecapture
+
+import (
+	"context"
+	"encoding/json"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/utils"
+	"gin-demo/internal/system/dynamiccolumn"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ChangeCaptureService interface {
+	GetTriggers(ctx context.Context) ([]CaptureTrigger, error)
+	SyncTriggers(ctx context.Context) error
+	DropTriggers(ctx context.Context) error
+	Run(ctx context.Context, logger *slog.Logger)
+}
+
+type changeCaptureService struct {
+	changeCaptureRepo    ChangeCaptureRepository
+	dynamicColumnRepo    dynamiccolumn.DynamicColumnRepository
+	dynamicColumnService dynamiccolumn.DynamicColumnService
+	base.BaseHelper
+}
+
+func NewChangeCaptureService(
+	changeCaptureRepo ChangeCaptureRepository,
+	dynamicColumnRepo dynamiccolumn.DynamicColumnRepository,
+	dynamicColumnService dynamiccolumn.DynamicColumnService,
+) ChangeCaptureService {
+	return &changeCaptureService{
+		changeCaptureRepo:    changeCaptureRepo,
+		dynamicColumnRepo:    dynamicColumnRepo,
+		dynamicColumnService: dynamicColumnService,
+	}
+}
+
+// GetTriggers lists the watched and the installed triggers, sorted by table
+func (s *changeCaptureService) GetTriggers(ctx context.Context) ([]CaptureTrigger, error) {
+	installed, err := s.changeCaptureRepo.GetInstalledTriggers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	watched, err := s.watchedColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byTable := make(map[constants.TableName]*CaptureTrigger)
+	for table, columns := range watched {
+		byTable[table] = &CaptureTrigger{TableName: table, Columns: columns, InstalledColumns: []string{}}
+	}
+	for _, trigger := range installed {
+		captureTrigger, exists := byTable[trigger.TableName]
+		if !exists {
+			captureTrigger = &CaptureTrigger{TableName: trigger.TableName, Columns: []string{}}
+			byTable[trigger.TableName] = captureTrigger
+		}
+		captureTrigger.InstalledColumns = strings.Split(trigger.Columns, ",")
+	}
+
+	result := make([]CaptureTrigger, 0, len(byTable))
+	for _, trigger := range byTable {
+		result = append(result, *trigger)
+	}
+	slices.SortFunc(result, func(a, b CaptureTrigger) int {
+		return strings.Compare(string(a.TableName), string(b.TableName))
+	})
+	return result, nil
+}
+
+// watchedColumns returns the columns of every table some dynamic column refreshed on writes depends on, sorted
+func (s *changeCaptureService) watchedColumns(ctx context.Context) (map[constants.TableName][]string, error) {
+	columns, err := s.dynamicColumnRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	watched := make(map[constants.TableName][]string)
+	for _, col := range columns {
+		// The view of a view mode column is refreshed on its schedule and a virtual column is computed when read,
+		// writes do not refresh them
+		if !col.IsStored() {
+			continue
+		}
+		for table, dependency := range col.Dependencies {
+			watched[table] = utils.AppendUnique(watched[table], dependency.Columns...)
+		}
+	}
+	for table := range watched {
+		slices.Sort(watched[table])
+	}
+	return watched, nil
+}
+
+/*
+* SyncTriggers makes the installed triggers match the dependencies of the dynamic columns:
+* the trigger of a table whose watched columns changed is replaced, the trigger of a table no longer watched is dropped.
+* It implements dynamiccolumn.ChangeCapture, the triggers change in the transaction of the definition.
+ */
+func (s *changeCaptureService) SyncTriggers(ctx context.Context) error {
+	return s.GetDbTx(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := context.WithValue(ctx, config.ContextKeyDB, tx)
+		err := s.changeCaptureRepo.Lock(txCtx)
+		if err != nil {
+			return err
+		}
+
+		triggers, err := s.GetTriggers(txCtx)
+		if err != nil {
+			return err
+		}
+		for _, trigger := range triggers {
+			if slices.Equal(trigger.Columns, trigger.InstalledColumns) {
+				continue
+			}
+			statements := buildDropStatements(trigger.TableName)
+			if len(trigger.Columns) > 0 {
+				statements = buildTriggerStatements(trigger.TableName, trigger.Columns)
+			}
+			err = s.changeCaptureRepo.ExecStatements(txCtx, statements)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DropTriggers removes every installed trigger, when the change capture is disabled
+func (s *changeCaptureService) DropTriggers(ctx context.Context) error {
+	return s.GetDbTx(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := context.WithValue(ctx, config.ContextKeyDB, tx)
+		err := s.changeCaptureRepo.Lock(txCtx)
+		if err != nil {
+			return err
+		}
+
+		installed, err := s.changeCaptureRepo.GetInstalledTriggers(txCtx)
+		if err != nil {
+			return err
+		}
+		for _, trigger := range installed {
+			err = s.changeCaptureRepo.ExecStatements(txCtx, buildDropStatements(trigger.TableName))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+/*
+* Run listens to the captured changes and refreshes their dependants until ctx is done,
+* listening again after CAPTURE_RETRY_INTERVAL when the connection is lost.
+* Notifications are not stored, a change made while no listener runs is not refreshed until the next schedule or backfill.
+* Hard deleted rows cannot be joined back to the rows depending on them, only the rows of their own table are refreshed.
+* ctx must carry the root database connection, not a request transaction.
+ */
+func (s *changeCaptureService) Run(ctx context.Context, logger *slog.Logger) {
+	for {
+		err := s.changeCaptureRepo.Listen(ctx, constants.CAPTURE_FLUSH_INTERVAL, constants.CAPTURE_BATCH_SIZE, func(payloads []string) error {
+			s.refreshChanges(ctx, payloads, logger)
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Error("dynamic column change capture stopped listening", "error", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(constants.CAPTURE_RETRY_INTERVAL):
+		}
+	}
+}
+
+// refreshChanges refreshes the dependants of the captured changes in one transaction.
+// Every table runs in its own savepoint, a failing table is logged without undoing the others.
+func (s *changeCaptureService) refreshChanges(ctx context.Context, payloads []string, logger *slog.Logger) {
+	changes := make([]CapturedChange, 0, len(payloads))
+	for _, payload := range payloads {
+		var change CapturedChange
+		err := json.Unmarshal([]byte(payload), &change)
+		if err != nil {
+			logger.Error("dynamic column change capture received an invalid payload", "payload", payload, "error", err.Error())
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	err := s.GetDbTx(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, change := range coalesce(changes) {
+			err := tx.Transaction(func(tableTx *gorm.DB) error {
+				tableCtx := context.WithValue(ctx, config.ContextKeyDB, tableTx)
+				return s.dynamicColumnService.RefreshCapturedChanges(tableCtx, change.table, change.recordIds, change.columns)
+			})
+			if err != nil {
+				logger.Error("dynamic column change capture refresh failed",
+					"table", change.table, "record_ids", len(change.recordIds), "error", err.Error())
+				continue
+			}
+			logger.Info("dynamic column change capture refreshed",
+				"table", change.table, "record_ids", len(change.recordIds), "columns", change.columns)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("dynamic column change capture refresh failed", "error", err.Error())
+	}
+}
+
+// coalesce merges the changes by table, in the order the tables were first changed
+func coalesce(changes []CapturedChange) []*coalescedChange {
+	result := make([]*coalescedChange, 0)
+	byTable := make(map[constants.TableName]*coalescedChange)
+	for _, change := range changes {
+		merged, exists := byTable[change.TableName]
+		if !exists {
+			merged = &coalescedChange{table: change.TableName}
+			byTable[change.TableName] = merged
+			result = append(result, merged)
+		}
+		merged.recordIds = utils.AppendUnique(merged.recordIds, change.RecordId)
+		merged.columns = utils.AppendUnique(merged.columns, change.Columns...)
+	}
+	return result
+}
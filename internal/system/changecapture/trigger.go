@@ -0,0 +1,77 @@
+package changecapture
+
+import (
+	"fmt"
+	"gin-demo/internal/shared/constants"
+	"strings"
+)
+
+// triggerFunctionName names the function of the change capture trigger of a table
+func triggerFunctionName(table constants.TableName) string {
+	return fmt.Sprintf("%s_%s", constants.CAPTURE_TRIGGER_NAME, table)
+}
+
+/*
+* buildTriggerStatements returns the statements installing, or replacing, the change capture trigger of a table.
+* The row trigger notifies the id of every inserted and deleted row, and of every updated row where a watched column changed.
+* It fires on UPDATE OF the watched columns only, so an update of other columns costs nothing.
+* Sessions setting CAPTURE_SETTING to off are skipped, the application refreshes its own writes.
+* The watched columns are kept in the comment of the function to tell whether the trigger is up to date.
+ */
+func buildTriggerStatements(table constants.TableName, columns []string) []string {
+	function := triggerFunctionName(table)
+	literals := make([]string, 0, len(columns))
+	for _, column := range columns {
+		literals = append(literals, fmt.Sprintf("'%s'", column))
+	}
+	allColumns := fmt.Sprintf("ARRAY[%s]::text[]", strings.Join(literals, ", "))
+
+	changedChecks := make([]string, 0, len(columns))
+	for _, column := range columns {
+		changedChecks = append(changedChecks, fmt.Sprintf(
+			"\t\tIF OLD.%[1]s IS DISTINCT FROM NEW.%[1]s THEN changed := changed || '%[1]s'::text; END IF;", column))
+	}
+
+	createFunction := fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s() RETURNS trigger AS $capture$
+DECLARE
+	changed text[] := ARRAY[]::text[];
+	record_id bigint;
+BEGIN
+	IF current_setting('%[2]s', true) = 'off' THEN
+		RETURN NULL;
+	END IF;
+	IF TG_OP = 'UPDATE' THEN
+%[3]s
+		IF cardinality(changed) = 0 THEN
+			RETURN NULL;
+		END IF;
+	ELSE
+		changed := %[4]s;
+	END IF;
+	IF TG_OP = 'DELETE' THEN
+		record_id := OLD.id;
+	ELSE
+		record_id := NEW.id;
+	END IF;
+	PERFORM pg_notify('%[5]s', json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'id', record_id, 'columns', changed)::text);
+	RETURN NULL;
+END
+$capture$ LANGUAGE plpgsql`,
+		function, constants.CAPTURE_SETTING, strings.Join(changedChecks, "\n"), allColumns, constants.CAPTURE_CHANNEL)
+
+	return []string{
+		createFunction,
+		fmt.Sprintf("COMMENT ON FUNCTION %s() IS '%s'", function, strings.Join(columns, ",")),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", constants.CAPTURE_TRIGGER_NAME, table),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT OR DELETE OR UPDATE OF %s ON %s FOR EACH ROW EXECUTE FUNCTION %s()",
+			constants.CAPTURE_TRIGGER_NAME, strings.Join(columns, ", "), table, function),
+	}
+}
+
+// buildDropStatements returns the statements removing the change capture trigger of a table
+func buildDropStatements(table constants.TableName) []string {
+	return []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", constants.CAPTURE_TRIGGER_NAME, table),
+		fmt.Sprintf("DROP FUNCTION IF EXISTS %s()", triggerFunctionName(table)),
+	}
+}
@@ -0,0 +1,85 @@
+package dynamiccolumn
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/internal/shared/constants"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// castFormula casts a compiled formula expression to the SQL type of the dynamic column type,
+// and falls back to the default value when the result is NULL, e.g. when a LEFT JOIN CTE has no row
+func castFormula(expr string, colType string, defaultValue string) (string, error) {
+	sqlType, exists := constants.DYNAMIC_COLUMN_SQL_TYPES[constants.DynamicColumnType(colType)]
+	if !exists {
+		return "", fmt.Errorf("unknown type %q, expected one of %s", colType, strings.Join(supportedTypes(), ", "))
+	}
+
+	res := fmt.Sprintf("CAST((%s) AS %s)", expr, sqlType)
+	if defaultValue == "" {
+		return res, nil
+	}
+	if err := checkDefaultValue(constants.DynamicColumnType(colType), defaultValue); err != nil {
+		return "", err
+	}
+	literal := "'" + strings.ReplaceAll(defaultValue, "'", "''") + "'"
+	return fmt.Sprintf("COALESCE(%s, CAST(%s AS %s))", res, literal, sqlType), nil
+}
+
+// checkDefaultValue verifies the default value can be cast to the type, so a bad default fails at definition time
+func checkDefaultValue(colType constants.DynamicColumnType, defaultValue string) error {
+	var err error
+	switch colType {
+	case constants.DynamicColumnTypeInt:
+		_, err = strconv.ParseInt(defaultValue, 10, 64)
+	case constants.DynamicColumnTypeFloat:
+		_, err = strconv.ParseFloat(defaultValue, 64)
+	case constants.DynamicColumnTypeBool:
+		_, err = strconv.ParseBool(defaultValue)
+	case constants.DynamicColumnTypeDate:
+		_, err = time.Parse(time.DateOnly, defaultValue)
+	case constants.DynamicColumnTypeTimestamp:
+		_, err = time.Parse(time.DateTime, defaultValue)
+		if err != nil {
+			_, err = time.Parse(time.RFC3339, defaultValue)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("default value %q is not a valid %s", defaultValue, colType)
+	}
+	return nil
+}
+
+func supportedTypes() []string {
+	types := make([]string, 0, len(constants.DYNAMIC_COLUMN_SQL_TYPES))
+	for colType := range constants.DYNAMIC_COLUMN_SQL_TYPES {
+		types = append(types, string(colType))
+	}
+	slices.Sort(types)
+	return types
+}
+
+// checkColumnType verifies the target column exists and its Postgres type can store the declared type.
+// A view mode or a virtual column is not stored, its table must not have a column of the same name.
+func (r *dynamicColumnService) checkColumnType(ctx context.Context, col *DynamicColumn) error {
+	dataType, err := r.dynamicColumnRepo.GetColumnDataType(ctx, col.TableName, col.Name)
+	if err != nil {
+		return err
+	}
+	if !col.IsStored() {
+		if dataType != "" {
+			return fmt.Errorf("%w: column %s.%s exists, a %s column is not stored in its table", ErrInvalidFormula, col.TableName, col.Name, col.RefreshMode)
+		}
+		return nil
+	}
+	if dataType == "" {
+		return fmt.Errorf("%w: column %s.%s does not exist", ErrInvalidFormula, col.TableName, col.Name)
+	}
+	if !slices.Contains(constants.DYNAMIC_COLUMN_DATA_TYPES[constants.DynamicColumnType(col.Type)], dataType) {
+		return fmt.Errorf("%w: type %s cannot be stored in column %s.%s of type %s", ErrInvalidFormula, col.Type, col.TableName, col.Name, dataType)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package dynamiccolumn
+
+import (
+	"gin-demo/internal/shared/constants"
+	"testing"
+)
+
+func TestCheckDefaultValue(t *testing.T) {
+	tests := []struct {
+		colType      constants.DynamicColumnType
+		defaultValue string
+		wantErr      bool
+	}{
+		{constants.DynamicColumnTypeString, "Pending", false},
+		{constants.DynamicColumnTypeString, "it's 42", false},
+		{constants.DynamicColumnTypeInt, "42", false},
+		{constants.DynamicColumnTypeInt, "-7", false},
+		{constants.DynamicColumnTypeInt, "4.2", true},
+		{constants.DynamicColumnTypeInt, "many", true},
+		{constants.DynamicColumnTypeFloat, "4.2", false},
+		{constants.DynamicColumnTypeFloat, "1e3", false},
+		{constants.DynamicColumnTypeFloat, "4,2", true},
+		{constants.DynamicColumnTypeBool, "true", false},
+		{constants.DynamicColumnTypeBool, "0", false},
+		{constants.DynamicColumnTypeBool, "maybe", true},
+		{constants.DynamicColumnTypeDate, "2026-01-31", false},
+		{constants.DynamicColumnTypeDate, "2026-02-31", true},
+		{constants.DynamicColumnTypeDate, "31/01/2026", true},
+		{constants.DynamicColumnTypeTimestamp, "2026-01-31 08:30:00", false},
+		{constants.DynamicColumnTypeTimestamp, "2026-01-31T08:30:00+07:00", false},
+		{constants.DynamicColumnTypeTimestamp, "2026-01-31", true},
+		{constants.DynamicColumnTypeTimestamp, "2026-01-31 25:00:00", true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.colType)+" "+tt.defaultValue, func(t *testing.T) {
+			err := checkDefaultValue(tt.colType, tt.defaultValue)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkDefaultValue(%s, %q) error = %v, want error %v", tt.colType, tt.defaultValue, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCastFormula(t *testing.T) {
+	tests := []struct {
+		name         string
+		colType      string
+		defaultValue string
+		want         string
+		wantErr      bool
+	}{
+		{"string", "string", "", "CAST((x) AS TEXT)", false},
+		{"string with default", "string", "it's", "COALESCE(CAST((x) AS TEXT), CAST('it''s' AS TEXT))", false},
+		{"int", "int", "", "CAST((x) AS BIGINT)", false},
+		{"int with default", "int", "0", "COALESCE(CAST((x) AS BIGINT), CAST('0' AS BIGINT))", false},
+		{"int with invalid default", "int", "zero", "", true},
+		{"float with default", "float", "0.5", "COALESCE(CAST((x) AS DOUBLE PRECISION), CAST('0.5' AS DOUBLE PRECISION))", false},
+		{"float with invalid default", "float", "half", "", true},
+		{"bool with default", "bool", "false", "COALESCE(CAST((x) AS BOOLEAN), CAST('false' AS BOOLEAN))", false},
+		{"bool with invalid default", "bool", "no way", "", true},
+		{"date with default", "date", "2026-01-31", "COALESCE(CAST((x) AS DATE), CAST('2026-01-31' AS DATE))", false},
+		{"date with invalid default", "date", "tomorrow", "", true},
+		{"timestamp", "timestamp", "", "CAST((x) AS TIMESTAMP)", false},
+		{"timestamp with default", "timestamp", "2026-01-31 08:30:00", "COALESCE(CAST((x) AS TIMESTAMP), CAST('2026-01-31 08:30:00' AS TIMESTAMP))", false},
+		{"timestamp with invalid default", "timestamp", "noon", "", true},
+		{"unknown type", "money", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := castFormula("x", tt.colType, tt.defaultValue)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("castFormula(x, %s, %q) = %q, want an error", tt.colType, tt.defaultValue, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("castFormula(x, %s, %q) returned %v", tt.colType, tt.defaultValue, err)
+			}
+			if got != tt.want {
+				t.Fatalf("castFormula(x, %s, %q) = %q, want %q", tt.colType, tt.defaultValue, got, tt.want)
+			}
+		})
+	}
+}
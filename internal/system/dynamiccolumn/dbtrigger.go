@@ -0,0 +1,329 @@
+package dynamiccolumn
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/internal/shared/constants"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// triggerEvent is a write a trigger of a trigger mode column fires on
+type triggerEvent struct {
+	Name      string // suffix of the trigger and function names
+	Operation string
+	Action    constants.Action // recorded in the history of the values the trigger changes
+}
+
+var triggerEvents = []triggerEvent{
+	{Name: "insert", Operation: "INSERT", Action: constants.ActionCreate},
+	{Name: "update", Operation: "UPDATE", Action: constants.ActionUpdate},
+	{Name: "delete", Operation: "DELETE", Action: constants.ActionDelete},
+}
+
+// dbTriggerIdsVariable holds the root ids to refresh inside a trigger function
+const dbTriggerIdsVariable = "_dynamic_column_ids"
+
+// dbTrigger is a compiled trigger of a trigger mode column, Statements create or replace its function and the trigger
+type dbTrigger struct {
+	DynamicColumnTrigger
+	Statements []string
+}
+
+// dbTriggerNamePrefix is the prefix of the names of the triggers of a dynamic column
+func dbTriggerNamePrefix(id int64) string {
+	return fmt.Sprintf("%s_%d_", constants.DB_TRIGGER_PREFIX, id)
+}
+
+/*
+* buildDbTriggers compiles a trigger mode column into statement level triggers on every table it depends on,
+* one per event since transition tables cannot be shared between events.
+* Every trigger selects the root ids of the written rows with the record selector of the dependency,
+* then runs the formula, and the transition formula, for those ids.
+* The temp ids table is never used: the selectors read the ids from the transition tables,
+* and the formula reads them from the array the selector filled.
+* - INSERT selects the root rows of the inserted rows
+* - UPDATE, of the dependency columns only, selects the root rows of the rows whose dependency columns changed,
+* joined with their new values and with their old values, so a root row a foreign key moved away from is refreshed too
+* - DELETE selects the root rows of the deleted rows joined with their old values,
+* there is none when the dependency is the root table itself
+* The formula updates the rows whose value changed only, the triggers of the columns reading it then fire in turn.
+* The changed values are inserted into the history, with the written rows as their origin.
+ */
+func buildDbTriggers(col DynamicColumn) []dbTrigger {
+	tables := make([]constants.TableName, 0, len(col.Dependencies))
+	for table := range col.Dependencies {
+		tables = append(tables, table)
+	}
+	slices.Sort(tables)
+
+	triggers := make([]dbTrigger, 0)
+	for _, table := range tables {
+		dep := col.Dependencies[table]
+		for _, event := range triggerEvents {
+			rootIds := buildDbTriggerRootIds(table, dep, event)
+			if rootIds == "" {
+				continue
+			}
+			trigger := DynamicColumnTrigger{
+				Name:      dbTriggerNamePrefix(col.ID) + event.Name,
+				TableName: table,
+				Function:  fmt.Sprintf("%s%s_%s", dbTriggerNamePrefix(col.ID), table, event.Name),
+			}
+			triggers = append(triggers, dbTrigger{
+				DynamicColumnTrigger: trigger,
+				Statements:           buildDbTriggerStatements(trigger, dep, event, rootIds, buildDbTriggerFormulas(col, event)),
+			})
+		}
+	}
+	return triggers
+}
+
+/*
+* buildDbTriggerFormulas returns the formula, and the transition formula, reading the root ids from the array the selector filled.
+* The changes the formula returns are inserted into the history instead, a trigger function cannot return them,
+* and the history rows are delivered to the active webhooks subscribed to the column, with the payload of webhook.WebhookPayload.
+ */
+func buildDbTriggerFormulas(col DynamicColumn, event triggerEvent) []string {
+	ids := fmt.Sprintf("SELECT unnest(%s) AS id", dbTriggerIdsVariable)
+	writtenRows := constants.DB_TRIGGER_NEW_TABLE
+	if event.Operation == "DELETE" {
+		writtenRows = constants.DB_TRIGGER_OLD_TABLE
+	}
+
+	changes := strings.NewReplacer("{{t_name}}", string(col.TableName), "{{c_name}}", col.Name).Replace(constants.FORMULA_CHANGES_SELECT)
+	insertHistory := fmt.Sprintf(`, %[11]s_%[12]s_history AS (
+		INSERT INTO %[1]s (dynamic_column_id, table_name, column_name, record_id, old_value, new_value,
+		origin_table, origin_action, origin_ids, request_id)
+		SELECT %[2]d, '%[3]s', '%[4]s', record_id, old_value, new_value,
+		TG_TABLE_NAME, '%[5]s', to_jsonb(ARRAY(SELECT w.id FROM %[6]s w LIMIT %[7]d)), NULLIF(current_setting('%[8]s', true), '')
+		FROM %[11]s_%[12]s_changes
+		RETURNING *
+		)
+		INSERT INTO %[9]s (subscription_id, payload)
+		SELECT s.id, jsonb_build_object('event', '%[13]s', 'dynamic_column_id', h.dynamic_column_id,
+		'table_name', h.table_name, 'column_name', h.column_name, 'record_id', h.record_id,
+		'old_value', h.old_value, 'new_value', h.new_value,
+		'origin_table', h.origin_table, 'origin_action', h.origin_action, 'origin_ids', h.origin_ids,
+		'request_id', h.request_id, 'changed_at', h.changed_at)
+		FROM %[11]s_%[12]s_history h
+		JOIN %[10]s s ON s.table_name = h.table_name AND s.column_name = h.column_name AND s.is_active
+		AND (s.new_value IS NULL OR s.new_value = h.new_value)`,
+		constants.HISTORY_TABLE_NAME, col.ID, col.TableName, col.Name,
+		event.Action, writtenRows, constants.HISTORY_ORIGIN_IDS_LIMIT, constants.HISTORY_REQUEST_ID_SETTING,
+		constants.WEBHOOK_DELIVERY_TABLE_NAME, constants.WEBHOOK_SUBSCRIPTION_TABLE_NAME,
+		col.TableName, col.Name, constants.WEBHOOK_EVENT)
+
+	formulas := []string{strings.Replace(substituteTempIds(col.Formula, ids), changes, strings.Join(strings.Fields(insertHistory), " "), 1)}
+	if col.TransitionFormula != "" {
+		formulas = append(formulas, substituteTempIds(col.TransitionFormula, ids))
+	}
+	return formulas
+}
+
+// buildDbTriggerRootIds selects the root ids to refresh after an event on a dependency table, "" when there is none
+func buildDbTriggerRootIds(table constants.TableName, dep Dependency, event triggerEvent) string {
+	var changedIds string
+	switch event.Operation {
+	case "INSERT":
+		changedIds = fmt.Sprintf("SELECT n.id FROM %s n", constants.DB_TRIGGER_NEW_TABLE)
+	case "DELETE":
+		changedIds = fmt.Sprintf("SELECT o.id FROM %s o", constants.DB_TRIGGER_OLD_TABLE)
+	case "UPDATE":
+		changed := make([]string, 0, len(dep.Columns))
+		for _, column := range dep.Columns {
+			changed = append(changed, fmt.Sprintf("n.%[1]s IS DISTINCT FROM o.%[1]s", column))
+		}
+		changedIds = fmt.Sprintf("SELECT n.id FROM %s n JOIN %s o ON o.id = n.id WHERE %s",
+			constants.DB_TRIGGER_NEW_TABLE, constants.DB_TRIGGER_OLD_TABLE, strings.Join(changed, " OR "))
+	}
+
+	// The written rows of the root table are the rows to refresh, deleted ones have nothing left to refresh
+	if dep.RecordIdsSelector == "" {
+		if event.Operation == "DELETE" {
+			return ""
+		}
+		return changedIds
+	}
+
+	selector := strings.Join(strings.Fields(dep.RecordIdsSelector), " ")
+	current := substituteTempIds(selector, changedIds)
+	old := substituteTempIds(selectOldRows(selector, table), changedIds)
+	switch event.Operation {
+	case "INSERT":
+		return current
+	case "DELETE":
+		return old
+	default:
+		return current + " UNION " + old
+	}
+}
+
+// substituteTempIds makes a compiled query read its ids from a subquery instead of the temp ids table
+func substituteTempIds(query string, ids string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	return strings.ReplaceAll(query, constants.TEMP_TABLE_NAME+" tdi", "("+ids+") tdi")
+}
+
+/*
+* selectOldRows makes a record selector join the ids to the old values of the rows of the table, from the old transition table.
+* Selectors start with "FROM tmp_dynamiccolumn_ids tdi JOIN <table> [alias] ON", see buildBackSelector.
+ */
+func selectOldRows(selector string, table constants.TableName) string {
+	pattern := regexp.MustCompile(fmt.Sprintf(`tdi JOIN %s( \w+)? ON `, regexp.QuoteMeta(string(table))))
+	return pattern.ReplaceAllStringFunc(selector, func(match string) string {
+		alias := strings.TrimSuffix(strings.TrimPrefix(match, "tdi JOIN "+string(table)), " ON ")
+		if alias == "" {
+			alias = " " + string(table)
+		}
+		return fmt.Sprintf("tdi JOIN %s%s ON ", constants.DB_TRIGGER_OLD_TABLE, alias)
+	})
+}
+
+// buildDbTriggerStatements returns the statements creating, or replacing, the function and the trigger
+func buildDbTriggerStatements(trigger DynamicColumnTrigger, dep Dependency, event triggerEvent, rootIds string, formulas []string) []string {
+	createFunction := fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s() RETURNS trigger AS $dynamic_column$
+DECLARE
+	%[2]s bigint[];
+BEGIN
+	%[2]s := ARRAY(%[3]s);
+	IF cardinality(%[2]s) = 0 THEN
+		RETURN NULL;
+	END IF;
+	%[4]s;
+	RETURN NULL;
+END
+$dynamic_column$ LANGUAGE plpgsql`, trigger.Function, dbTriggerIdsVariable, rootIds, strings.Join(formulas, ";\n\t"))
+
+	var timing string
+	switch event.Operation {
+	case "INSERT":
+		timing = fmt.Sprintf("AFTER INSERT ON %s REFERENCING NEW TABLE AS %s", trigger.TableName, constants.DB_TRIGGER_NEW_TABLE)
+	case "DELETE":
+		timing = fmt.Sprintf("AFTER DELETE ON %s REFERENCING OLD TABLE AS %s", trigger.TableName, constants.DB_TRIGGER_OLD_TABLE)
+	case "UPDATE":
+		timing = fmt.Sprintf("AFTER UPDATE OF %s ON %s REFERENCING OLD TABLE AS %s NEW TABLE AS %s",
+			strings.Join(dep.Columns, ", "), trigger.TableName, constants.DB_TRIGGER_OLD_TABLE, constants.DB_TRIGGER_NEW_TABLE)
+	}
+
+	return []string{
+		createFunction,
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", trigger.Name, trigger.TableName),
+		fmt.Sprintf("CREATE TRIGGER %s %s FOR EACH STATEMENT EXECUTE FUNCTION %s()", trigger.Name, timing, trigger.Function),
+	}
+}
+
+// dropDbTriggerStatements returns the statements removing an installed trigger and its function
+func dropDbTriggerStatements(trigger DynamicColumnTrigger) []string {
+	return []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", trigger.Name, trigger.TableName),
+		fmt.Sprintf("DROP FUNCTION IF EXISTS %s()", trigger.Function),
+	}
+}
+
+// syncDbTriggers drops the installed triggers of a dynamic column, then installs its triggers again when it is in trigger mode.
+// It runs in the transaction of the definition change, like the DDL of the target column.
+func (r *dynamicColumnService) syncDbTriggers(ctx context.Context, col DynamicColumn) error {
+	installed, err := r.dynamicColumnRepo.GetInstalledTriggers(ctx, dbTriggerNamePrefix(col.ID))
+	if err != nil {
+		return err
+	}
+	for _, trigger := range installed {
+		err = r.execStatements(ctx, dropDbTriggerStatements(trigger))
+		if err != nil {
+			return err
+		}
+	}
+	if col.RefreshMode != constants.RefreshModeTrigger {
+		return nil
+	}
+	for _, trigger := range buildDbTriggers(col) {
+		err = r.execStatements(ctx, trigger.Statements)
+		if err != nil {
+			return fmt.Errorf("installing trigger %s on %s: %w", trigger.Name, trigger.TableName, err)
+		}
+	}
+	return nil
+}
+
+func (r *dynamicColumnService) execStatements(ctx context.Context, statements []string) error {
+	for _, statement := range statements {
+		err := r.dynamicColumnRepo.ExecDDL(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncDbTriggers replaces the triggers of every dynamic column, and drops the triggers left by deleted ones
+func (r *dynamicColumnService) SyncDbTriggers(ctx context.Context) error {
+	columns, err := r.dynamicColumnRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, col := range columns {
+		err := r.syncDbTriggers(ctx, col)
+		if err != nil {
+			return fmt.Errorf("dynamic column %s.%s: %w", col.TableName, col.Name, err)
+		}
+	}
+
+	installed, err := r.dynamicColumnRepo.GetInstalledTriggers(ctx, constants.DB_TRIGGER_PREFIX+"_")
+	if err != nil {
+		return err
+	}
+	for _, trigger := range installed {
+		if slices.ContainsFunc(columns, func(col DynamicColumn) bool { return strings.HasPrefix(trigger.Name, dbTriggerNamePrefix(col.ID)) }) {
+			continue
+		}
+		err = r.execStatements(ctx, dropDbTriggerStatements(trigger))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetRefreshModes reports the refresh mode of every dynamic column, with its installed triggers
+func (r *dynamicColumnService) GetRefreshModes(ctx context.Context) ([]DynamicColumnRefreshMode, error) {
+	installed, err := r.dynamicColumnRepo.GetInstalledTriggers(ctx, constants.DB_TRIGGER_PREFIX+"_")
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := r.dynamicColumnRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]DynamicColumnRefreshMode, 0)
+	for _, col := range columns {
+		mode := DynamicColumnRefreshMode{
+			ID:          col.ID,
+			TableName:   col.TableName,
+			Name:        col.Name,
+			RefreshMode: col.RefreshMode,
+			Triggers:    make([]DynamicColumnTrigger, 0),
+		}
+		for _, trigger := range installed {
+			if strings.HasPrefix(trigger.Name, dbTriggerNamePrefix(col.ID)) {
+				mode.Triggers = append(mode.Triggers, trigger)
+			}
+		}
+
+		expected := make([]DynamicColumnTrigger, 0)
+		if col.RefreshMode == constants.RefreshModeTrigger {
+			for _, trigger := range buildDbTriggers(col) {
+				expected = append(expected, trigger.DynamicColumnTrigger)
+			}
+		}
+		mode.InSync = len(expected) == len(mode.Triggers)
+		for _, trigger := range expected {
+			mode.InSync = mode.InSync && slices.Contains(mode.Triggers, trigger)
+		}
+		result = append(result, mode)
+	}
+	return result, nil
+}
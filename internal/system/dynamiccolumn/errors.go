@@ -0,0 +1,19 @@
+package dynamiccolumn
+
+import "errors"
+
+// ErrInvalidFormula wraps every error raised while compiling a dynamic column definition
+// so callers can tell user input errors apart from database errors.
+var ErrInvalidFormula = errors.New("invalid formula")
+
+// ErrDynamicColumnInUse is returned when deleting a dynamic column that other dynamic columns depend on
+var ErrDynamicColumnInUse = errors.New("dynamic column is in use")
+
+// ErrDependencyCycle is returned when dynamic columns read each other without declaring max_iterations
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// ErrUnknownTable is returned when a request names a table that has no model
+var ErrUnknownTable = errors.New("unknown table")
+
+// ErrNotViewColumn is returned when refreshing the materialized view of a dynamic column that is not served from one
+var ErrNotViewColumn = errors.New("not a view column")
@@ -0,0 +1,225 @@
+package formula
+
+import "strings"
+
+// Expr is a node of a parsed formula or variable expression
+type Expr interface {
+	Position() Position
+}
+
+// Literal is a number, string, boolean or NULL literal kept in its source form
+type Literal struct {
+	Pos Position
+	Raw string
+}
+
+// TypedLiteral is a string literal prefixed by a type, e.g. INTERVAL '1 day'
+type TypedLiteral struct {
+	Pos   Position
+	Type  string
+	Value string // raw quoted string
+}
+
+// TableColumnRef is a column of a referenced table: {{table}}.column
+type TableColumnRef struct {
+	Pos      Position
+	Table    string // inner text of {{...}}
+	Column   string // raw column text, quoted identifiers keep their quotes
+	TablePos Position
+}
+
+// Ident is a bare, optionally dotted, name such as a variable, a root table column or CURRENT_DATE.
+// Quoted parts keep their quotes.
+type Ident struct {
+	Pos   Position
+	Parts []string
+}
+
+// Name returns the identifier when it is a single unquoted part, or "" otherwise
+func (i *Ident) Name() string {
+	if len(i.Parts) != 1 || strings.HasPrefix(i.Parts[0], `"`) {
+		return ""
+	}
+	return i.Parts[0]
+}
+
+// Star is the * argument of COUNT(*)
+type Star struct {
+	Pos Position
+}
+
+type OrderItem struct {
+	Expr      Expr
+	Direction string // ASC, DESC or ""
+}
+
+// FuncCall is a function or aggregate call with optional DISTINCT, ORDER BY and FILTER (WHERE ...)
+type FuncCall struct {
+	Pos      Position
+	Name     string
+	Distinct bool
+	Args     []Expr
+	OrderBy  []OrderItem
+	Filter   Expr
+}
+
+type When struct {
+	Cond   Expr
+	Result Expr
+}
+
+// Case is CASE [operand] WHEN ... THEN ... [ELSE ...] END
+type Case struct {
+	Pos     Position
+	Operand Expr
+	Whens   []When
+	Else    Expr
+}
+
+// Binary is an infix operation. Op is normalized to upper case for keyword operators (AND, NOT LIKE, ...).
+type Binary struct {
+	Pos   Position
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// Unary is a prefix operation: NOT, - or +
+type Unary struct {
+	Pos Position
+	Op  string
+	X   Expr
+}
+
+// Is is X IS [NOT] NULL|TRUE|FALSE|UNKNOWN or X IS [NOT] DISTINCT FROM Right
+type Is struct {
+	Pos   Position
+	X     Expr
+	Not   bool
+	What  string
+	Right Expr
+}
+
+// In is X [NOT] IN (list)
+type In struct {
+	Pos  Position
+	X    Expr
+	Not  bool
+	List []Expr
+}
+
+// Between is X [NOT] BETWEEN Low AND High
+type Between struct {
+	Pos  Position
+	X    Expr
+	Not  bool
+	Low  Expr
+	High Expr
+}
+
+// Cast is X::type or CAST(X AS type)
+type Cast struct {
+	Pos    Position
+	X      Expr
+	Type   string
+	Syntax string // "::" or "CAST"
+}
+
+// Paren is a parenthesized expression
+type Paren struct {
+	Pos Position
+	X   Expr
+}
+
+func (e *Literal) Position() Position        { return e.Pos }
+func (e *TypedLiteral) Position() Position   { return e.Pos }
+func (e *TableColumnRef) Position() Position { return e.Pos }
+func (e *Ident) Position() Position          { return e.Pos }
+func (e *Star) Position() Position           { return e.Pos }
+func (e *FuncCall) Position() Position       { return e.Pos }
+func (e *Case) Position() Position           { return e.Pos }
+func (e *Binary) Position() Position         { return e.Pos }
+func (e *Unary) Position() Position          { return e.Pos }
+func (e *Is) Position() Position             { return e.Pos }
+func (e *In) Position() Position             { return e.Pos }
+func (e *Between) Position() Position        { return e.Pos }
+func (e *Cast) Position() Position           { return e.Pos }
+func (e *Paren) Position() Position          { return e.Pos }
+
+// VarDecl is a variable declaration: var name = expr
+type VarDecl struct {
+	Pos     Position
+	Name    string
+	NamePos Position
+	Expr    Expr
+}
+
+// Walk calls fn for every node of the tree in depth-first order.
+// Children are skipped when fn returns false.
+func Walk(e Expr, fn func(Expr) bool) {
+	if e == nil || !fn(e) {
+		return
+	}
+	switch n := e.(type) {
+	case *FuncCall:
+		for _, arg := range n.Args {
+			Walk(arg, fn)
+		}
+		for _, item := range n.OrderBy {
+			Walk(item.Expr, fn)
+		}
+		Walk(n.Filter, fn)
+	case *Case:
+		Walk(n.Operand, fn)
+		for _, w := range n.Whens {
+			Walk(w.Cond, fn)
+			Walk(w.Result, fn)
+		}
+		Walk(n.Else, fn)
+	case *Binary:
+		Walk(n.Left, fn)
+		Walk(n.Right, fn)
+	case *Unary:
+		Walk(n.X, fn)
+	case *Is:
+		Walk(n.X, fn)
+		Walk(n.Right, fn)
+	case *In:
+		Walk(n.X, fn)
+		for _, item := range n.List {
+			Walk(item, fn)
+		}
+	case *Between:
+		Walk(n.X, fn)
+		Walk(n.Low, fn)
+		Walk(n.High, fn)
+	case *Cast:
+		Walk(n.X, fn)
+	case *Paren:
+		Walk(n.X, fn)
+	}
+}
+
+// TableColumnRefs returns every {{table}}.column reference of the tree in source order
+func TableColumnRefs(e Expr) []*TableColumnRef {
+	refs := make([]*TableColumnRef, 0)
+	Walk(e, func(n Expr) bool {
+		if ref, ok := n.(*TableColumnRef); ok {
+			refs = append(refs, ref)
+		}
+		return true
+	})
+	return refs
+}
+
+// Idents returns every bare identifier of the tree in source order
+func Idents(e Expr) []*Ident {
+	idents := make([]*Ident, 0)
+	Walk(e, func(n Expr) bool {
+		if ident, ok := n.(*Ident); ok {
+			idents = append(idents, ident)
+		}
+		return true
+	})
+	return idents
+}
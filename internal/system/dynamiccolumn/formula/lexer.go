@@ -0,0 +1,248 @@
+package formula
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// multi-character operators must be matched before their single character prefixes
+var multiCharOperators = []string{"::", "<>", "!=", "<=", ">=", "||"}
+
+const singleCharOperators = "+-*/%=<>^"
+
+type lexer struct {
+	src    string
+	offset int
+	line   int
+	column int
+	tokens []Token
+}
+
+// Tokenize splits a formula or variables source into tokens.
+// Comments (-- and /* */) and whitespace are skipped.
+func Tokenize(src string) ([]Token, error) {
+	l := &lexer{src: src, line: 1, column: 1}
+	for {
+		l.skipSpaceAndComments()
+		if l.offset >= len(l.src) {
+			l.tokens = append(l.tokens, Token{Kind: TokenEOF, Pos: l.pos()})
+			return l.tokens, nil
+		}
+		if err := l.next(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (l *lexer) pos() Position {
+	return Position{Offset: l.offset, Line: l.line, Column: l.column}
+}
+
+func (l *lexer) peek() rune {
+	if l.offset >= len(l.src) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.offset:])
+	return r
+}
+
+func (l *lexer) peekAt(n int) rune {
+	offset := l.offset
+	for i := 0; i < n && offset < len(l.src); i++ {
+		_, size := utf8.DecodeRuneInString(l.src[offset:])
+		offset += size
+	}
+	if offset >= len(l.src) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[offset:])
+	return r
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.offset:])
+	l.offset += size
+	if r == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	return r
+}
+
+func (l *lexer) skipSpaceAndComments() {
+	for l.offset < len(l.src) {
+		r := l.peek()
+		switch {
+		case unicode.IsSpace(r):
+			l.advance()
+		case r == '-' && l.peekAt(1) == '-':
+			for l.offset < len(l.src) && l.peek() != '\n' {
+				l.advance()
+			}
+		case r == '/' && l.peekAt(1) == '*':
+			l.advance()
+			l.advance()
+			for l.offset < len(l.src) && !(l.peek() == '*' && l.peekAt(1) == '/') {
+				l.advance()
+			}
+			if l.offset < len(l.src) {
+				l.advance()
+				l.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) emit(kind TokenKind, start Position, value string) {
+	l.tokens = append(l.tokens, Token{Kind: kind, Raw: l.src[start.Offset:l.offset], Value: value, Pos: start})
+}
+
+func (l *lexer) next() error {
+	start := l.pos()
+	r := l.peek()
+
+	switch {
+	case r == '{' && l.peekAt(1) == '{':
+		return l.tableRef(start)
+	case isIdentStart(r):
+		for l.offset < len(l.src) && isIdentPart(l.peek()) {
+			l.advance()
+		}
+		l.emit(TokenIdent, start, l.src[start.Offset:l.offset])
+		return nil
+	case unicode.IsDigit(r) || (r == '.' && unicode.IsDigit(l.peekAt(1))):
+		l.number()
+		l.emit(TokenNumber, start, l.src[start.Offset:l.offset])
+		return nil
+	case r == '\'':
+		value, err := l.quoted('\'', start, "string")
+		if err != nil {
+			return err
+		}
+		l.emit(TokenString, start, value)
+		return nil
+	case r == '"':
+		value, err := l.quoted('"', start, "quoted identifier")
+		if err != nil {
+			return err
+		}
+		l.emit(TokenQuotedIdent, start, value)
+		return nil
+	case r == '(':
+		l.advance()
+		l.emit(TokenLParen, start, "(")
+		return nil
+	case r == ')':
+		l.advance()
+		l.emit(TokenRParen, start, ")")
+		return nil
+	case r == ',':
+		l.advance()
+		l.emit(TokenComma, start, ",")
+		return nil
+	case r == '.':
+		l.advance()
+		l.emit(TokenDot, start, ".")
+		return nil
+	case r == ';':
+		l.advance()
+		l.emit(TokenSemicolon, start, ";")
+		return nil
+	}
+
+	for _, op := range multiCharOperators {
+		if strings.HasPrefix(l.src[l.offset:], op) {
+			for range op {
+				l.advance()
+			}
+			l.emit(TokenOperator, start, op)
+			return nil
+		}
+	}
+	if strings.ContainsRune(singleCharOperators, r) {
+		l.advance()
+		l.emit(TokenOperator, start, string(r))
+		return nil
+	}
+
+	return Errorf(start, "unexpected character %q", r)
+}
+
+// tableRef reads {{...}} and keeps the inner text with its spaces collapsed as the token value, e.g. "company via contract"
+func (l *lexer) tableRef(start Position) error {
+	l.advance()
+	l.advance()
+	end := strings.Index(l.src[l.offset:], "}}")
+	if end < 0 {
+		return Errorf(start, "unterminated table reference, missing '}}'")
+	}
+	inner := l.src[l.offset : l.offset+end]
+	if strings.ContainsAny(inner, "{\n") {
+		return Errorf(start, "unterminated table reference, missing '}}'")
+	}
+	for l.offset < start.Offset+2+end+2 {
+		l.advance()
+	}
+	value := strings.Join(strings.Fields(inner), " ")
+	if value == "" {
+		return Errorf(start, "empty table reference")
+	}
+	l.emit(TokenTableRef, start, value)
+	return nil
+}
+
+func (l *lexer) number() {
+	for unicode.IsDigit(l.peek()) {
+		l.advance()
+	}
+	if l.peek() == '.' && unicode.IsDigit(l.peekAt(1)) {
+		l.advance()
+		for unicode.IsDigit(l.peek()) {
+			l.advance()
+		}
+	}
+	if (l.peek() == 'e' || l.peek() == 'E') &&
+		(unicode.IsDigit(l.peekAt(1)) || ((l.peekAt(1) == '+' || l.peekAt(1) == '-') && unicode.IsDigit(l.peekAt(2)))) {
+		l.advance()
+		if l.peek() == '+' || l.peek() == '-' {
+			l.advance()
+		}
+		for unicode.IsDigit(l.peek()) {
+			l.advance()
+		}
+	}
+}
+
+// quoted reads a quote delimited literal where a doubled quote escapes the quote character
+func (l *lexer) quoted(quote rune, start Position, what string) (string, error) {
+	var value strings.Builder
+	l.advance()
+	for {
+		if l.offset >= len(l.src) {
+			return "", Errorf(start, "unterminated %s", what)
+		}
+		r := l.advance()
+		if r == quote {
+			if l.peek() == quote {
+				l.advance()
+				value.WriteRune(quote)
+				continue
+			}
+			return value.String(), nil
+		}
+		value.WriteRune(r)
+	}
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
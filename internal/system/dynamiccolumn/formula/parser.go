@@ -0,0 +1,644 @@
+package formula
+
+import (
+	"strings"
+)
+
+// Operator precedences, loosely following PostgreSQL
+const (
+	precLowest = iota
+	precOr
+	precAnd
+	precNot
+	precIs
+	precComparison
+	precRange // BETWEEN, IN, LIKE, ILIKE
+	precOther // ||
+	precAdditive
+	precMultiplicative
+	precExponent
+	precUnary
+	precCast
+)
+
+// reserved words cannot be used as bare identifiers in an expression
+var reservedWords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IS": true, "IN": true, "BETWEEN": true,
+	"LIKE": true, "ILIKE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
+	"AS": true, "FILTER": true, "WHERE": true, "DISTINCT": true, "FROM": true, "ORDER": true,
+	"BY": true, "ASC": true, "DESC": true, "SELECT": true, "OVER": true,
+}
+
+// words that continue a multi-word type name, e.g. double precision, timestamp with time zone
+var typeNameContinuations = map[string]bool{
+	"PRECISION": true, "VARYING": true, "WITH": true, "WITHOUT": true, "TIME": true, "ZONE": true,
+}
+
+// SQL value functions called without parentheses, the only bare identifiers that are not variables
+var valueKeywords = map[string]bool{
+	"CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true, "LOCALTIME": true, "LOCALTIMESTAMP": true,
+}
+
+// IsValueKeyword reports whether a bare identifier is a SQL value function such as CURRENT_DATE
+func IsValueKeyword(name string) bool {
+	return valueKeywords[strings.ToUpper(name)]
+}
+
+var typedLiteralTypes = map[string]bool{
+	"INTERVAL": true, "DATE": true, "TIMESTAMP": true, "TIMESTAMPTZ": true, "TIME": true,
+}
+
+type parser struct {
+	tokens []Token
+	pos    int
+}
+
+// ParseExpression parses a formula into an expression tree
+func ParseExpression(src string) (Expr, error) {
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.at(TokenEOF) {
+		return nil, Errorf(p.peek().Pos, "formula is empty")
+	}
+	expr, err := p.parseExpr(precLowest)
+	if err != nil {
+		return nil, err
+	}
+	for p.at(TokenSemicolon) {
+		p.advance()
+	}
+	if !p.at(TokenEOF) {
+		return nil, p.unexpected()
+	}
+	return expr, nil
+}
+
+// ParseVariables parses variable declarations: var name = expr.
+// Declarations may span several lines and can be separated by ';'.
+func ParseVariables(src string) ([]*VarDecl, error) {
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	decls := make([]*VarDecl, 0)
+	for {
+		for p.at(TokenSemicolon) {
+			p.advance()
+		}
+		if p.at(TokenEOF) {
+			return decls, nil
+		}
+		decl, err := p.parseVarDecl()
+		if err != nil {
+			return nil, err
+		}
+		decls = append(decls, decl)
+		if !p.at(TokenEOF) && !p.at(TokenSemicolon) && !p.isKeyword("VAR") {
+			return nil, p.unexpected()
+		}
+	}
+}
+
+func (p *parser) parseVarDecl() (*VarDecl, error) {
+	start := p.peek()
+	if !p.isKeyword("VAR") {
+		return nil, Errorf(start.Pos, "expected 'var' keyword, found %s", start)
+	}
+	p.advance()
+
+	name := p.peek()
+	if name.Kind != TokenIdent || reservedWords[strings.ToUpper(name.Value)] {
+		return nil, Errorf(name.Pos, "expected variable name, found %s", name)
+	}
+	p.advance()
+
+	if !p.atOperator("=") {
+		return nil, Errorf(p.peek().Pos, "expected assign operator '=' after variable %q, found %s", name.Value, p.peek())
+	}
+	p.advance()
+
+	if p.at(TokenEOF) || p.at(TokenSemicolon) || p.isKeyword("VAR") {
+		return nil, Errorf(p.peek().Pos, "missing expression for variable %q", name.Value)
+	}
+	expr, err := p.parseExpr(precLowest)
+	if err != nil {
+		return nil, err
+	}
+	return &VarDecl{Pos: start.Pos, Name: name.Value, NamePos: name.Pos, Expr: expr}, nil
+}
+
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(n int) Token {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *parser) advance() Token {
+	t := p.tokens[p.pos]
+	if t.Kind != TokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) at(kind TokenKind) bool {
+	return p.peek().Kind == kind
+}
+
+func (p *parser) atOperator(op string) bool {
+	return p.peek().Kind == TokenOperator && p.peek().Value == op
+}
+
+func (p *parser) isKeyword(word string) bool {
+	return isKeyword(p.peek(), word)
+}
+
+func isKeyword(t Token, word string) bool {
+	return t.Kind == TokenIdent && strings.EqualFold(t.Value, word)
+}
+
+func (p *parser) expectKeyword(word string) error {
+	if !p.isKeyword(word) {
+		return Errorf(p.peek().Pos, "expected %s, found %s", word, p.peek())
+	}
+	p.advance()
+	return nil
+}
+
+func (p *parser) expect(kind TokenKind) (Token, error) {
+	if !p.at(kind) {
+		return Token{}, Errorf(p.peek().Pos, "expected %s, found %s", kind, p.peek())
+	}
+	return p.advance(), nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.Kind == TokenEOF {
+		return Errorf(t.Pos, "unexpected end of input")
+	}
+	return Errorf(t.Pos, "unexpected %s", t)
+}
+
+// infixPrecedence returns the precedence of the operator at the current token, or precLowest if there is none
+func (p *parser) infixPrecedence() int {
+	t := p.peek()
+	switch t.Kind {
+	case TokenOperator:
+		switch t.Value {
+		case "=", "<>", "!=", "<", ">", "<=", ">=":
+			return precComparison
+		case "||":
+			return precOther
+		case "+", "-":
+			return precAdditive
+		case "*", "/", "%":
+			return precMultiplicative
+		case "^":
+			return precExponent
+		case "::":
+			return precCast
+		}
+	case TokenIdent:
+		switch strings.ToUpper(t.Value) {
+		case "OR":
+			return precOr
+		case "AND":
+			return precAnd
+		case "IS":
+			return precIs
+		case "IN", "BETWEEN", "LIKE", "ILIKE":
+			return precRange
+		case "NOT":
+			next := strings.ToUpper(p.peekAt(1).Value)
+			if p.peekAt(1).Kind == TokenIdent && (next == "IN" || next == "BETWEEN" || next == "LIKE" || next == "ILIKE") {
+				return precRange
+			}
+		}
+	}
+	return precLowest
+}
+
+// parseExpr parses an expression whose infix operators bind tighter than minPrec
+func (p *parser) parseExpr(minPrec int) (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		prec := p.infixPrecedence()
+		if prec == precLowest || prec <= minPrec {
+			return left, nil
+		}
+		left, err = p.parseInfix(left, prec)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t := p.peek()
+	if isKeyword(t, "NOT") {
+		p.advance()
+		x, err := p.parseExpr(precNot)
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Pos: t.Pos, Op: "NOT", X: x}, nil
+	}
+	if t.Kind == TokenOperator && (t.Value == "-" || t.Value == "+") {
+		p.advance()
+		x, err := p.parseExpr(precUnary)
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Pos: t.Pos, Op: t.Value, X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parseInfix(left Expr, prec int) (Expr, error) {
+	t := p.advance()
+
+	if t.Kind == TokenOperator {
+		if t.Value == "::" {
+			typeName, err := p.parseTypeName()
+			if err != nil {
+				return nil, err
+			}
+			return &Cast{Pos: left.Position(), X: left, Type: typeName, Syntax: "::"}, nil
+		}
+		right, err := p.parseExpr(prec)
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{Pos: left.Position(), Op: t.Value, Left: left, Right: right}, nil
+	}
+
+	word := strings.ToUpper(t.Value)
+	not := false
+	if word == "NOT" {
+		not = true
+		word = strings.ToUpper(p.advance().Value)
+	}
+
+	switch word {
+	case "OR", "AND":
+		right, err := p.parseExpr(prec)
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{Pos: left.Position(), Op: word, Left: left, Right: right}, nil
+	case "LIKE", "ILIKE":
+		right, err := p.parseExpr(prec)
+		if err != nil {
+			return nil, err
+		}
+		if not {
+			word = "NOT " + word
+		}
+		return &Binary{Pos: left.Position(), Op: word, Left: left, Right: right}, nil
+	case "IN":
+		return p.parseIn(left, not)
+	case "BETWEEN":
+		low, err := p.parseExpr(precRange)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.parseExpr(precRange)
+		if err != nil {
+			return nil, err
+		}
+		return &Between{Pos: left.Position(), X: left, Not: not, Low: low, High: high}, nil
+	case "IS":
+		return p.parseIs(left)
+	}
+	return nil, Errorf(t.Pos, "unexpected %s", t)
+}
+
+func (p *parser) parseIn(left Expr, not bool) (Expr, error) {
+	if _, err := p.expect(TokenLParen); err != nil {
+		return nil, err
+	}
+	if p.isKeyword("SELECT") {
+		return nil, Errorf(p.peek().Pos, "subqueries are not supported")
+	}
+	list, err := p.parseExprList()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	return &In{Pos: left.Position(), X: left, Not: not, List: list}, nil
+}
+
+func (p *parser) parseIs(left Expr) (Expr, error) {
+	is := &Is{Pos: left.Position(), X: left}
+	if p.isKeyword("NOT") {
+		p.advance()
+		is.Not = true
+	}
+	t := p.peek()
+	word := strings.ToUpper(t.Value)
+	switch {
+	case t.Kind == TokenIdent && (word == "NULL" || word == "TRUE" || word == "FALSE" || word == "UNKNOWN"):
+		p.advance()
+		is.What = word
+	case isKeyword(t, "DISTINCT"):
+		p.advance()
+		if err := p.expectKeyword("FROM"); err != nil {
+			return nil, err
+		}
+		right, err := p.parseExpr(precIs)
+		if err != nil {
+			return nil, err
+		}
+		is.What = "DISTINCT FROM"
+		is.Right = right
+	default:
+		return nil, Errorf(t.Pos, "expected NULL, TRUE, FALSE or DISTINCT FROM after IS, found %s", t)
+	}
+	return is, nil
+}
+
+func (p *parser) parseExprList() ([]Expr, error) {
+	list := make([]Expr, 0)
+	for {
+		e, err := p.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+		if !p.at(TokenComma) {
+			return list, nil
+		}
+		p.advance()
+	}
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	switch t.Kind {
+	case TokenNumber, TokenString:
+		p.advance()
+		return &Literal{Pos: t.Pos, Raw: t.Raw}, nil
+	case TokenTableRef:
+		return p.parseTableColumnRef()
+	case TokenLParen:
+		p.advance()
+		if p.isKeyword("SELECT") {
+			return nil, Errorf(p.peek().Pos, "subqueries are not supported")
+		}
+		x, err := p.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(TokenRParen); err != nil {
+			return nil, err
+		}
+		return &Paren{Pos: t.Pos, X: x}, nil
+	case TokenQuotedIdent:
+		return p.parseNameOrCall()
+	case TokenIdent:
+		word := strings.ToUpper(t.Value)
+		switch {
+		case word == "CASE":
+			return p.parseCase()
+		case word == "NULL" || word == "TRUE" || word == "FALSE":
+			p.advance()
+			return &Literal{Pos: t.Pos, Raw: t.Raw}, nil
+		case word == "CAST" && p.peekAt(1).Kind == TokenLParen:
+			return p.parseCast()
+		case typedLiteralTypes[word] && p.peekAt(1).Kind == TokenString:
+			p.advance()
+			value := p.advance()
+			return &TypedLiteral{Pos: t.Pos, Type: word, Value: value.Raw}, nil
+		case reservedWords[word]:
+			return nil, Errorf(t.Pos, "unexpected keyword %s", t)
+		}
+		return p.parseNameOrCall()
+	}
+	return nil, p.unexpected()
+}
+
+// parseTableColumnRef parses {{table}}.column
+func (p *parser) parseTableColumnRef() (Expr, error) {
+	t := p.advance()
+	if !p.at(TokenDot) {
+		return nil, Errorf(p.peek().Pos, "expected '.' and a column after table reference %s", t)
+	}
+	p.advance()
+	col := p.peek()
+	if col.Kind != TokenIdent && col.Kind != TokenQuotedIdent {
+		return nil, Errorf(col.Pos, "expected column name after %s., found %s", t, col)
+	}
+	p.advance()
+	return &TableColumnRef{Pos: t.Pos, Table: t.Value, Column: col.Raw, TablePos: t.Pos}, nil
+}
+
+// parseNameOrCall parses a dotted name, or a function call when the name is followed by '('
+func (p *parser) parseNameOrCall() (Expr, error) {
+	first := p.advance()
+	parts := []string{first.Raw}
+	for p.at(TokenDot) {
+		p.advance()
+		part := p.peek()
+		if part.Kind != TokenIdent && part.Kind != TokenQuotedIdent {
+			return nil, Errorf(part.Pos, "expected name after '.', found %s", part)
+		}
+		p.advance()
+		parts = append(parts, part.Raw)
+	}
+	if p.at(TokenLParen) {
+		return p.parseCall(first.Pos, strings.Join(parts, "."))
+	}
+	return &Ident{Pos: first.Pos, Parts: parts}, nil
+}
+
+func (p *parser) parseCall(pos Position, name string) (Expr, error) {
+	p.advance()
+	call := &FuncCall{Pos: pos, Name: name}
+
+	switch {
+	case p.atOperator("*"):
+		star := p.advance()
+		call.Args = []Expr{&Star{Pos: star.Pos}}
+	case p.at(TokenRParen):
+	default:
+		if p.isKeyword("DISTINCT") {
+			p.advance()
+			call.Distinct = true
+		}
+		args, err := p.parseExprList()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = args
+		if p.isKeyword("ORDER") {
+			orderBy, err := p.parseOrderBy()
+			if err != nil {
+				return nil, err
+			}
+			call.OrderBy = orderBy
+		}
+	}
+	if _, err := p.expect(TokenRParen); err != nil {
+		return nil, err
+	}
+
+	if p.isKeyword("FILTER") {
+		p.advance()
+		if _, err := p.expect(TokenLParen); err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("WHERE"); err != nil {
+			return nil, err
+		}
+		filter, err := p.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(TokenRParen); err != nil {
+			return nil, err
+		}
+		call.Filter = filter
+	}
+	if p.isKeyword("OVER") {
+		return nil, Errorf(p.peek().Pos, "window functions are not supported")
+	}
+	return call, nil
+}
+
+func (p *parser) parseOrderBy() ([]OrderItem, error) {
+	p.advance()
+	if err := p.expectKeyword("BY"); err != nil {
+		return nil, err
+	}
+	items := make([]OrderItem, 0)
+	for {
+		e, err := p.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		item := OrderItem{Expr: e}
+		if p.isKeyword("ASC") || p.isKeyword("DESC") {
+			item.Direction = strings.ToUpper(p.advance().Value)
+		}
+		items = append(items, item)
+		if !p.at(TokenComma) {
+			return items, nil
+		}
+		p.advance()
+	}
+}
+
+func (p *parser) parseCase() (Expr, error) {
+	start := p.advance()
+	c := &Case{Pos: start.Pos}
+	if !p.isKeyword("WHEN") {
+		operand, err := p.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		c.Operand = operand
+	}
+	for p.isKeyword("WHEN") {
+		p.advance()
+		cond, err := p.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("THEN"); err != nil {
+			return nil, err
+		}
+		result, err := p.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		c.Whens = append(c.Whens, When{Cond: cond, Result: result})
+	}
+	if len(c.Whens) == 0 {
+		return nil, Errorf(p.peek().Pos, "expected WHEN in CASE started at %s, found %s", start.Pos, p.peek())
+	}
+	if p.isKeyword("ELSE") {
+		p.advance()
+		elseExpr, err := p.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		c.Else = elseExpr
+	}
+	if !p.isKeyword("END") {
+		return nil, Errorf(p.peek().Pos, "expected END to close CASE started at %s, found %s", start.Pos, p.peek())
+	}
+	p.advance()
+	return c, nil
+}
+
+func (p *parser) parseCast() (Expr, error) {
+	start := p.advance()
+	p.advance()
+	x, err := p.parseExpr(precLowest)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("AS"); err != nil {
+		return nil, err
+	}
+	typeName, err := p.parseTypeName()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	return &Cast{Pos: start.Pos, X: x, Type: typeName, Syntax: "CAST"}, nil
+}
+
+// parseTypeName parses a type such as numeric, numeric(15, 2), double precision or timestamp with time zone
+func (p *parser) parseTypeName() (string, error) {
+	t := p.peek()
+	if t.Kind != TokenIdent {
+		return "", Errorf(t.Pos, "expected type name, found %s", t)
+	}
+	p.advance()
+	parts := []string{t.Raw}
+	for p.at(TokenIdent) && typeNameContinuations[strings.ToUpper(p.peek().Value)] {
+		parts = append(parts, p.advance().Raw)
+	}
+	name := strings.Join(parts, " ")
+	if p.at(TokenLParen) {
+		p.advance()
+		mods := make([]string, 0)
+		for {
+			n, err := p.expect(TokenNumber)
+			if err != nil {
+				return "", err
+			}
+			mods = append(mods, n.Raw)
+			if !p.at(TokenComma) {
+				break
+			}
+			p.advance()
+		}
+		if _, err := p.expect(TokenRParen); err != nil {
+			return "", err
+		}
+		name += "(" + strings.Join(mods, ", ") + ")"
+	}
+	return name, nil
+}
@@ -0,0 +1,92 @@
+package formula
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseExpressionRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		formula string
+		want    string
+	}{
+		{"column", "{{invoice}}.amount", "{{invoice}}.amount"},
+		{"binary", "{{invoice}}.amount*2+1", "{{invoice}}.amount * 2 + 1"},
+		{"negation", "-{{invoice}}.amount", "-{{invoice}}.amount"},
+		{"double negation", "- -{{invoice}}.amount", "- -{{invoice}}.amount"},
+		{"negated plus", "- +{{invoice}}.amount", "- +{{invoice}}.amount"},
+		{"subtracted negation", "1 - -{{invoice}}.amount", "1 - -{{invoice}}.amount"},
+		{"not", "NOT {{contract}}.is_cancelled", "NOT {{contract}}.is_cancelled"},
+		{"parenthesized", "({{invoice}}.amount + 1) * 2", "({{invoice}}.amount + 1) * 2"},
+		{"aggregate filter", "COUNT(*) FILTER (WHERE {{invoice}}.status = 'Overdue')", "COUNT(*) FILTER (WHERE {{invoice}}.status = 'Overdue')"},
+		{"case", "CASE WHEN {{invoice}}.paid_at IS NULL THEN 'Pending' ELSE 'Paid' END", "CASE WHEN {{invoice}}.paid_at IS NULL THEN 'Pending' ELSE 'Paid' END"},
+		{"in", "{{invoice}}.status NOT IN ('Paid', 'Cancelled')", "{{invoice}}.status NOT IN ('Paid', 'Cancelled')"},
+		{"between", "{{invoice}}.amount BETWEEN 1 AND 10", "{{invoice}}.amount BETWEEN 1 AND 10"},
+		{"casts", "CAST({{invoice}}.amount AS int) + {{invoice}}.amount::numeric", "CAST({{invoice}}.amount AS int) + {{invoice}}.amount::numeric"},
+		{"comment", "{{invoice}}.amount -- the amount\n+ 1", "{{invoice}}.amount + 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseExpression(tt.formula)
+			if err != nil {
+				t.Fatalf("ParseExpression(%q) returned %v", tt.formula, err)
+			}
+			got := Render(expr)
+			if got != tt.want {
+				t.Fatalf("Render(ParseExpression(%q)) = %q, want %q", tt.formula, got, tt.want)
+			}
+
+			// The rendered form is parsed back to the same tree, a sign must never turn into a comment
+			again, err := ParseExpression(got)
+			if err != nil {
+				t.Fatalf("ParseExpression(%q) of the rendered form returned %v", got, err)
+			}
+			if Render(again) != got {
+				t.Fatalf("rendered form %q renders again as %q", got, Render(again))
+			}
+		})
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		formula string
+		want    string
+	}{
+		{"empty", "", "formula is empty"},
+		{"trailing operator", "{{invoice}}.amount +", ""},
+		{"unclosed paren", "({{invoice}}.amount", ""},
+		{"trailing tokens", "{{invoice}}.amount {{invoice}}.amount", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExpression(tt.formula)
+			var formulaErr *Error
+			if !errors.As(err, &formulaErr) {
+				t.Fatalf("ParseExpression(%q) returned %v, want a formula error", tt.formula, err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseExpression(%q) returned %q, want it to contain %q", tt.formula, err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVariables(t *testing.T) {
+	decls, err := ParseVariables(`
+var total_count = COUNT(*)
+var overdue_count = COUNT(*) FILTER (WHERE {{invoice}}.status = 'Overdue');
+`)
+	if err != nil {
+		t.Fatalf("ParseVariables returned %v", err)
+	}
+	if len(decls) != 2 || decls[0].Name != "total_count" || decls[1].Name != "overdue_count" {
+		t.Fatalf("ParseVariables returned %d declarations, want total_count and overdue_count", len(decls))
+	}
+	if got := Render(decls[1].Expr); got != "COUNT(*) FILTER (WHERE {{invoice}}.status = 'Overdue')" {
+		t.Fatalf("second declaration renders as %q", got)
+	}
+}
@@ -0,0 +1,147 @@
+package formula
+
+import "strings"
+
+// Renderer turns an expression tree back into SQL.
+// Hooks may rewrite table column references and identifiers, nil hooks keep the source form.
+type Renderer struct {
+	TableColumn func(ref *TableColumnRef) string
+	Ident       func(ident *Ident) string
+}
+
+// Render renders an expression in its source form
+func Render(e Expr) string {
+	return (&Renderer{}).Render(e)
+}
+
+func (r *Renderer) Render(e Expr) string {
+	var b strings.Builder
+	r.render(&b, e)
+	return b.String()
+}
+
+func (r *Renderer) render(b *strings.Builder, e Expr) {
+	switch n := e.(type) {
+	case *Literal:
+		b.WriteString(n.Raw)
+	case *TypedLiteral:
+		b.WriteString(n.Type + " " + n.Value)
+	case *TableColumnRef:
+		if r.TableColumn != nil {
+			b.WriteString(r.TableColumn(n))
+			return
+		}
+		b.WriteString("{{" + n.Table + "}}." + n.Column)
+	case *Ident:
+		if r.Ident != nil {
+			b.WriteString(r.Ident(n))
+			return
+		}
+		b.WriteString(strings.Join(n.Parts, "."))
+	case *Star:
+		b.WriteString("*")
+	case *FuncCall:
+		b.WriteString(n.Name + "(")
+		if n.Distinct {
+			b.WriteString("DISTINCT ")
+		}
+		r.renderList(b, n.Args)
+		if len(n.OrderBy) > 0 {
+			b.WriteString(" ORDER BY ")
+			for i, item := range n.OrderBy {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				r.render(b, item.Expr)
+				if item.Direction != "" {
+					b.WriteString(" " + item.Direction)
+				}
+			}
+		}
+		b.WriteString(")")
+		if n.Filter != nil {
+			b.WriteString(" FILTER (WHERE ")
+			r.render(b, n.Filter)
+			b.WriteString(")")
+		}
+	case *Case:
+		b.WriteString("CASE")
+		if n.Operand != nil {
+			b.WriteString(" ")
+			r.render(b, n.Operand)
+		}
+		for _, w := range n.Whens {
+			b.WriteString(" WHEN ")
+			r.render(b, w.Cond)
+			b.WriteString(" THEN ")
+			r.render(b, w.Result)
+		}
+		if n.Else != nil {
+			b.WriteString(" ELSE ")
+			r.render(b, n.Else)
+		}
+		b.WriteString(" END")
+	case *Binary:
+		r.render(b, n.Left)
+		b.WriteString(" " + n.Op + " ")
+		r.render(b, n.Right)
+	case *Unary:
+		// A sign followed by another sign would read as a -- line comment or a single operator
+		operand := r.Render(n.X)
+		b.WriteString(n.Op)
+		if n.Op == "NOT" || strings.HasPrefix(operand, "-") || strings.HasPrefix(operand, "+") {
+			b.WriteString(" ")
+		}
+		b.WriteString(operand)
+	case *Is:
+		r.render(b, n.X)
+		b.WriteString(" IS ")
+		if n.Not {
+			b.WriteString("NOT ")
+		}
+		b.WriteString(n.What)
+		if n.Right != nil {
+			b.WriteString(" ")
+			r.render(b, n.Right)
+		}
+	case *In:
+		r.render(b, n.X)
+		if n.Not {
+			b.WriteString(" NOT")
+		}
+		b.WriteString(" IN (")
+		r.renderList(b, n.List)
+		b.WriteString(")")
+	case *Between:
+		r.render(b, n.X)
+		if n.Not {
+			b.WriteString(" NOT")
+		}
+		b.WriteString(" BETWEEN ")
+		r.render(b, n.Low)
+		b.WriteString(" AND ")
+		r.render(b, n.High)
+	case *Cast:
+		if n.Syntax == "CAST" {
+			b.WriteString("CAST(")
+			r.render(b, n.X)
+			b.WriteString(" AS " + n.Type + ")")
+			return
+		}
+		r.render(b, n.X)
+		b.WriteString("::" + n.Type)
+	case *Paren:
+		b.WriteString("(")
+		r.render(b, n.X)
+		b.WriteString(")")
+	}
+}
+
+func (r *Renderer) renderList(b *strings.Builder, list []Expr) {
+	for i, item := range list {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		r.render(b, item)
+	}
+}
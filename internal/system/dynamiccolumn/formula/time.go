@@ -0,0 +1,135 @@
+package formula
+
+import "strings"
+
+// functions returning the current time, whose result changes without any row changing
+var currentTimeFunctions = map[string]bool{
+	"NOW": true, "CLOCK_TIMESTAMP": true, "STATEMENT_TIMESTAMP": true, "TRANSACTION_TIMESTAMP": true, "TIMEOFDAY": true,
+}
+
+// UsesCurrentTime reports whether the tree reads the current time, through CURRENT_DATE and the like,
+// NOW() and the like, or AGE with a single argument
+func UsesCurrentTime(e Expr) bool {
+	found := false
+	Walk(e, func(n Expr) bool {
+		switch node := n.(type) {
+		case *Ident:
+			found = found || IsValueKeyword(node.Name())
+		case *FuncCall:
+			name := strings.ToUpper(node.Name)
+			found = found || currentTimeFunctions[name] || (name == "AGE" && len(node.Args) == 1)
+		}
+		return !found
+	})
+	return found
+}
+
+// TimeThreshold is an instant the result of a formula may change at,
+// the expression a comparison compares the current time to, solved for the current time
+type TimeThreshold struct {
+	Expr  Expr
+	Daily bool // compared to CURRENT_DATE, the result changes at the start of the day of the instant or of the next day
+}
+
+/*
+* TimeThresholds returns the thresholds of the comparisons of the current time in the tree:
+* - CURRENT_DATE < start_date gives start_date
+* - CURRENT_DATE - created_at > payment_terms * INTERVAL '1 day' gives payment_terms * INTERVAL '1 day' + created_at
+* - NOW() BETWEEN starts_at AND ends_at gives starts_at and ends_at
+* complete is false when the current time is read any other way, e.g. AGE(created_at) or CURRENT_TIME,
+* the changes of the result cannot be predicted from the thresholds then.
+ */
+func TimeThresholds(e Expr) (thresholds []TimeThreshold, complete bool) {
+	thresholds = make([]TimeThreshold, 0)
+	complete = true
+	Walk(e, func(n Expr) bool {
+		switch node := n.(type) {
+		case *Binary:
+			if !comparisonOperators[node.Op] {
+				return true
+			}
+			if threshold, ok := solveCurrentTime(node.Left, node.Right); ok {
+				thresholds = append(thresholds, threshold)
+				return false
+			}
+			if threshold, ok := solveCurrentTime(node.Right, node.Left); ok {
+				thresholds = append(thresholds, threshold)
+				return false
+			}
+		case *Between:
+			daily, isTime := currentTime(node.X)
+			if isTime && !UsesCurrentTime(node.Low) && !UsesCurrentTime(node.High) {
+				thresholds = append(thresholds, TimeThreshold{Expr: node.Low, Daily: daily}, TimeThreshold{Expr: node.High, Daily: daily})
+				return false
+			}
+		case *Ident:
+			// Comparisons solved above are not walked into, any other read of the current time is left
+			complete = complete && !IsValueKeyword(node.Name())
+		case *FuncCall:
+			name := strings.ToUpper(node.Name)
+			complete = complete && !currentTimeFunctions[name] && !(name == "AGE" && len(node.Args) == 1)
+		}
+		return true
+	})
+	return thresholds, complete
+}
+
+var comparisonOperators = map[string]bool{"<": true, "<=": true, ">": true, ">=": true, "=": true, "<>": true, "!=": true}
+
+// solveCurrentTime solves side op other for the current time, when side is the current time alone,
+// or the current time plus or minus an expression
+func solveCurrentTime(side Expr, other Expr) (TimeThreshold, bool) {
+	if UsesCurrentTime(other) {
+		return TimeThreshold{}, false
+	}
+	for {
+		paren, ok := side.(*Paren)
+		if !ok {
+			break
+		}
+		side = paren.X
+	}
+
+	if daily, ok := currentTime(side); ok {
+		return TimeThreshold{Expr: other, Daily: daily}, true
+	}
+	binary, ok := side.(*Binary)
+	if !ok || (binary.Op != "+" && binary.Op != "-") || UsesCurrentTime(binary.Right) {
+		return TimeThreshold{}, false
+	}
+	daily, ok := currentTime(binary.Left)
+	if !ok {
+		return TimeThreshold{}, false
+	}
+	// now - x > y is now > y + x, now + x > y is now > y - x
+	op := "+"
+	if binary.Op == "+" {
+		op = "-"
+	}
+	solved := &Binary{
+		Pos:   other.Position(),
+		Op:    op,
+		Left:  &Paren{Pos: other.Position(), X: other},
+		Right: &Paren{Pos: binary.Right.Position(), X: binary.Right},
+	}
+	return TimeThreshold{Expr: solved, Daily: daily}, true
+}
+
+// currentTime reports whether the expression is the current date or timestamp, and whether it is the current date
+func currentTime(e Expr) (daily bool, ok bool) {
+	switch node := e.(type) {
+	case *Ident:
+		switch strings.ToUpper(node.Name()) {
+		case "CURRENT_DATE":
+			return true, true
+		case "CURRENT_TIMESTAMP", "LOCALTIMESTAMP":
+			return false, true
+		}
+	case *FuncCall:
+		name := strings.ToUpper(node.Name)
+		if name != "TIMEOFDAY" && currentTimeFunctions[name] {
+			return false, true
+		}
+	}
+	return false, false
+}
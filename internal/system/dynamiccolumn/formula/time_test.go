@@ -0,0 +1,136 @@
+package formula
+
+import (
+	"slices"
+	"testing"
+)
+
+// thresholdNames renders the thresholds, with a "daily" suffix for the ones compared to CURRENT_DATE
+func thresholdNames(thresholds []TimeThreshold) []string {
+	names := make([]string, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		name := Render(threshold.Expr)
+		if threshold.Daily {
+			name += " daily"
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestTimeThresholds(t *testing.T) {
+	tests := []struct {
+		name         string
+		formula      string
+		want         []string
+		wantComplete bool
+	}{
+		{"no current time", "{{invoice}}.amount > 10", []string{}, true},
+		{"date before", "CURRENT_DATE < {{contract}}.start_date", []string{"{{contract}}.start_date daily"}, true},
+		{"date after or on", "{{contract}}.start_date <= CURRENT_DATE", []string{"{{contract}}.start_date daily"}, true},
+		{"timestamp after", "NOW() > {{invoice}}.due_at", []string{"{{invoice}}.due_at"}, true},
+		{"timestamp on the right", "{{invoice}}.due_at >= now()", []string{"{{invoice}}.due_at"}, true},
+		{"current timestamp", "CURRENT_TIMESTAMP >= {{invoice}}.due_at", []string{"{{invoice}}.due_at"}, true},
+		{
+			"interval subtracted",
+			"CURRENT_DATE - {{invoice}}.created_at > {{invoice}}.payment_terms * INTERVAL '1 day'",
+			[]string{"({{invoice}}.payment_terms * INTERVAL '1 day') + ({{invoice}}.created_at) daily"},
+			true,
+		},
+		{
+			"interval added in parentheses",
+			"(NOW() + INTERVAL '1 hour') < {{invoice}}.due_at",
+			[]string{"({{invoice}}.due_at) - (INTERVAL '1 hour')"},
+			true,
+		},
+		{
+			"between",
+			"NOW() BETWEEN {{contract}}.starts_at AND {{contract}}.ends_at",
+			[]string{"{{contract}}.starts_at", "{{contract}}.ends_at"},
+			true,
+		},
+		{
+			"inside a case",
+			"CASE WHEN NOW() > {{invoice}}.due_at THEN 'Overdue' ELSE {{invoice}}.status END",
+			[]string{"{{invoice}}.due_at"},
+			true,
+		},
+		{
+			"several comparisons",
+			"NOW() > {{invoice}}.due_at AND CURRENT_DATE < {{contract}}.end_date",
+			[]string{"{{invoice}}.due_at", "{{contract}}.end_date daily"},
+			true,
+		},
+		{"age", "AGE({{invoice}}.created_at) > INTERVAL '1 year'", []string{}, false},
+		{"current time of day", "CURRENT_TIME > '12:00'", []string{}, false},
+		{"timeofday", "TIMEOFDAY() > '12:00'", []string{}, false},
+		{"current time on both sides", "NOW() < NOW() + INTERVAL '1 day'", []string{}, false},
+		{"current time subtracted from a column", "{{invoice}}.due_at - NOW() < INTERVAL '1 day'", []string{}, false},
+		{
+			"solved and unsolved reads",
+			"NOW() > {{invoice}}.due_at OR AGE({{invoice}}.created_at) > INTERVAL '1 year'",
+			[]string{"{{invoice}}.due_at"},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseExpression(tt.formula)
+			if err != nil {
+				t.Fatalf("ParseExpression(%q) returned %v", tt.formula, err)
+			}
+			thresholds, complete := TimeThresholds(expr)
+			if got := thresholdNames(thresholds); !slices.Equal(got, tt.want) {
+				t.Fatalf("TimeThresholds(%q) = %q, want %q", tt.formula, got, tt.want)
+			}
+			if complete != tt.wantComplete {
+				t.Fatalf("TimeThresholds(%q) complete = %v, want %v", tt.formula, complete, tt.wantComplete)
+			}
+		})
+	}
+}
+
+func TestSolveCurrentTime(t *testing.T) {
+	tests := []struct {
+		name   string
+		side   string
+		other  string
+		want   string
+		wantOk bool
+	}{
+		{"current date", "CURRENT_DATE", "{{contract}}.start_date", "{{contract}}.start_date daily", true},
+		{"now", "NOW()", "{{invoice}}.due_at", "{{invoice}}.due_at", true},
+		{"localtimestamp", "LOCALTIMESTAMP", "{{invoice}}.due_at", "{{invoice}}.due_at", true},
+		{"parenthesized", "((NOW()))", "{{invoice}}.due_at", "{{invoice}}.due_at", true},
+		{"minus interval", "NOW() - INTERVAL '2 days'", "{{invoice}}.due_at", "({{invoice}}.due_at) + (INTERVAL '2 days')", true},
+		{"plus interval", "CURRENT_DATE + {{invoice}}.grace_days", "{{invoice}}.due_date", "({{invoice}}.due_date) - ({{invoice}}.grace_days) daily", true},
+		{"column side", "{{invoice}}.due_at", "NOW()", "", false},
+		{"current time on the other side", "NOW()", "NOW() + INTERVAL '1 day'", "", false},
+		{"current time subtracted", "NOW() - NOW()", "INTERVAL '1 day'", "", false},
+		{"multiplied", "NOW() * 2", "{{invoice}}.due_at", "", false},
+		{"interval minus current time", "INTERVAL '1 day' - NOW()", "{{invoice}}.due_at", "", false},
+		{"timeofday", "TIMEOFDAY()", "'12:00'", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			side, err := ParseExpression(tt.side)
+			if err != nil {
+				t.Fatalf("ParseExpression(%q) returned %v", tt.side, err)
+			}
+			other, err := ParseExpression(tt.other)
+			if err != nil {
+				t.Fatalf("ParseExpression(%q) returned %v", tt.other, err)
+			}
+			threshold, ok := solveCurrentTime(side, other)
+			if ok != tt.wantOk {
+				t.Fatalf("solveCurrentTime(%q, %q) ok = %v, want %v", tt.side, tt.other, ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if got := thresholdNames([]TimeThreshold{threshold}); got[0] != tt.want {
+				t.Fatalf("solveCurrentTime(%q, %q) = %q, want %q", tt.side, tt.other, got[0], tt.want)
+			}
+		})
+	}
+}
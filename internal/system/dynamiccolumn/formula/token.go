@@ -0,0 +1,64 @@
+package formula
+
+import "fmt"
+
+type TokenKind int
+
+const (
+	TokenEOF TokenKind = iota
+	TokenIdent
+	TokenQuotedIdent
+	TokenNumber
+	TokenString
+	TokenTableRef // {{table}}
+	TokenOperator
+	TokenLParen
+	TokenRParen
+	TokenComma
+	TokenDot
+	TokenSemicolon
+)
+
+var tokenKindNames = map[TokenKind]string{
+	TokenEOF:         "end of input",
+	TokenIdent:       "identifier",
+	TokenQuotedIdent: "quoted identifier",
+	TokenNumber:      "number",
+	TokenString:      "string",
+	TokenTableRef:    "table reference",
+	TokenOperator:    "operator",
+	TokenLParen:      "'('",
+	TokenRParen:      "')'",
+	TokenComma:       "','",
+	TokenDot:         "'.'",
+	TokenSemicolon:   "';'",
+}
+
+func (k TokenKind) String() string {
+	return tokenKindNames[k]
+}
+
+// Position is a location in the source text. Line and Column are 1-based, Offset is the byte offset.
+type Position struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+type Token struct {
+	Kind  TokenKind
+	Raw   string // exact source text of the token
+	Value string // decoded value: unquoted identifier or string, inner text of a table reference
+	Pos   Position
+}
+
+func (t Token) String() string {
+	if t.Kind == TokenEOF {
+		return t.Kind.String()
+	}
+	return fmt.Sprintf("%q", t.Raw)
+}
@@ -0,0 +1,192 @@
+package dynamiccolumn
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// dependencyGraph is the column level graph of dynamic columns.
+// Nodes are "table.column" names, an edge goes from a dynamic column to every dynamic column it reads.
+type dependencyGraph struct {
+	columns map[string]DynamicColumn
+	edges   map[string][]string
+}
+
+func columnKey(col DynamicColumn) string {
+	return string(col.TableName) + "." + col.Name
+}
+
+func newDependencyGraph(cols []DynamicColumn) *dependencyGraph {
+	g := &dependencyGraph{
+		columns: make(map[string]DynamicColumn),
+		edges:   make(map[string][]string),
+	}
+	for _, col := range cols {
+		g.columns[columnKey(col)] = col
+	}
+	for key, col := range g.columns {
+		edges := make([]string, 0)
+		for depTable, dep := range col.Dependencies {
+			for _, depCol := range dep.Columns {
+				depKey := string(depTable) + "." + depCol
+				if _, isDynamic := g.columns[depKey]; isDynamic && !slices.Contains(edges, depKey) {
+					edges = append(edges, depKey)
+				}
+			}
+		}
+		// Sorted edges make the reported cycle paths deterministic
+		slices.Sort(edges)
+		g.edges[key] = edges
+	}
+	return g
+}
+
+// components returns the strongly connected components of the graph (Tarjan).
+// A column is in a cycle when its component has more than one column or when it reads itself.
+func (g *dependencyGraph) components() map[string][]string {
+	index := 0
+	indexes := make(map[string]int)
+	lowLinks := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	result := make(map[string][]string)
+
+	var connect func(key string)
+	connect = func(key string) {
+		indexes[key] = index
+		lowLinks[key] = index
+		index++
+		stack = append(stack, key)
+		onStack[key] = true
+
+		for _, next := range g.edges[key] {
+			if _, visited := indexes[next]; !visited {
+				connect(next)
+				lowLinks[key] = min(lowLinks[key], lowLinks[next])
+			} else if onStack[next] {
+				lowLinks[key] = min(lowLinks[key], indexes[next])
+			}
+		}
+
+		if lowLinks[key] != indexes[key] {
+			return
+		}
+		component := make([]string, 0)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == key {
+				break
+			}
+		}
+		slices.Sort(component)
+		for _, member := range component {
+			result[member] = component
+		}
+	}
+
+	for _, key := range g.sortedKeys() {
+		if _, visited := indexes[key]; !visited {
+			connect(key)
+		}
+	}
+	return result
+}
+
+// levels assigns every column the length of the longest chain of dynamic columns it reads.
+// A column only reads columns of lower levels, except the columns of its own cycle which share its level.
+func (g *dependencyGraph) levels() map[string]int {
+	components := g.components()
+	componentLevels := make(map[string]int)
+
+	var levelOf func(key string) int
+	levelOf = func(key string) int {
+		component := components[key]
+		if level, done := componentLevels[component[0]]; done {
+			return level
+		}
+		level := 0
+		for _, member := range component {
+			for _, next := range g.edges[member] {
+				if !slices.Contains(component, next) {
+					level = max(level, levelOf(next)+1)
+				}
+			}
+		}
+		componentLevels[component[0]] = level
+		return level
+	}
+
+	result := make(map[string]int)
+	for _, key := range g.sortedKeys() {
+		result[key] = levelOf(key)
+	}
+	return result
+}
+
+// cycleMembers returns the columns sharing a cycle with the given column, or nil when it is not in a cycle
+func (g *dependencyGraph) cycleMembers(key string) []string {
+	component := g.components()[key]
+	if len(component) > 1 || slices.Contains(g.edges[key], key) {
+		return component
+	}
+	return nil
+}
+
+// cyclePath returns the shortest path from the column back to itself, e.g. [company.status contract.status company.status]
+func (g *dependencyGraph) cyclePath(key string) []string {
+	parents := make(map[string]string)
+	queue := []string{key}
+	visited := map[string]bool{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range g.edges[current] {
+			if next == key {
+				path := []string{key}
+				for node := current; node != key; node = parents[node] {
+					path = append(path, node)
+				}
+				path = append(path, key)
+				slices.Reverse(path)
+				return path
+			}
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			parents[next] = current
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
+
+// cycleIterations returns how many times a cycle is refreshed.
+// Every column of the cycle must declare max_iterations, the smallest one wins.
+func (g *dependencyGraph) cycleIterations(key string, members []string) (int, error) {
+	iterations := 0
+	for _, member := range members {
+		maxIterations := g.columns[member].MaxIterations
+		if maxIterations <= 0 {
+			return 0, fmt.Errorf("%w: %s, set max_iterations on %s to allow it",
+				ErrDependencyCycle, strings.Join(g.cyclePath(key), " -> "), member)
+		}
+		if iterations == 0 || maxIterations < iterations {
+			iterations = maxIterations
+		}
+	}
+	return iterations, nil
+}
+
+func (g *dependencyGraph) sortedKeys() []string {
+	keys := make([]string, 0, len(g.columns))
+	for key := range g.columns {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,165 @@
+package dynamiccolumn
+
+import (
+	"errors"
+	"gin-demo/internal/shared/constants"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testColumn builds a dynamic column of the table reading the given "table.column" names
+func testColumn(key string, maxIterations int, reads ...string) DynamicColumn {
+	table, name, _ := strings.Cut(key, ".")
+	col := DynamicColumn{
+		TableName:     constants.TableName(table),
+		Name:          name,
+		MaxIterations: maxIterations,
+		Dependencies:  make(map[constants.TableName]Dependency),
+	}
+	for _, read := range reads {
+		depTable, depColumn, _ := strings.Cut(read, ".")
+		dep := col.Dependencies[constants.TableName(depTable)]
+		dep.Columns = append(dep.Columns, depColumn)
+		dep.RecordIdsSelector = "SELECT id FROM " + depTable
+		col.Dependencies[constants.TableName(depTable)] = dep
+	}
+	return col
+}
+
+func TestDependencyGraphCycleMembers(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []DynamicColumn
+		key     string
+		want    []string
+	}{
+		{
+			name: "no cycle",
+			columns: []DynamicColumn{
+				testColumn("invoice.status", 0, "invoice.paid_at"),
+				testColumn("contract.status", 0, "invoice.status"),
+			},
+			key:  "contract.status",
+			want: nil,
+		},
+		{
+			name: "self reference",
+			columns: []DynamicColumn{
+				testColumn("company.score", 0, "company.score"),
+			},
+			key:  "company.score",
+			want: []string{"company.score"},
+		},
+		{
+			name: "two columns",
+			columns: []DynamicColumn{
+				testColumn("company.status", 0, "contract.status"),
+				testColumn("contract.status", 0, "company.status"),
+			},
+			key:  "contract.status",
+			want: []string{"company.status", "contract.status"},
+		},
+		{
+			name: "reader of a cycle",
+			columns: []DynamicColumn{
+				testColumn("company.status", 0, "contract.status"),
+				testColumn("contract.status", 0, "company.status"),
+				testColumn("deployment.status", 0, "company.status"),
+			},
+			key:  "deployment.status",
+			want: nil,
+		},
+		{
+			name: "three columns",
+			columns: []DynamicColumn{
+				testColumn("company.status", 0, "contract.status"),
+				testColumn("contract.status", 0, "invoice.status"),
+				testColumn("invoice.status", 0, "company.status"),
+			},
+			key:  "invoice.status",
+			want: []string{"company.status", "contract.status", "invoice.status"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newDependencyGraph(tt.columns).cycleMembers(tt.key)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("cycleMembers(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencyGraphCycleIterations(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []DynamicColumn
+		want    int
+		wantErr string
+	}{
+		{
+			name: "smallest max_iterations",
+			columns: []DynamicColumn{
+				testColumn("company.status", 3, "contract.status"),
+				testColumn("contract.status", 2, "company.status"),
+			},
+			want: 2,
+		},
+		{
+			name: "missing max_iterations",
+			columns: []DynamicColumn{
+				testColumn("company.status", 3, "contract.status"),
+				testColumn("contract.status", 0, "company.status"),
+			},
+			wantErr: "dependency cycle: company.status -> contract.status -> company.status, set max_iterations on contract.status to allow it",
+		},
+		{
+			name: "self reference",
+			columns: []DynamicColumn{
+				testColumn("company.status", 0, "company.status"),
+			},
+			wantErr: "dependency cycle: company.status -> company.status, set max_iterations on company.status to allow it",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := newDependencyGraph(tt.columns)
+			key := "company.status"
+			got, err := graph.cycleIterations(key, graph.cycleMembers(key))
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrDependencyCycle) || err.Error() != tt.wantErr {
+					t.Fatalf("cycleIterations() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cycleIterations() returned %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("cycleIterations() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencyGraphLevels(t *testing.T) {
+	graph := newDependencyGraph([]DynamicColumn{
+		testColumn("invoice.status", 0, "invoice.paid_at"),
+		testColumn("contract.status", 2, "invoice.status", "company.status"),
+		testColumn("company.status", 2, "contract.status"),
+		testColumn("deployment.status", 0, "company.status"),
+	})
+	want := map[string]int{
+		"invoice.status":    0,
+		"contract.status":   1,
+		"company.status":    1,
+		"deployment.status": 2,
+	}
+	got := graph.levels()
+	for key, level := range want {
+		if got[key] != level {
+			t.Errorf("level of %s = %d, want %d", key, got[key], level)
+		}
+	}
+}
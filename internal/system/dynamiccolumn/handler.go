@@ -0,0 +1,244 @@
+package dynamiccolumn
+
+import (
+	"errors"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/types"
+	"gin-demo/internal/system/dynamiccolumn/formula"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DynamicColumnHandler interface {
+	base.BaseHandler
+	Validate(c *gin.Context)
+	Preview(c *gin.Context)
+	RefreshPlan(c *gin.Context)
+	GetAllDdlLogs(c *gin.Context)
+	GetRefreshModes(c *gin.Context)
+	SyncDbTriggers(c *gin.Context)
+	RefreshView(c *gin.Context)
+}
+
+type dynamicColumnHandler struct {
+	dynamicColumnService DynamicColumnService
+}
+
+func NewDynamicColumnHandler(dynamicColumnService DynamicColumnService) DynamicColumnHandler {
+	return &dynamicColumnHandler{dynamicColumnService: dynamicColumnService}
+}
+
+func (h *dynamicColumnHandler) GetAll(c *gin.Context) {
+	columns, err := h.dynamicColumnService.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get dynamic columns", err.Error()))
+		return
+	}
+	c.JSON(200, types.NewListResponse(columns, nil, ""))
+}
+
+func (h *dynamicColumnHandler) GetAllDdlLogs(c *gin.Context) {
+	logs := h.dynamicColumnService.GetAllDdlLogs(c.Request.Context())
+	c.JSON(200, types.NewListResponse(logs, nil, ""))
+}
+
+// GetRefreshModes reports which dynamic columns are refreshed by the application and which by in-database triggers
+func (h *dynamicColumnHandler) GetRefreshModes(c *gin.Context) {
+	modes, err := h.dynamicColumnService.GetRefreshModes(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get refresh modes", err.Error()))
+		return
+	}
+	c.JSON(200, types.NewListResponse(modes, nil, ""))
+}
+
+// SyncDbTriggers replaces the triggers of every trigger mode column, e.g. after a migration dropped a table they were on
+func (h *dynamicColumnHandler) SyncDbTriggers(c *gin.Context) {
+	err := h.dynamicColumnService.SyncDbTriggers(c.Request.Context())
+	if err != nil {
+		h.writeError(c, "Failed to sync dynamic column triggers", err)
+		return
+	}
+	modes, err := h.dynamicColumnService.GetRefreshModes(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get refresh modes", err.Error()))
+		return
+	}
+	c.JSON(200, types.NewListResponse(modes, nil, "Dynamic column triggers synced"))
+}
+
+// RefreshView refreshes the materialized view of a view mode column now, without waiting for its schedule
+func (h *dynamicColumnHandler) RefreshView(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	column, err := h.dynamicColumnService.RefreshView(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, "Failed to refresh dynamic column view", err)
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(column, "Dynamic column view refreshed successfully"))
+}
+
+func (h *dynamicColumnHandler) GetById(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	column, err := h.dynamicColumnService.GetById(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, "Failed to get dynamic column", err)
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(column, ""))
+}
+
+func (h *dynamicColumnHandler) Validate(c *gin.Context) {
+	var payload DynamicColumnCreateRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	column, err := h.dynamicColumnService.Validate(c.Request.Context(), &payload)
+	if err != nil {
+		h.writeError(c, "Failed to validate dynamic column", err)
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(column, "Dynamic column is valid"))
+}
+
+func (h *dynamicColumnHandler) Preview(c *gin.Context) {
+	var payload DynamicColumnPreviewRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	preview, err := h.dynamicColumnService.Preview(c.Request.Context(), &payload)
+	if err != nil {
+		h.writeError(c, "Failed to preview dynamic column", err)
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(preview, ""))
+}
+
+func (h *dynamicColumnHandler) RefreshPlan(c *gin.Context) {
+	var payload RefreshPlanRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	plan, err := h.dynamicColumnService.GetRefreshPlan(c.Request.Context(), &payload)
+	if err != nil {
+		h.writeError(c, "Failed to plan refresh", err)
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(plan, ""))
+}
+
+func (h *dynamicColumnHandler) Create(c *gin.Context) {
+	var payload DynamicColumnCreateRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	column, err := h.dynamicColumnService.Create(c.Request.Context(), &payload)
+	if err != nil {
+		h.writeError(c, "Failed to create dynamic column", err)
+		return
+	}
+
+	c.JSON(201, types.NewSingleResponse(column, "Dynamic column created successfully"))
+}
+
+func (h *dynamicColumnHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	var payload DynamicColumnUpdateRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	column, err := h.dynamicColumnService.Update(c.Request.Context(), id, &payload)
+	if err != nil {
+		h.writeError(c, "Failed to update dynamic column", err)
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(column, "Dynamic column updated successfully"))
+}
+
+func (h *dynamicColumnHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	dropColumn := false
+	if c.Query("drop_column") != "" {
+		dropColumn, err = strconv.ParseBool(c.Query("drop_column"))
+		if err != nil {
+			c.JSON(400, types.NewErrorResponse("Invalid drop_column", err.Error()))
+			return
+		}
+	}
+
+	err = h.dynamicColumnService.Delete(c.Request.Context(), id, dropColumn)
+	if err != nil {
+		h.writeError(c, "Failed to delete dynamic column", err)
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse[DynamicColumn](nil, "Dynamic column deleted successfully"))
+}
+
+// writeError responds with 404 for unknown dynamic columns, 400 for formula errors, unknown tables and view refreshes of other columns,
+// 409 for conflicts with other dynamic columns and 500 for everything else
+func (h *dynamicColumnHandler) writeError(c *gin.Context, message string, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, types.NewErrorResponse("Not found", err.Error()))
+		return
+	}
+	if errors.Is(err, ErrInvalidFormula) {
+		res := types.NewErrorResponse(message, err.Error())
+
+		// Point to the exact location of syntax errors
+		var formulaErr *formula.Error
+		if errors.As(err, &formulaErr) {
+			res.Details["line"] = strconv.Itoa(formulaErr.Pos.Line)
+			res.Details["column"] = strconv.Itoa(formulaErr.Pos.Column)
+		}
+		c.JSON(400, res)
+		return
+	}
+	if errors.Is(err, ErrUnknownTable) || errors.Is(err, ErrNotViewColumn) {
+		c.JSON(400, types.NewErrorResponse(message, err.Error()))
+		return
+	}
+	if errors.Is(err, ErrDynamicColumnInUse) || errors.Is(err, ErrDependencyCycle) {
+		c.JSON(409, types.NewErrorResponse(message, err.Error()))
+		return
+	}
+	c.JSON(500, types.NewErrorResponse(message, err.Error()))
+}
@@ -0,0 +1,41 @@
+package dynamiccolumn
+
+import (
+	"context"
+	"gin-demo/internal/shared/constants"
+)
+
+// refreshOriginKey holds the RefreshOrigin of the refreshes run with a context
+const refreshOriginKey = "dynamic_column_refresh_origin"
+
+// withRefreshOrigin returns a context recording the change of the table as the cause of its refreshes.
+// An origin already set is kept: the refreshes cascading from a change, or from a schedule, were caused by it.
+func withRefreshOrigin(ctx context.Context, table constants.TableName, action constants.Action, ids []int64) context.Context {
+	if _, exists := ctx.Value(refreshOriginKey).(RefreshOrigin); exists {
+		return ctx
+	}
+	origin := RefreshOrigin{Table: table, Action: action, Ids: ids[:min(len(ids), constants.HISTORY_ORIGIN_IDS_LIMIT)]}
+	return context.WithValue(ctx, refreshOriginKey, origin)
+}
+
+// refreshColumn computes a dynamic column for the rows of the temp ids table, records the values it changed, publishes and returns them
+func (r *dynamicColumnService) refreshColumn(ctx context.Context, col DynamicColumnWithMetadata) ([]ValueChange, error) {
+	changes, err := r.dynamicColumnRepo.RefreshDynamicColumn(ctx, col)
+	if err != nil || len(changes) == 0 {
+		return changes, err
+	}
+	origin, _ := ctx.Value(refreshOriginKey).(RefreshOrigin)
+	if r.historyRecorder != nil {
+		err = r.historyRecorder.RecordChanges(ctx, col.DynamicColumn, changes, origin)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.changePublisher != nil {
+		err = r.changePublisher.PublishChanges(ctx, col.DynamicColumn, changes, origin)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
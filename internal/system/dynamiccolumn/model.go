@@ -1,21 +1,37 @@
 package dynamiccolumn
 
-import "gin-demo/internal/shared/constants"
+import (
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/types"
+	"gin-demo/internal/system/dynamiccolumn/formula"
+	"time"
+)
 
 type Dependency struct {
 	RecordIdsSelector string   // SQL query to find which records are affected by this dependency
 	Columns           []string // Columns from the dependency that are used
 }
 
+// ComputedValues is embedded by the models of the tables read through JoinComputedColumns.
+// Computed holds the dynamic columns of the row that are not stored in its table, when read with their values.
+type ComputedValues struct {
+	Computed map[string]any `json:"computed,omitempty" gorm:"column:computed;->;serializer:json"`
+}
+
 type DynamicColumn struct {
-	ID           int64                              `json:"id" gorm:"primaryKey;column:id"`
-	Name         string                             `json:"name" gorm:"column:name"`
-	TableName    constants.TableName                `json:"table_name" gorm:"column:table_name"`
-	Formula      string                             `json:"formula" gorm:"column:formula"`
-	DefaultValue string                             `json:"default_value" gorm:"column:default_value"`
-	Type         string                             `json:"type" gorm:"column:type"`
-	Dependencies map[constants.TableName]Dependency `json:"dependencies" gorm:"column:dependencies;type:jsonb;serializer:json"`
-	Variables    string                             `json:"variables" gorm:"column:variables"`
+	ID                int64                              `json:"id" gorm:"primaryKey;column:id"`
+	Name              string                             `json:"name" gorm:"column:name"`
+	TableName         constants.TableName                `json:"table_name" gorm:"column:table_name"`
+	Formula           string                             `json:"formula" gorm:"column:formula"`
+	UserFormula       string                             `json:"user_formula" gorm:"column:user_formula"`
+	DefaultValue      string                             `json:"default_value" gorm:"column:default_value"`
+	Type              string                             `json:"type" gorm:"column:type"`
+	Dependencies      map[constants.TableName]Dependency `json:"dependencies" gorm:"column:dependencies;type:jsonb;serializer:json"`
+	Variables         string                             `json:"variables" gorm:"column:variables"`
+	MaxIterations     int                                `json:"max_iterations" gorm:"column:max_iterations"`         // > 0 allows the column in a dependency cycle refreshed at most this many times
+	RefreshCron       string                             `json:"refresh_cron" gorm:"column:refresh_cron"`             // schedule of a time dependent column or of the view of a view mode column, the default schedule when empty
+	TransitionFormula string                             `json:"transition_formula" gorm:"column:transition_formula"` // stores the next instant each row may change at, empty when it cannot be predicted
+	RefreshMode       constants.RefreshMode              `json:"refresh_mode" gorm:"column:refresh_mode"`
 }
 
 type DynamicColumnWithMetadata struct {
@@ -29,17 +45,157 @@ type CtxObjIds = map[string]struct {
 }
 
 type DynamicColumnCreateRequest struct {
-	TableName constants.TableName `json:"table_name"`
-	Name      string              `json:"name"`
-	Formula   string              `json:"formula"`
-	Variables string              `json:"variables"`
-	Type      string              `json:"type"`
+	TableName     constants.TableName   `json:"table_name" binding:"required"`
+	Name          string                `json:"name" binding:"required"`
+	Formula       string                `json:"formula" binding:"required"`
+	Variables     string                `json:"variables"`
+	Type          string                `json:"type" binding:"required"`
+	DefaultValue  string                `json:"default_value"`
+	MaxIterations int                   `json:"max_iterations"`
+	RefreshCron   string                `json:"refresh_cron"`
+	RefreshMode   constants.RefreshMode `json:"refresh_mode"`  // sync when empty, async, trigger, view or virtual
+	ManageColumn  bool                  `json:"manage_column"` // add the target column when it does not exist yet
+	CreateIndex   bool                  `json:"create_index"`  // index the added target column
+}
+
+type DynamicColumnUpdateRequest struct {
+	Formula       *string                `json:"formula,omitempty"`
+	Variables     *string                `json:"variables,omitempty"`
+	Type          *string                `json:"type,omitempty"`
+	DefaultValue  *string                `json:"default_value,omitempty"`
+	MaxIterations *int                   `json:"max_iterations,omitempty"`
+	RefreshCron   *string                `json:"refresh_cron,omitempty"`
+	RefreshMode   *constants.RefreshMode `json:"refresh_mode,omitempty"`
+}
+
+// DynamicColumnDdlLog records a schema change issued for the target column of a dynamic column
+type DynamicColumnDdlLog struct {
+	ID              int64                  `json:"id" gorm:"primaryKey;column:id"`
+	DynamicColumnID int64                  `json:"dynamic_column_id" gorm:"column:dynamic_column_id"`
+	TableName       constants.TableName    `json:"table_name" gorm:"column:table_name"`
+	ColumnName      string                 `json:"column_name" gorm:"column:column_name"`
+	Operation       constants.DdlOperation `json:"operation" gorm:"column:operation"`
+	Statement       string                 `json:"statement" gorm:"column:statement"`
+	CreatedAt       time.Time              `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// DynamicColumnPreviewRequest evaluates a draft definition on the given ids, or on the first Limit rows of the table
+type DynamicColumnPreviewRequest struct {
+	TableName    constants.TableName `json:"table_name" binding:"required"`
+	Name         string              `json:"name" binding:"required"`
+	Formula      string              `json:"formula" binding:"required"`
+	Variables    string              `json:"variables"`
+	Type         string              `json:"type"`
+	DefaultValue string              `json:"default_value"`
+	Ids          []int64             `json:"ids"`
+	Limit        int                 `json:"limit" binding:"omitempty,min=1"`
+}
+
+type DynamicColumnPreviewRow struct {
+	Id           int64       `json:"id" gorm:"column:id"`
+	CurrentValue interface{} `json:"current_value" gorm:"column:current_value"`
+	NewValue     interface{} `json:"new_value" gorm:"column:new_value"`
+	Changed      bool        `json:"changed" gorm:"column:changed"`
+}
+
+type DynamicColumnPreview struct {
+	Formula   string                    `json:"formula"`
+	Rows      []DynamicColumnPreviewRow `json:"rows"`
+	Total     int                       `json:"total"`
+	Changed   int                       `json:"changed"`
+	Unchanged int                       `json:"unchanged"`
+}
+
+// ValueChange is a value of a dynamic column a refresh changed, old and new values as text
+type ValueChange struct {
+	RecordId int64   `gorm:"column:record_id"`
+	OldValue *string `gorm:"column:old_value"`
+	NewValue *string `gorm:"column:new_value"`
+}
+
+// RefreshOrigin is the change a refresh was caused by, at most HISTORY_ORIGIN_IDS_LIMIT of its ids are kept
+type RefreshOrigin struct {
+	Table  constants.TableName
+	Action constants.Action
+	Ids    []int64
+}
+
+// RefreshPlan lists, level by level, the dynamic column refreshes caused by changes of a table
+type RefreshPlan struct {
+	Table   constants.TableName                `json:"table"`
+	Changes map[constants.TableName]Dependency `json:"changes"`
+	Steps   []RefreshStep                      `json:"steps"`
+}
+
+// RefreshStep refreshes one dynamic column for the ids resolved from its sources
+type RefreshStep struct {
+	Column        string          `json:"column"`
+	Level         int             `json:"level"`
+	Iteration     int             `json:"iteration"` // > 1 when a dependency cycle is repeated
+	Sources       []RefreshSource `json:"sources"`
+	FanOut        int             `json:"fan_out"`     // number of rows to refresh, set once ids are resolved
+	Async         bool            `json:"async"`       // deferred to the refresh worker: the column is async or reads an async step
+	InDatabase    bool            `json:"in_database"` // already refreshed by the triggers of a trigger mode column, only its ids are resolved
+	DynamicColumn DynamicColumn   `json:"-"`
+	Ids           []int64         `json:"-"`
+}
+
+// DynamicColumnTrigger is an installed trigger of a trigger mode dynamic column, on one of its dependencies
+type DynamicColumnTrigger struct {
+	Name      string              `json:"name" gorm:"column:name"`
+	TableName constants.TableName `json:"table_name" gorm:"column:table_name"`
+	Function  string              `json:"function" gorm:"column:function_name"`
+}
+
+// DynamicColumnRefreshMode reports how a dynamic column is refreshed, with the triggers installed for it
+type DynamicColumnRefreshMode struct {
+	ID          int64                  `json:"id"`
+	TableName   constants.TableName    `json:"table_name"`
+	Name        string                 `json:"name"`
+	RefreshMode constants.RefreshMode  `json:"refresh_mode"`
+	Triggers    []DynamicColumnTrigger `json:"triggers"`
+	InSync      bool                   `json:"in_sync"` // the installed triggers are the ones the mode and the dependencies expect
+}
+
+// RefreshSource is where the ids of a step come from: the changed rows, or the rows refreshed by an earlier step
+type RefreshSource struct {
+	From     string `json:"from"`     // changed table, or "table.column" of an earlier step
+	Selector string `json:"selector"` // empty when the source rows are the rows to refresh
+}
+
+type RefreshPlanRequest struct {
+	TableName constants.TableName `json:"table_name" binding:"required"`
+	Ids       []int64             `json:"ids" binding:"required"`
+	Columns   []string            `json:"columns"` // changed columns, every column when empty
 }
 
 type Variable struct {
-	Name  string
-	Value string
-	Table constants.TableName
+	Name   string
+	Value  string                // rendered SQL expression
+	Table  constants.TableName   // aggregated table placeholder, the one farthest from the root table
+	Tables []constants.TableName // referenced table placeholders, e.g. contract or deployment.employee
+	Refs   []string              // variables used by the variable, it is then computed on the root row instead of a CTE
+	Expr   formula.Expr
+	Pos    formula.Position
+}
+
+// cteKey names the CTE a variable is read from: its table CTE, or its own CTE when it references several tables
+func (v Variable) cteKey() string {
+	if len(v.Tables) > 1 {
+		return "var_" + v.Name
+	}
+	return tableKey(v.Table)
+}
+
+// joinOrder lists the referenced tables with the aggregated table first
+func (v Variable) joinOrder() []constants.TableName {
+	tables := []constants.TableName{v.Table}
+	for _, table := range v.Tables {
+		if table != v.Table {
+			tables = append(tables, table)
+		}
+	}
+	return tables
 }
 
 type FormulaCte struct {
@@ -49,8 +205,28 @@ type FormulaCte struct {
 }
 
 type RelationLink struct {
-	Table    constants.TableName
-	Relation constants.TableRelation
+	Table      constants.TableName
+	Relation   constants.TableRelation
+	Via        constants.TableName // junction table of a many to many relation
+	Foreign    types.Relation      // foreign key joining the table, the junction key to the table for a many to many relation
+	ViaForeign types.Relation      // junction key to the previous table of a many to many relation
+	Alias      string              // name of the joined table in SQL when it is reached through a named relation
+}
+
+// name returns the name the joined table is referred to in SQL
+func (l RelationLink) name() string {
+	if l.Alias != "" {
+		return l.Alias
+	}
+	return string(l.Table)
+}
+
+// TableRef is a table placeholder of a formula resolved from the root table.
+// {{contract}} is the contract table, {{deployment.employee}} the table the employee relation of deployment references.
+type TableRef struct {
+	Key   string              // name of the referenced table in SQL, the placeholder with dots replaced by underscores
+	Table constants.TableName // referenced table
+	Links []RelationLink      // relation path from the root table, empty for the root table itself
 }
 
 type RelatedTables map[constants.TableName][]string
@@ -0,0 +1,290 @@
+package dynamiccolumn
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/utils"
+	"slices"
+)
+
+// PlanRefresh computes the refresh plan of the changes of a table and resolves the ids of every step.
+// Nothing is refreshed, the plan is run by executeRefreshPlan.
+func (r *dynamicColumnService) PlanRefresh(
+	ctx context.Context, table constants.TableName, ids []int64, changes map[constants.TableName]Dependency, originalRecordId *int64) (*RefreshPlan, error) {
+	columns, err := r.dynamicColumnRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := buildRefreshPlan(columns, table, changes)
+	if err != nil {
+		return nil, err
+	}
+	err = r.resolveRefreshPlan(ctx, plan, ids, originalRecordId)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// resolveRefreshPlan resolves the ids of every step of a built plan
+func (r *dynamicColumnService) resolveRefreshPlan(ctx context.Context, plan *RefreshPlan, ids []int64, originalRecordId *int64) error {
+	if len(plan.Steps) == 0 {
+		return nil
+	}
+
+	// Create a temp table to store ids that need refreshing
+	err := r.dynamicColumnRepo.CreateTempIdsTable(ctx)
+	if err != nil {
+		return err
+	}
+	return r.resolveRefreshPlanIds(ctx, plan, ids, originalRecordId)
+}
+
+// GetRefreshPlan returns the refresh plan of a change of the given columns of the records, for debugging
+func (r *dynamicColumnService) GetRefreshPlan(ctx context.Context, payload *RefreshPlanRequest) (*RefreshPlan, error) {
+	if _, exists := r.modelsMap[payload.TableName]; !exists {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTable, payload.TableName)
+	}
+
+	changes := make(map[constants.TableName]Dependency)
+	if len(payload.Columns) == 0 {
+		_, changes = r.CheckShouldRefreshDynamicColumn(ctx, payload.TableName, constants.ActionRefresh, nil)
+	} else {
+		r.addColumnsToDependency(changes, payload.TableName, payload.Columns)
+	}
+	return r.PlanRefresh(ctx, payload.TableName, payload.Ids, changes, nil)
+}
+
+/*
+* buildRefreshPlan finds every dynamic column transitively affected by the changes of a table and orders them by level.
+* Level 0 columns only read the changes, a level N column reads at least one level N-1 column.
+* Steps of the same level are sorted by column name so the same changes always give the same plan.
+* Columns of a dependency cycle share a level and are repeated max_iterations times.
+ */
+func buildRefreshPlan(columns []DynamicColumn, table constants.TableName, changes map[constants.TableName]Dependency) (*RefreshPlan, error) {
+	plan := &RefreshPlan{Table: table, Changes: changes, Steps: make([]RefreshStep, 0)}
+
+	affected := affectedColumns(columns, changes)
+	if len(affected) == 0 {
+		return plan, nil
+	}
+
+	graph := newDependencyGraph(affected)
+	levels := graph.levels()
+	keys := graph.sortedKeys()
+	slices.SortStableFunc(keys, func(a, b string) int {
+		return levels[a] - levels[b]
+	})
+
+	planned := make(map[string]bool)
+	for _, key := range keys {
+		if planned[key] {
+			continue
+		}
+		members := graph.cycleMembers(key)
+		if members == nil {
+			plan.Steps = append(plan.Steps, newRefreshStep(graph, key, levels[key], 1, changes))
+			planned[key] = true
+			continue
+		}
+
+		// Every member of a cycle is refreshed once per iteration, reading the values of the previous iteration
+		iterations, err := graph.cycleIterations(key, members)
+		if err != nil {
+			return nil, err
+		}
+		for iteration := 1; iteration <= iterations; iteration++ {
+			for _, member := range members {
+				plan.Steps = append(plan.Steps, newRefreshStep(graph, member, levels[member], iteration, changes))
+			}
+		}
+		for _, member := range members {
+			planned[member] = true
+		}
+	}
+	markAsyncSteps(plan.Steps)
+	return plan, nil
+}
+
+// markAsyncSteps marks the steps of async columns, and the steps reading them since their ids are only known once they ran.
+// Members of a cycle may read a later step, so marks spread until nothing changes.
+func markAsyncSteps(steps []RefreshStep) {
+	async := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for i := range steps {
+			step := &steps[i]
+			if step.Async {
+				continue
+			}
+			step.Async = step.DynamicColumn.RefreshMode == constants.RefreshModeAsync ||
+				slices.ContainsFunc(step.Sources, func(source RefreshSource) bool { return async[source.From] })
+			if step.Async {
+				async[step.Column] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// markInDatabaseSteps marks the steps of trigger mode columns, their triggers refreshed them when the changes were written.
+// Their ids are still resolved since the steps reading them need them.
+func markInDatabaseSteps(steps []RefreshStep) {
+	for i := range steps {
+		steps[i].InDatabase = steps[i].DynamicColumn.RefreshMode == constants.RefreshModeTrigger
+	}
+}
+
+// affectedColumns returns the dynamic columns reading the changes, then the ones reading those columns, and so on
+func affectedColumns(columns []DynamicColumn, changes map[constants.TableName]Dependency) []DynamicColumn {
+	result := make([]DynamicColumn, 0)
+	affected := make(map[string]bool)
+
+	currentChanges := changes
+	for len(currentChanges) > 0 {
+		// Changes of every column found in this round are propagated together
+		nextChanges := make(map[constants.TableName]Dependency)
+		for _, col := range columns {
+			// View mode columns are refreshed with their view, never row by row, and virtual columns are never stored
+			key := columnKey(col)
+			if affected[key] || !col.IsStored() || !readsChanges(col, currentChanges) {
+				continue
+			}
+			affected[key] = true
+			result = append(result, col)
+
+			change := nextChanges[col.TableName]
+			change.Columns = append(change.Columns, col.Name)
+			nextChanges[col.TableName] = change
+		}
+		currentChanges = nextChanges
+	}
+	return result
+}
+
+// readsChanges checks whether a dynamic column depends on one of the changed columns
+func readsChanges(col DynamicColumn, changes map[constants.TableName]Dependency) bool {
+	for changedTable, change := range changes {
+		dep, exists := col.Dependencies[changedTable]
+		if exists && len(utils.StringSlicesIntersect(dep.Columns, change.Columns)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newRefreshStep builds the step of a column, its ids come from the changed rows it reads and from the steps of the dynamic columns it reads
+func newRefreshStep(graph *dependencyGraph, key string, level int, iteration int, changes map[constants.TableName]Dependency) RefreshStep {
+	col := graph.columns[key]
+	sources := make([]RefreshSource, 0)
+
+	changedTables := make([]constants.TableName, 0, len(changes))
+	for changedTable := range changes {
+		changedTables = append(changedTables, changedTable)
+	}
+	slices.Sort(changedTables)
+	for _, changedTable := range changedTables {
+		dep, exists := col.Dependencies[changedTable]
+		if exists && len(utils.StringSlicesIntersect(dep.Columns, changes[changedTable].Columns)) > 0 {
+			sources = append(sources, RefreshSource{From: string(changedTable), Selector: dep.RecordIdsSelector})
+		}
+	}
+
+	for _, depKey := range graph.edges[key] {
+		depTable := graph.columns[depKey].TableName
+		sources = append(sources, RefreshSource{From: depKey, Selector: col.Dependencies[depTable].RecordIdsSelector})
+	}
+
+	return RefreshStep{
+		Column:        key,
+		Level:         level,
+		Iteration:     iteration,
+		Sources:       sources,
+		DynamicColumn: col,
+	}
+}
+
+// resolveRefreshPlanIds resolves the rows to refresh of every step, from the changed ids or from the rows of earlier steps.
+// For example:
+// 1. if invoice.status changes, invoice.status is refreshed for the changed invoice ids.
+// 2. company.status reads invoice.status, its company ids are selected from the invoice ids of the invoice.status step.
+func (r *dynamicColumnService) resolveRefreshPlanIds(ctx context.Context, plan *RefreshPlan, ids []int64, originalRecordId *int64) error {
+	changedIds := slices.Clone(ids)
+	if originalRecordId != nil {
+		changedIds = utils.AppendUnique(changedIds, *originalRecordId)
+	}
+
+	// Source rows by changed table name or "table.column" of the planned steps
+	resolved := map[string][]int64{string(plan.Table): changedIds}
+
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		stepIds := make([]int64, 0)
+		for _, source := range step.Sources {
+			sourceIds, err := r.resolveSelectorIds(ctx, resolved[source.From], source.Selector)
+			if err != nil {
+				return fmt.Errorf("resolving ids of %s from %s: %w", step.Column, source.From, err)
+			}
+			stepIds = utils.AppendUnique(stepIds, sourceIds...)
+		}
+		step.Ids = stepIds
+		step.FanOut = len(stepIds)
+		resolved[step.Column] = utils.AppendUnique(resolved[step.Column], stepIds...)
+	}
+	return nil
+}
+
+// resolveSelectorIds maps source row ids to the ids of the rows to refresh with a dependency record selector.
+// An empty selector means the source rows are the rows to refresh.
+func (r *dynamicColumnService) resolveSelectorIds(ctx context.Context, ids []int64, selector string) ([]int64, error) {
+	if len(ids) == 0 {
+		return []int64{}, nil
+	}
+	if selector == "" {
+		return ids, nil
+	}
+
+	err := r.dynamicColumnRepo.CopyIdsToTempTable(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	foundIds, err := r.dynamicColumnRepo.GetAllSelectorIds(ctx, selector, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = r.dynamicColumnRepo.TruncateTempTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return foundIds, nil
+}
+
+// executeRefreshPlan refreshes the steps of a resolved plan in order, and reports the values they changed
+func (r *dynamicColumnService) executeRefreshPlan(ctx context.Context, plan *RefreshPlan) (*RefreshResult, error) {
+	logPayload := r.GetLogPayload(ctx)
+	result := newRefreshResult()
+
+	for _, step := range plan.Steps {
+		if len(step.Ids) == 0 || step.InDatabase {
+			continue
+		}
+		err := r.dynamicColumnRepo.CopyIdsToTempTable(ctx, step.Ids)
+		if err != nil {
+			(*logPayload)["error"] = fmt.Sprintf("Error copying ids to temp ids table: %v", err)
+			return nil, err
+		}
+		changes, err := r.refreshColumn(ctx, DynamicColumnWithMetadata{DynamicColumn: step.DynamicColumn, Ids: step.Ids})
+		if err != nil {
+			(*logPayload)["error"] = fmt.Sprintf("Error refreshing dynamic column %s: %v", step.Column, err)
+			return nil, err
+		}
+		result.add(step.DynamicColumn, changes)
+		err = r.dynamicColumnRepo.TruncateTempTable(ctx)
+		if err != nil {
+			(*logPayload)["error"] = fmt.Sprintf("Error truncating temp ids table: %v", err)
+			return nil, err
+		}
+	}
+	return result.finish(), nil
+}
@@ -0,0 +1,171 @@
+package dynamiccolumn
+
+import (
+	"errors"
+	"fmt"
+	"gin-demo/internal/shared/constants"
+	"slices"
+	"testing"
+)
+
+// stepNames lists the steps as "column@level#iteration", with an "async" or "db" suffix for the marked ones
+func stepNames(steps []RefreshStep) []string {
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		name := fmt.Sprintf("%s@%d#%d", step.Column, step.Level, step.Iteration)
+		if step.Async {
+			name += " async"
+		}
+		if step.InDatabase {
+			name += " db"
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func withMode(col DynamicColumn, mode constants.RefreshMode) DynamicColumn {
+	col.RefreshMode = mode
+	return col
+}
+
+func TestBuildRefreshPlan(t *testing.T) {
+	invoiceStatus := testColumn("invoice.status", 0, "invoice.paid_at")
+	contractStatus := testColumn("contract.status", 0, "invoice.status")
+	companyStatus := testColumn("company.status", 0, "contract.status")
+
+	tests := []struct {
+		name    string
+		columns []DynamicColumn
+		changes map[constants.TableName]Dependency
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "unrelated change",
+			columns: []DynamicColumn{invoiceStatus, contractStatus},
+			changes: map[constants.TableName]Dependency{constants.TableNameInvoice: {Columns: []string{"amount"}}},
+			want:    []string{},
+		},
+		{
+			name:    "chain",
+			columns: []DynamicColumn{companyStatus, contractStatus, invoiceStatus},
+			changes: map[constants.TableName]Dependency{constants.TableNameInvoice: {Columns: []string{"paid_at"}}},
+			want:    []string{"invoice.status@0#1", "contract.status@1#1", "company.status@2#1"},
+		},
+		{
+			name:    "middle of a chain",
+			columns: []DynamicColumn{companyStatus, contractStatus, invoiceStatus},
+			changes: map[constants.TableName]Dependency{constants.TableNameInvoice: {Columns: []string{"status"}}},
+			want:    []string{"contract.status@0#1", "company.status@1#1"},
+		},
+		{
+			name: "same level sorted by name",
+			columns: []DynamicColumn{
+				testColumn("deployment.status", 0, "invoice.paid_at"),
+				testColumn("contract.status", 0, "invoice.paid_at"),
+			},
+			changes: map[constants.TableName]Dependency{constants.TableNameInvoice: {Columns: []string{"paid_at"}}},
+			want:    []string{"contract.status@0#1", "deployment.status@0#1"},
+		},
+		{
+			name: "not stored columns skipped",
+			columns: []DynamicColumn{
+				invoiceStatus,
+				withMode(contractStatus, constants.RefreshModeView),
+				withMode(testColumn("deployment.status", 0, "invoice.status"), constants.RefreshModeVirtual),
+			},
+			changes: map[constants.TableName]Dependency{constants.TableNameInvoice: {Columns: []string{"paid_at"}}},
+			want:    []string{"invoice.status@0#1"},
+		},
+		{
+			name: "async spreads to readers",
+			columns: []DynamicColumn{
+				withMode(invoiceStatus, constants.RefreshModeAsync),
+				contractStatus,
+				testColumn("deployment.status", 0, "invoice.paid_at"),
+			},
+			changes: map[constants.TableName]Dependency{constants.TableNameInvoice: {Columns: []string{"paid_at"}}},
+			want:    []string{"deployment.status@0#1", "invoice.status@0#1 async", "contract.status@1#1 async"},
+		},
+		{
+			name: "cycle repeated",
+			columns: []DynamicColumn{
+				invoiceStatus,
+				testColumn("contract.status", 2, "invoice.status", "company.status"),
+				testColumn("company.status", 3, "contract.status"),
+			},
+			changes: map[constants.TableName]Dependency{constants.TableNameInvoice: {Columns: []string{"paid_at"}}},
+			want: []string{
+				"invoice.status@0#1",
+				"company.status@1#1", "contract.status@1#1",
+				"company.status@1#2", "contract.status@1#2",
+			},
+		},
+		{
+			name: "cycle without max_iterations",
+			columns: []DynamicColumn{
+				invoiceStatus,
+				testColumn("contract.status", 0, "invoice.status", "company.status"),
+				testColumn("company.status", 0, "contract.status"),
+			},
+			changes: map[constants.TableName]Dependency{constants.TableNameInvoice: {Columns: []string{"paid_at"}}},
+			wantErr: ErrDependencyCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := buildRefreshPlan(tt.columns, constants.TableNameInvoice, tt.changes)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("buildRefreshPlan() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildRefreshPlan() returned %v", err)
+			}
+			got := stepNames(plan.Steps)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("buildRefreshPlan() steps = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRefreshPlanSources(t *testing.T) {
+	columns := []DynamicColumn{
+		testColumn("invoice.status", 0, "invoice.paid_at"),
+		testColumn("contract.status", 0, "invoice.status", "invoice.amount"),
+	}
+	changes := map[constants.TableName]Dependency{constants.TableNameInvoice: {Columns: []string{"paid_at", "amount"}}}
+	plan, err := buildRefreshPlan(columns, constants.TableNameInvoice, changes)
+	if err != nil {
+		t.Fatalf("buildRefreshPlan() returned %v", err)
+	}
+
+	want := [][]RefreshSource{
+		{{From: "invoice", Selector: "SELECT id FROM invoice"}},
+		{{From: "invoice", Selector: "SELECT id FROM invoice"}, {From: "invoice.status", Selector: "SELECT id FROM invoice"}},
+	}
+	if len(plan.Steps) != len(want) {
+		t.Fatalf("buildRefreshPlan() steps = %v, want %d steps", stepNames(plan.Steps), len(want))
+	}
+	for i, step := range plan.Steps {
+		if !slices.Equal(step.Sources, want[i]) {
+			t.Errorf("sources of %s = %v, want %v", step.Column, step.Sources, want[i])
+		}
+	}
+}
+
+func TestMarkInDatabaseSteps(t *testing.T) {
+	steps := []RefreshStep{
+		{Column: "invoice.status", DynamicColumn: withMode(testColumn("invoice.status", 0), constants.RefreshModeTrigger)},
+		{Column: "contract.status", DynamicColumn: testColumn("contract.status", 0)},
+	}
+	markInDatabaseSteps(steps)
+	want := []string{"invoice.status@0#0 db", "contract.status@0#0"}
+	if got := stepNames(steps); !slices.Equal(got, want) {
+		t.Fatalf("markInDatabaseSteps() = %v, want %v", got, want)
+	}
+}
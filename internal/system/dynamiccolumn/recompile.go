@@ -0,0 +1,90 @@
+package dynamiccolumn
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/shared/constants"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// FormulaRecompileResult lists the columns whose compiled formulas were out of date,
+// and the hand written ones that cannot be recompiled and do not return their changes
+type FormulaRecompileResult struct {
+	Recompiled []string `json:"recompiled"`
+	Stale      []string `json:"stale"`
+}
+
+/*
+* RecompileFormulas compiles the user formula of every stored and virtual dynamic column again with the current templates,
+* and saves the columns whose compiled formulas changed, e.g. compiled before FORMULA_TEMPLATE returned the changes
+* the history, the webhooks and the refresh results are built from, or before VIRTUAL_TEMPLATE only computed the rows read.
+* Triggers of trigger mode columns are reinstalled. View mode columns keep the definition of their materialized views.
+* A hand written formula without a user formula is reported as stale when it does not return its changes.
+* Everything commits in one transaction, ctx must carry the root database connection.
+ */
+func (r *dynamicColumnService) RecompileFormulas(ctx context.Context) (*FormulaRecompileResult, error) {
+	result := &FormulaRecompileResult{Recompiled: make([]string, 0), Stale: make([]string, 0)}
+	err := r.GetDbTx(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := context.WithValue(ctx, config.ContextKeyDB, tx)
+		columns, err := r.dynamicColumnRepo.GetAll(txCtx)
+		if err != nil {
+			return err
+		}
+		for _, col := range columns {
+			if col.RefreshMode == constants.RefreshModeView {
+				continue
+			}
+			name := string(col.TableName) + "." + col.Name
+			if col.UserFormula == "" {
+				if col.IsStored() && !returnsChanges(col.Formula) {
+					result.Stale = append(result.Stale, name)
+				}
+				continue
+			}
+
+			compiled, err := r.compileDynamicColumn(&DynamicColumnCreateRequest{
+				TableName:     col.TableName,
+				Name:          col.Name,
+				Formula:       col.UserFormula,
+				Variables:     col.Variables,
+				Type:          col.Type,
+				DefaultValue:  col.DefaultValue,
+				MaxIterations: col.MaxIterations,
+				RefreshCron:   col.RefreshCron,
+				RefreshMode:   col.RefreshMode,
+			})
+			if err != nil {
+				return fmt.Errorf("dynamic column %s: %w", name, err)
+			}
+			if compiled.Formula == col.Formula && compiled.TransitionFormula == col.TransitionFormula {
+				continue
+			}
+
+			col.Formula = compiled.Formula
+			col.TransitionFormula = compiled.TransitionFormula
+			col.Dependencies = compiled.Dependencies
+			_, err = r.dynamicColumnRepo.Update(txCtx, &col)
+			if err != nil {
+				return err
+			}
+			err = r.syncDbTriggers(txCtx, col)
+			if err != nil {
+				return fmt.Errorf("dynamic column %s: %w", name, err)
+			}
+			result.Recompiled = append(result.Recompiled, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// returnsChanges checks whether a compiled formula returns the rows it updated, see FORMULA_CHANGES_SELECT
+func returnsChanges(formula string) bool {
+	return strings.Contains(strings.ToUpper(formula), "RETURNING")
+}
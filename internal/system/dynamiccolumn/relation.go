@@ -0,0 +1,322 @@
+package dynamiccolumn
+
+import (
+	"cmp"
+	"fmt"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/types"
+	"gin-demo/internal/shared/utils"
+	"slices"
+	"strings"
+)
+
+// tableKey is the SQL name of a table placeholder, e.g. deployment_employee for deployment.employee
+// and company_via_contract for "company via contract"
+func tableKey(placeholder constants.TableName) string {
+	return strings.NewReplacer(".", "_", " ", "_").Replace(string(placeholder))
+}
+
+/*
+* resolveTableRef resolves a table placeholder from the root table.
+* The placeholder is a table, followed by named relations of the previous table separated by dots,
+* then by "via <table>" or "via <table>.<relation>" clauses choosing the route from the root table to the first table.
+* The tables reached through named relations or via clauses are aliased by the placeholder,
+* so two routes to the same table can be used in one formula.
+* Examples:
+* - for root table deployment, deployment.employee joins "employee deployment_employee" on deployment.employee_id
+* - for root table invoice, contract.company joins contract, then "company contract_company" on contract.company_id
+* - for root table employee, "company via contract" joins "company company_via_contract" after deployment and contract
+* - for root table employee, "approval via approval.requester" joins "approval approval_via_approval_requester" on approval.requester_employee_id
+ */
+func (r *dynamicColumnService) resolveTableRef(rootTable constants.TableName, placeholder string) (*TableRef, error) {
+	fields := strings.Fields(placeholder)
+	via := make([]constants.TableName, 0)
+	for i := 1; i < len(fields); i += 2 {
+		if fields[i] != "via" || i+1 >= len(fields) {
+			return nil, fmt.Errorf("invalid table reference %q, expected {{table via other_table}}", placeholder)
+		}
+		viaTable, viaRelation, isRelation := strings.Cut(fields[i+1], ".")
+		if _, exists := r.modelsMap[constants.TableName(viaTable)]; !exists {
+			return nil, fmt.Errorf("unknown table %q", viaTable)
+		}
+		if _, exists := utils.FindRelationByName(r.relationRegistry, constants.TableName(viaTable), viaRelation); isRelation && !exists {
+			return nil, fmt.Errorf("table %q has no relation %q", viaTable, viaRelation)
+		}
+		via = append(via, constants.TableName(fields[i+1]))
+	}
+	viaSuffix := ""
+	if len(fields) > 1 {
+		viaSuffix = " " + strings.Join(fields[1:], " ")
+	}
+
+	parts := strings.Split(fields[0], ".")
+	table := constants.TableName(parts[0])
+	if _, exists := r.modelsMap[table]; !exists {
+		return nil, fmt.Errorf("unknown table %q", table)
+	}
+
+	links := make([]RelationLink, 0)
+	if table != rootTable || len(via) > 0 {
+		var err error
+		links, err = r.ResolveTablesRelationLink(rootTable, table, via)
+		if err != nil {
+			return nil, err
+		}
+		if len(via) > 0 {
+			links[len(links)-1].Alias = tableKey(constants.TableName(parts[0] + viaSuffix))
+		}
+	}
+
+	for i, name := range parts[1:] {
+		relation, exists := utils.FindRelationByName(r.relationRegistry, table, name)
+		if !exists {
+			return nil, fmt.Errorf("table %q has no relation %q", table, name)
+		}
+		links = append(links, RelationLink{
+			Table:    relation.TargetTable,
+			Relation: constants.TableRelationManyToOne,
+			Foreign:  relation,
+			Alias:    tableKey(constants.TableName(strings.Join(parts[:i+2], ".") + viaSuffix)),
+		})
+		table = relation.TargetTable
+	}
+
+	return &TableRef{Key: tableKey(constants.TableName(placeholder)), Table: table, Links: links}, nil
+}
+
+// relationPaths returns every path from a table to another table that visits no table twice, shortest first.
+// Tables related by several foreign keys are reached once by each of them.
+// A route through a junction table is kept once, as its many to many link.
+func (r *dynamicColumnService) relationPaths(from constants.TableName, to constants.TableName) [][]RelationLink {
+	cases := []constants.TableRelation{constants.TableRelationOneToMany, constants.TableRelationManyToOne, constants.TableRelationManyToMany}
+	routes := make(map[string][]RelationLink)
+	visited := map[constants.TableName]bool{from: true}
+
+	var walk func(table constants.TableName, links []RelationLink)
+	walk = func(table constants.TableName, links []RelationLink) {
+		for _, caseType := range cases {
+			for _, relatedTable := range r.modelRelationsMap[caseType][table] {
+				for _, link := range r.newRelationLinks(table, relatedTable, caseType) {
+					if visited[relatedTable] || (link.Via != "" && visited[link.Via]) {
+						continue
+					}
+					path := append(slices.Clone(links), link)
+					if relatedTable == to {
+						route := describePath(from, path)
+						if existing, exists := routes[route]; !exists || len(path) < len(existing) {
+							routes[route] = path
+						}
+						continue
+					}
+
+					visited[relatedTable] = true
+					if link.Via != "" {
+						visited[link.Via] = true
+					}
+					walk(relatedTable, path)
+					visited[relatedTable] = false
+					if link.Via != "" {
+						visited[link.Via] = false
+					}
+				}
+			}
+		}
+	}
+	walk(from, nil)
+
+	keys := make([]string, 0, len(routes))
+	for route := range routes {
+		keys = append(keys, route)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Or(cmp.Compare(strings.Count(a, "->"), strings.Count(b, "->")), cmp.Compare(a, b))
+	})
+	result := make([][]RelationLink, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, routes[key])
+	}
+	return result
+}
+
+// pathHop is a table of a relation path with the foreign key it is joined on
+type pathHop struct {
+	Table    constants.TableName
+	Relation types.Relation
+}
+
+// pathHops lists the tables of a relation path with their foreign key, junction tables included
+func pathHops(links []RelationLink) []pathHop {
+	hops := make([]pathHop, 0, len(links))
+	for _, link := range links {
+		if link.Via != "" {
+			hops = append(hops, pathHop{Table: link.Via, Relation: link.ViaForeign})
+		}
+		hops = append(hops, pathHop{Table: link.Table, Relation: link.Foreign})
+	}
+	return hops
+}
+
+// describePath names the tables of a relation path and every foreign key on the way,
+// e.g. "employee -> deployment (deployment.employee) -> contract (deployment.contract)"
+func describePath(from constants.TableName, links []RelationLink) string {
+	var sb strings.Builder
+	sb.WriteString(string(from))
+	for _, hop := range pathHops(links) {
+		fmt.Fprintf(&sb, " -> %s (%s.%s)", hop.Table, hop.Relation.Table, hop.Relation.Name)
+	}
+	return sb.String()
+}
+
+/*
+* passesThrough checks a relation path goes through the via clauses in order.
+* A via table matches a middle table of the path, e.g. contract for employee -> deployment -> contract -> company.
+* A via relation, written <table>.<relation>, matches the foreign key of any table of the path,
+* e.g. approval.requester for approval -> employee or employee -> approval.
+ */
+func passesThrough(links []RelationLink, via []constants.TableName) bool {
+	hops := pathHops(links)
+	next := 0
+	for i, hop := range hops {
+		if next == len(via) {
+			break
+		}
+		table, name, isRelation := strings.Cut(string(via[next]), ".")
+		if isRelation {
+			if hop.Relation.Table == constants.TableName(table) && hop.Relation.Name == name {
+				next++
+			}
+			continue
+		}
+		if i < len(hops)-1 && hop.Table == constants.TableName(table) {
+			next++
+		}
+	}
+	return next == len(via)
+}
+
+func joinTableNames(tables []constants.TableName, sep string) string {
+	names := make([]string, 0, len(tables))
+	for _, table := range tables {
+		names = append(names, string(table))
+	}
+	return strings.Join(names, sep)
+}
+
+// addDependency adds columns of a table to the dependencies, merging the record selector with the one of another path to the table
+func addDependency(dependencies map[constants.TableName]Dependency, table constants.TableName, selector string, columns ...string) {
+	dep, exists := dependencies[table]
+	dep.Columns = utils.AppendUnique(dep.Columns, columns...)
+	if exists {
+		dep.RecordIdsSelector = mergeSelectors(dep.RecordIdsSelector, selector)
+	} else {
+		dep.RecordIdsSelector = selector
+	}
+	dependencies[table] = dep
+}
+
+// addPathDependencies adds the dependencies of a column read along a relation path.
+// The column is a dependency of the last table, every table adds id and is_deleted, and the foreign keys
+// of every link are dependencies of the table holding them since they decide which rows are joined.
+// Every table selects the root records along the path, merged with the selectors of other paths reaching the table.
+func (r *dynamicColumnService) addPathDependencies(
+	dependencies map[constants.TableName]Dependency, rootTable constants.TableName, tableRef *TableRef, column string) {
+	addDependency(dependencies, rootTable, "", "is_deleted", "id")
+
+	for i, link := range tableRef.Links {
+		selector := r.buildPathDependencySelector(rootTable, tableRef.Links, i)
+		addDependency(dependencies, link.Table, selector, "is_deleted", "id")
+		if i == len(tableRef.Links)-1 {
+			addDependency(dependencies, link.Table, selector, column)
+		}
+
+		switch link.Relation {
+		case constants.TableRelationManyToOne:
+			prevTable, prevSelector := rootTable, ""
+			if i > 0 {
+				prevTable = tableRef.Links[i-1].Table
+				prevSelector = r.buildPathDependencySelector(rootTable, tableRef.Links, i-1)
+			}
+			addDependency(dependencies, prevTable, prevSelector, link.Foreign.Column)
+		case constants.TableRelationOneToMany:
+			addDependency(dependencies, link.Table, selector, link.Foreign.Column)
+		case constants.TableRelationManyToMany:
+			addDependency(dependencies, link.Via, r.buildJunctionDependencySelector(rootTable, tableRef.Links, i),
+				"is_deleted", "id", link.ViaForeign.Column, link.Foreign.Column)
+		}
+	}
+}
+
+// reverseLink returns the i-th link of a relation path walked the other way, towards the root table
+func reverseLink(links []RelationLink, i int, rootTable constants.TableName) RelationLink {
+	link := links[i]
+	result := RelationLink{Table: rootTable, Via: link.Via, Foreign: link.Foreign, ViaForeign: link.ViaForeign}
+	if i > 0 {
+		result.Table = links[i-1].Table
+		result.Alias = links[i-1].Alias
+	}
+	switch link.Relation {
+	case constants.TableRelationManyToOne:
+		result.Relation = constants.TableRelationOneToMany
+	case constants.TableRelationOneToMany:
+		result.Relation = constants.TableRelationManyToOne
+	case constants.TableRelationManyToMany:
+		result.Relation = constants.TableRelationManyToMany
+		result.Foreign, result.ViaForeign = link.ViaForeign, link.Foreign
+	}
+	return result
+}
+
+// reversePath returns the first n links of a relation path walked back to the root table
+func reversePath(links []RelationLink, n int, rootTable constants.TableName) []RelationLink {
+	result := make([]RelationLink, 0, n)
+	for j := n - 1; j >= 0; j-- {
+		result = append(result, reverseLink(links, j, rootTable))
+	}
+	return result
+}
+
+// buildPathDependencySelector selects the root records related to the temp ids of the i-th table of a relation path,
+// by joining the path back to the root table
+func (r *dynamicColumnService) buildPathDependencySelector(rootTable constants.TableName, links []RelationLink, i int) string {
+	return r.buildBackSelector(rootTable, links[i].Table, links[i].Alias, reversePath(links, i+1, rootTable))
+}
+
+// buildJunctionDependencySelector selects the root records related to the temp ids of the junction table of the i-th link
+func (r *dynamicColumnService) buildJunctionDependencySelector(rootTable constants.TableName, links []RelationLink, i int) string {
+	prev := RelationLink{Table: rootTable, Relation: constants.TableRelationManyToOne, Foreign: links[i].ViaForeign}
+	if i > 0 {
+		prev.Table = links[i-1].Table
+		prev.Alias = links[i-1].Alias
+	}
+	backLinks := append([]RelationLink{prev}, reversePath(links, i, rootTable)...)
+	return r.buildBackSelector(rootTable, links[i].Via, "", backLinks)
+}
+
+// buildBackSelector joins the rows of a table matching the temp ids back to the root table with inner joins
+func (r *dynamicColumnService) buildBackSelector(rootTable constants.TableName, table constants.TableName, alias string, backLinks []RelationLink) string {
+	from, name := string(table), string(table)
+	if alias != "" {
+		from, name = from+" "+alias, alias
+	}
+	joinStms := r.createJoinStmFromCteLinks(backLinks, constants.TableName(name))
+	joinStr := fmt.Sprintf("SELECT DISTINCT %s.id FROM %s tdi JOIN %s ON %s.id = tdi.id %s", rootTable, constants.TEMP_TABLE_NAME, from, name, strings.Join(joinStms, " "))
+	return strings.ReplaceAll(joinStr, "LEFT JOIN", "JOIN") // Use INNER JOIN for selector to ensure only matching records are returned
+}
+
+// mergeSelectors combines the record selectors of two paths to the same table.
+// An empty selector means the changed records are the records to refresh, it is kept as is when it is the only one.
+func mergeSelectors(selector string, other string) string {
+	if selector == other {
+		return selector
+	}
+	if selector == "" {
+		selector = fmt.Sprintf("SELECT tdi.id FROM %s tdi", constants.TEMP_TABLE_NAME)
+	}
+	if other == "" {
+		other = fmt.Sprintf("SELECT tdi.id FROM %s tdi", constants.TEMP_TABLE_NAME)
+	}
+	if slices.Contains(strings.Split(selector, " UNION "), other) {
+		return selector
+	}
+	return selector + " UNION " + other
+}
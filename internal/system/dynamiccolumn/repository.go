@@ -6,18 +6,36 @@ import (
 	"fmt"
 	"gin-demo/internal/shared/base"
 	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/metrics"
 	"gin-demo/internal/shared/types"
 	"gin-demo/internal/shared/utils"
 	"strings"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type DynamicColumnRepository interface {
-	GetAll(ctx context.Context) []DynamicColumn
+	GetAll(ctx context.Context) ([]DynamicColumn, error)
+	GetById(ctx context.Context, id int64) (*DynamicColumn, error)
 	Create(ctx context.Context, column *DynamicColumn) (*DynamicColumn, error)
+	Update(ctx context.Context, column *DynamicColumn) (*DynamicColumn, error)
+	Delete(ctx context.Context, id int64) error
+	GetAllRecordIds(ctx context.Context, table constants.TableName) ([]int64, error)
+	GetFirstRecordIds(ctx context.Context, table constants.TableName, limit int) ([]int64, error)
+	GetColumnDataType(ctx context.Context, table constants.TableName, column string) (string, error)
+	ExecDDL(ctx context.Context, statement string) error
+	CreateDdlLog(ctx context.Context, log *DynamicColumnDdlLog) error
+	GetAllDdlLogs(ctx context.Context) []DynamicColumnDdlLog
+	PreviewDynamicColumn(ctx context.Context, query string) ([]DynamicColumnPreviewRow, error)
 	GetRefreshRecordById(ctx context.Context, table constants.TableName, id int64) (interface{}, error)
 	GetRecordByDependency(ctx context.Context, dependency string) []DynamicColumn
-	RefreshDynamicColumn(ctx context.Context, col DynamicColumnWithMetadata) error
-	GetAllDependantsByChanges(ctx context.Context, table constants.TableName, changes map[constants.TableName]Dependency) []DynamicColumn
+	RefreshDynamicColumn(ctx context.Context, col DynamicColumnWithMetadata) ([]ValueChange, error)
+	DeleteTransitions(ctx context.Context, table constants.TableName, column string) error
+	GetInstalledTriggers(ctx context.Context, prefix string) ([]DynamicColumnTrigger, error)
+	RefreshView(ctx context.Context, view string) error
+	JoinComputedColumns(ctx context.Context, table constants.TableName) func(db *gorm.DB) *gorm.DB
+	GetAllDependantsByChanges(ctx context.Context, table constants.TableName, changes map[constants.TableName]Dependency) ([]DynamicColumn, error)
 	GetAllSelectorIds(ctx context.Context, querySelector string, ctxObj map[string]interface{}) ([]int64, error)
 	CreateTempIdsTable(ctx context.Context) error
 	CopyIdsToTempTable(ctx context.Context, ids []int64) error
@@ -37,14 +55,29 @@ func NewDynamicColumnRepository(modelsMap types.ModelsMap, modelRelationsMap typ
 	}
 }
 
-func (r *dynamicColumnRepository) GetAll(ctx context.Context) []DynamicColumn {
-	// Dummy data for illustration
-	return []DynamicColumn{}
+func (r *dynamicColumnRepository) GetAll(ctx context.Context) ([]DynamicColumn, error) {
+	tx := r.GetDbTx(ctx)
+	var columns []DynamicColumn
+	err := tx.Order("id").Find(&columns).Error
+	if err != nil {
+		return nil, err
+	}
+	return columns, nil
 }
 
-func (r *dynamicColumnRepository) GetAllDependantsByChanges(ctx context.Context, table constants.TableName, changes map[constants.TableName]Dependency) []DynamicColumn {
+func (r *dynamicColumnRepository) GetById(ctx context.Context, id int64) (*DynamicColumn, error) {
+	tx := r.GetDbTx(ctx)
+	var column DynamicColumn
+	err := tx.First(&column, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &column, nil
+}
+
+func (r *dynamicColumnRepository) GetAllDependantsByChanges(ctx context.Context, table constants.TableName, changes map[constants.TableName]Dependency) ([]DynamicColumn, error) {
 	if len(changes) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	tx := r.GetDbTx(ctx)
@@ -61,10 +94,10 @@ func (r *dynamicColumnRepository) GetAllDependantsByChanges(ctx context.Context,
 	query := fmt.Sprintf("SELECT * FROM dynamic_column WHERE dependencies ?| ARRAY[%s]", depTables)
 	err := tx.Raw(query).Scan(&columns).Error
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	return r.compareDepColumns(columns, changes)
+	return r.compareDepColumns(columns, changes), nil
 }
 
 /*
@@ -98,6 +131,89 @@ func (r *dynamicColumnRepository) Create(ctx context.Context, column *DynamicCol
 	return column, nil
 }
 
+func (r *dynamicColumnRepository) Update(ctx context.Context, column *DynamicColumn) (*DynamicColumn, error) {
+	tx := r.GetDbTx(ctx)
+
+	err := tx.Save(column).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return column, nil
+}
+
+func (r *dynamicColumnRepository) Delete(ctx context.Context, id int64) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Delete(&DynamicColumn{}, id).Error
+}
+
+// GetAllRecordIds returns the ids of every non deleted record of a table
+func (r *dynamicColumnRepository) GetAllRecordIds(ctx context.Context, table constants.TableName) ([]int64, error) {
+	tx := r.GetDbTx(ctx)
+	var ids []int64
+	err := tx.Table(string(table)).Where("is_deleted = false").Order("id").Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetFirstRecordIds returns the ids of the first non deleted records of a table
+func (r *dynamicColumnRepository) GetFirstRecordIds(ctx context.Context, table constants.TableName, limit int) ([]int64, error) {
+	tx := r.GetDbTx(ctx)
+	var ids []int64
+	err := tx.Table(string(table)).Where("is_deleted = false").Order("id").Limit(limit).Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetColumnDataType returns the information_schema data type of a column, or "" when the column does not exist
+func (r *dynamicColumnRepository) GetColumnDataType(ctx context.Context, table constants.TableName, column string) (string, error) {
+	tx := r.GetDbTx(ctx)
+	var dataTypes []string
+	err := tx.Raw(`
+		SELECT data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?
+	`, string(table), column).Scan(&dataTypes).Error
+	if err != nil {
+		return "", err
+	}
+	if len(dataTypes) == 0 {
+		return "", nil
+	}
+	return dataTypes[0], nil
+}
+
+func (r *dynamicColumnRepository) ExecDDL(ctx context.Context, statement string) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Exec(statement).Error
+}
+
+func (r *dynamicColumnRepository) CreateDdlLog(ctx context.Context, log *DynamicColumnDdlLog) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Create(log).Error
+}
+
+func (r *dynamicColumnRepository) GetAllDdlLogs(ctx context.Context) []DynamicColumnDdlLog {
+	tx := r.GetDbTx(ctx)
+	var logs []DynamicColumnDdlLog
+	tx.Order("id").Find(&logs)
+	return logs
+}
+
+// PreviewDynamicColumn runs a compiled preview query, it reads the temp ids table and never writes
+func (r *dynamicColumnRepository) PreviewDynamicColumn(ctx context.Context, query string) ([]DynamicColumnPreviewRow, error) {
+	tx := r.GetDbTx(ctx)
+	rows := make([]DynamicColumnPreviewRow, 0)
+	err := tx.Raw(strings.Join(strings.Fields(query), " ")).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
 func (r *dynamicColumnRepository) GetRefreshRecordById(ctx context.Context, table constants.TableName, id int64) (interface{}, error) {
 	tx := r.GetDbTx(ctx)
 	modelType, exists := r.ModelsMap[table]
@@ -135,7 +251,7 @@ func (r *dynamicColumnRepository) CreateTempIdsTable(ctx context.Context) error
 	tx := r.GetDbTx(ctx)
 
 	err := tx.Exec(fmt.Sprintf(`
-		CREATE TEMP TABLE %s (
+		CREATE TEMP TABLE IF NOT EXISTS %s (
 			id BIGINT PRIMARY KEY
 		) ON COMMIT DROP;
 	`, constants.TEMP_TABLE_NAME)).Error
@@ -160,6 +276,7 @@ func (r *dynamicColumnRepository) CopyIdsToTempTable(ctx context.Context, ids []
 	if len(ids) == 0 {
 		return nil
 	}
+	metrics.TempTableCopyRows.Observe(float64(len(ids)))
 
 	tx := r.GetDbTx(ctx)
 
@@ -200,14 +317,127 @@ func (r *dynamicColumnRepository) CopyIdsToTempTable(ctx context.Context, ids []
 	return nil
 }
 
-func (r *dynamicColumnRepository) RefreshDynamicColumn(ctx context.Context, col DynamicColumnWithMetadata) error {
+// RefreshDynamicColumn computes the column for the rows of the temp ids table and returns the values it changed,
+// then stores their next transition when the column tracks transitions.
+// A formula compiled before FORMULA_TEMPLATE returned the changes returns none, the server recompiles them when it starts
+// and reports the hand written ones, see RecompileFormulas.
+func (r *dynamicColumnRepository) RefreshDynamicColumn(ctx context.Context, col DynamicColumnWithMetadata) ([]ValueChange, error) {
 	tx := r.GetDbTx(ctx)
+	labels := []string{string(col.TableName), col.Name}
+	start := time.Now()
+	defer func() {
+		metrics.RefreshDuration.WithLabelValues(labels...).Observe(metrics.Since(start))
+	}()
+
+	changes := make([]ValueChange, 0)
 	query := strings.Join(strings.Fields(col.Formula), " ")
-	err := tx.Exec(query).Error
+	err := tx.Raw(query).Scan(&changes).Error
 	if err != nil {
-		return err
+		metrics.RefreshFailures.WithLabelValues(labels...).Inc()
+		return nil, err
 	}
-	return nil
+	metrics.RowsExamined.WithLabelValues(labels...).Add(float64(len(col.Ids)))
+	metrics.RowsUpdated.WithLabelValues(labels...).Add(float64(len(changes)))
+	if col.TransitionFormula == "" {
+		return changes, nil
+	}
+	query = strings.Join(strings.Fields(col.TransitionFormula), " ")
+	err = tx.Exec(query).Error
+	if err != nil {
+		metrics.RefreshFailures.WithLabelValues(labels...).Inc()
+		return nil, err
+	}
+	return changes, nil
+}
+
+// DeleteTransitions removes the stored transitions of a dynamic column
+func (r *dynamicColumnRepository) DeleteTransitions(ctx context.Context, table constants.TableName, column string) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Table(constants.TRANSITION_TABLE_NAME).Where("table_name = ? AND column_name = ?", table, column).Delete(nil).Error
+}
+
+// GetInstalledTriggers returns the triggers whose name starts with prefix on the tables of the search path
+func (r *dynamicColumnRepository) GetInstalledTriggers(ctx context.Context, prefix string) ([]DynamicColumnTrigger, error) {
+	tx := r.GetDbTx(ctx)
+	triggers := make([]DynamicColumnTrigger, 0)
+	err := tx.Raw(`SELECT t.tgname AS name, c.relname AS table_name, p.proname AS function_name
+		FROM pg_trigger t
+		JOIN pg_class c ON c.oid = t.tgrelid
+		JOIN pg_proc p ON p.oid = t.tgfoid
+		WHERE starts_with(t.tgname, ?) AND NOT t.tgisinternal AND pg_table_is_visible(c.oid)
+		ORDER BY c.relname, t.tgname`, prefix).Scan(&triggers).Error
+	if err != nil {
+		return nil, err
+	}
+	return triggers, nil
+}
+
+// RefreshView recomputes a materialized view without locking out its readers, it must have a unique index
+func (r *dynamicColumnRepository) RefreshView(ctx context.Context, view string) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Exec(fmt.Sprintf("REFRESH MATERIALIZED VIEW CONCURRENTLY %s", view)).Error
+}
+
+/*
+* JoinComputedColumns returns a scope selecting the rows of a table with the value of every dynamic column of the table
+* that is not stored in it, so a model declaring the column as a read only field reads it like a stored one:
+* - a view mode column is left joined from its materialized view, rows created since its last refresh read NULL
+* - a virtual column is left joined from its compiled formula, computed by the query itself for the rows it reads only
+* The values are also selected together as the computed json object, for the models without a field for them.
+* Deleted rows read NULL. The columns are looked up when the query runs, an error of the lookup is the error of the query.
+ */
+func (r *dynamicColumnRepository) JoinComputedColumns(ctx context.Context, table constants.TableName) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		var columns []DynamicColumn
+		err := r.GetDbTx(ctx).
+			Where("table_name = ? AND refresh_mode IN ?", table, []constants.RefreshMode{constants.RefreshModeView, constants.RefreshModeVirtual}).
+			Order("name").Find(&columns).Error
+		if err != nil {
+			db.AddError(fmt.Errorf("loading the computed dynamic columns of %s: %w", table, err))
+			return db
+		}
+		return joinComputedColumns(db, table, columns)
+	}
+}
+
+// joinComputedColumns joins the view mode and virtual columns of a table to a query reading the table, see JoinComputedColumns.
+// A virtual column reads the ids of its rows from the query itself, with the joins and conditions it has so far.
+func joinComputedColumns(db *gorm.DB, table constants.TableName, columns []DynamicColumn) *gorm.DB {
+	if len(columns) == 0 {
+		return db
+	}
+	ids := db.Session(&gorm.Session{}).Select(string(table) + ".id")
+	// Without an order a limited copy may select other rows than the query, it then selects every row matching the conditions
+	if _, ordered := ids.Statement.Clauses["ORDER BY"]; !ordered {
+		delete(ids.Statement.Clauses, "LIMIT")
+	}
+
+	selects := []string{string(table) + ".*"}
+	computed := make([]string, 0, len(columns))
+	for _, col := range columns {
+		alias := string(col.RefreshMode) + "_" + col.Name
+		selects = append(selects, fmt.Sprintf("%s.%s", alias, col.Name))
+		computed = append(computed, fmt.Sprintf("'%s', %s.%s", col.Name, alias, col.Name))
+		if col.RefreshMode == constants.RefreshModeVirtual {
+			source := "(" + substituteIdsCte(col.Formula, alias+"_ids") + ")"
+			db = db.Joins(fmt.Sprintf("LEFT JOIN %s %s ON %s.id = %s.id", source, alias, alias, table), ids)
+			continue
+		}
+		db = db.Joins(fmt.Sprintf("LEFT JOIN %s %s ON %s.id = %s.id", viewName(col), alias, alias, table))
+	}
+	selects = append(selects, fmt.Sprintf("jsonb_build_object(%s) AS computed", strings.Join(computed, ", ")))
+	return db.Select(selects)
+}
+
+/*
+* substituteIdsCte makes the compiled formula of a virtual column read its ids from a leading CTE instead of the temp ids table,
+* the CTE being the ? placeholder bound to the query selecting them. It comes first so no question mark of the formula is bound instead.
+* Formulas compiled before virtual columns were scoped to the temp ids table ignore the CTE and compute every row.
+ */
+func substituteIdsCte(formula string, cte string) string {
+	body := strings.TrimPrefix(strings.TrimSpace(formula), "WITH")
+	body = strings.ReplaceAll(body, constants.TEMP_TABLE_NAME+" tdi", cte+" tdi")
+	return fmt.Sprintf("WITH %s AS (?),%s", cte, body)
 }
 
 func (r *dynamicColumnRepository) getSelectorQueries(columns []DynamicColumn, changes map[constants.TableName]Dependency) map[constants.TableName][]string {
@@ -240,7 +470,9 @@ func (r *dynamicColumnRepository) GetAllSelectorIds(ctx context.Context, querySe
 	var ids []sql.NullInt64
 	tx := r.GetDbTx(ctx)
 	query := utils.BuildFormulaSQL(querySelector, ctxObj)
+	start := time.Now()
 	err := tx.Raw(query).Scan(&ids).Error
+	metrics.SelectorDuration.Observe(metrics.Since(start))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,102 @@
+package dynamiccolumn
+
+import (
+	"database/sql"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/types"
+	"strings"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type testInvoice struct {
+	types.GormModel
+	ComputedValues
+	Status string `gorm:"column:status"`
+}
+
+func (testInvoice) TableName() string { return "invoice" }
+
+// newDryRunDB builds the statements of the queries without running them
+func newDryRunDB(t *testing.T) *gorm.DB {
+	sqlDB, err := sql.Open("pgx", "postgres://localhost/dry_run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		NamingStrategy:       schema.NamingStrategy{SingularTable: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestJoinComputedColumns(t *testing.T) {
+	columns := []DynamicColumn{
+		{Name: "overdue_days", TableName: constants.TableNameInvoice, RefreshMode: constants.RefreshModeView},
+		{Name: "score", TableName: constants.TableNameInvoice, RefreshMode: constants.RefreshModeVirtual, Formula: `
+WITH invoice_score AS (
+    SELECT invoice.id, CASE WHEN invoice.status = 'Why?' THEN 1 END AS score
+    FROM invoice
+    JOIN tmp_dynamiccolumn_ids tdi ON invoice.id = tdi.id
+    WHERE invoice.is_deleted = false
+)
+SELECT id, score FROM invoice_score
+`},
+	}
+	scope := func(db *gorm.DB) *gorm.DB { return joinComputedColumns(db, constants.TableNameInvoice, columns) }
+
+	tests := []struct {
+		name  string
+		query func(db *gorm.DB) *gorm.DB
+		want  []string
+	}{
+		{
+			name: "by id",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Scopes(scope).First(&testInvoice{}, 5)
+			},
+			want: []string{
+				`LEFT JOIN dynamic_column_view_invoice_overdue_days view_overdue_days ON view_overdue_days.id = invoice.id`,
+				`WITH virtual_score_ids AS (SELECT invoice.id FROM "invoice" WHERE "invoice"."id" = $1 ORDER BY "invoice"."id" LIMIT $2),`,
+				`JOIN virtual_score_ids tdi ON invoice.id = tdi.id`,
+				`invoice.status = 'Why?'`,
+				`jsonb_build_object('overdue_days', view_overdue_days.overdue_days, 'score', virtual_score.score) AS computed`,
+			},
+		},
+		{
+			name: "limited without order",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Scopes(scope).Where("status = ?", "Overdue").Limit(10).Find(&[]testInvoice{})
+			},
+			want: []string{
+				`WITH virtual_score_ids AS (SELECT invoice.id FROM "invoice" WHERE status = $1),`,
+				`WHERE status = $2 LIMIT $3`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query(newDryRunDB(t))
+			if query.Error != nil {
+				t.Fatalf("query returned %v", query.Error)
+			}
+			got := strings.Join(strings.Fields(query.Statement.SQL.String()), " ")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("query %s\ndoes not contain %s", got, want)
+				}
+			}
+			if strings.Contains(got, "tmp_dynamiccolumn_ids") {
+				t.Errorf("query %s\nstill reads the temp ids table", got)
+			}
+		})
+	}
+}
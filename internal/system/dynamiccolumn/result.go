@@ -0,0 +1,76 @@
+package dynamiccolumn
+
+import (
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/types"
+)
+
+/*
+* RefreshResult reports the values a refresh changed, column by column in the order they were first refreshed.
+* The steps of trigger mode columns run inside the database and the steps of async columns run later,
+* neither is reported.
+ */
+type RefreshResult struct {
+	RowsAffected int                         `json:"rows_affected"`
+	Columns      []types.ChangedDynamicField `json:"columns"`
+	// columns locates the entry of every column by column key, positions its changed rows by record id
+	columns   map[string]int
+	positions map[string]map[int64]int
+}
+
+func newRefreshResult() *RefreshResult {
+	return &RefreshResult{
+		Columns:   []types.ChangedDynamicField{},
+		columns:   make(map[string]int),
+		positions: make(map[string]map[int64]int),
+	}
+}
+
+// add merges the values a refresh of a column changed.
+// A row changed again, by the iterations of a cycle, keeps the value it had before its first change.
+func (r *RefreshResult) add(col DynamicColumn, changes []ValueChange) {
+	if len(changes) == 0 {
+		return
+	}
+	key := columnKey(col)
+	if _, exists := r.columns[key]; !exists {
+		r.columns[key] = len(r.Columns)
+		r.positions[key] = make(map[int64]int)
+		r.Columns = append(r.Columns, types.ChangedDynamicField{TableName: col.TableName, Column: col.Name, ChangedIds: []int64{}})
+	}
+	field := &r.Columns[r.columns[key]]
+	positions := r.positions[key]
+	for _, change := range changes {
+		if i, exists := positions[change.RecordId]; exists {
+			field.Values[i].NewValue = change.NewValue
+			continue
+		}
+		positions[change.RecordId] = len(field.ChangedIds)
+		field.ChangedIds = append(field.ChangedIds, change.RecordId)
+		field.Values = append(field.Values, types.ChangedDynamicValue{
+			RecordId: change.RecordId,
+			OldValue: change.OldValue,
+			NewValue: change.NewValue,
+		})
+		field.RowsAffected++
+		r.RowsAffected++
+	}
+}
+
+// finish drops the values of the columns changing more than REFRESH_RESULT_VALUES_LIMIT rows
+func (r *RefreshResult) finish() *RefreshResult {
+	for i := range r.Columns {
+		if len(r.Columns[i].Values) > constants.REFRESH_RESULT_VALUES_LIMIT {
+			r.Columns[i].Values = nil
+		}
+	}
+	return r
+}
+
+// ChangedDynamicFields returns the changed columns, for the changed_dynamic_fields of a response. It is nil safe.
+func (r *RefreshResult) ChangedDynamicFields() []types.ChangedDynamicField {
+	if r == nil {
+		return nil
+	}
+	return r.Columns
+}
@@ -0,0 +1,55 @@
+package dynamiccolumn
+
+import (
+	"context"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn/formula"
+	"regexp"
+)
+
+// Hand written formulas have no user formula to parse, their SQL is scanned for time functions instead
+var currentTimePattern = regexp.MustCompile(`(?i)\b(current_date|current_time|current_timestamp|localtime|localtimestamp)\b|\b(now|clock_timestamp|statement_timestamp|transaction_timestamp|timeofday)\s*\(`)
+
+// IsTimeDependent reports whether the value of the column changes with time alone, e.g. an invoice becoming overdue,
+// so it goes stale without any write to the tables it reads
+func (c DynamicColumn) IsTimeDependent() bool {
+	if c.UserFormula == "" {
+		return currentTimePattern.MatchString(c.Formula)
+	}
+
+	expr, err := formula.ParseExpression(c.UserFormula)
+	if err != nil {
+		return currentTimePattern.MatchString(c.Formula)
+	}
+	if formula.UsesCurrentTime(expr) {
+		return true
+	}
+	decls, err := formula.ParseVariables(c.Variables)
+	if err != nil {
+		return currentTimePattern.MatchString(c.Formula)
+	}
+	for _, decl := range decls {
+		if formula.UsesCurrentTime(decl.Expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetScheduledColumns returns the dynamic columns to refresh on a schedule: the time dependent columns, and the view mode
+// columns whose views go stale with every write. Columns tracking their transitions are refreshed row by row when a transition passes instead,
+// and virtual columns are computed when read.
+func (r *dynamicColumnService) GetScheduledColumns(ctx context.Context) ([]DynamicColumn, error) {
+	columns, err := r.dynamicColumnRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]DynamicColumn, 0)
+	for _, col := range columns {
+		if col.RefreshMode == constants.RefreshModeView || (col.IsStored() && col.IsTimeDependent() && col.TransitionFormula == "") {
+			result = append(result, col)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,110 @@
+package dynamiccolumn
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/internal/shared/constants"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Column names are written into DDL and formulas as is, so only plain lower case identifiers are accepted
+var columnNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// reservedColumnNames are the audit columns of types.GormModel and the column the virtual values are read into
+var reservedColumnNames = []string{"id", "created_at", "updated_at", "is_deleted", "computed"}
+
+// checkColumnName verifies a dynamic column may write the column of its name:
+// a lower case identifier that is neither a reserved column nor the foreign key of a relation of the table
+func (r *dynamicColumnService) checkColumnName(table constants.TableName, name string) error {
+	if !columnNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: column name %q must be a lower case identifier", ErrInvalidFormula, name)
+	}
+	if slices.Contains(reservedColumnNames, name) {
+		return fmt.Errorf("%w: column name %q is reserved", ErrInvalidFormula, name)
+	}
+	for _, relation := range r.relationRegistry {
+		if relation.Table == table && relation.Column == name {
+			return fmt.Errorf("%w: column %s.%s is the foreign key of relation %s", ErrInvalidFormula, table, name, relation.Name)
+		}
+	}
+	return nil
+}
+
+// schemaChange is a DDL statement issued for the target column of a dynamic column
+type schemaChange struct {
+	operation constants.DdlOperation
+	statement string
+}
+
+// addTargetColumn adds the physical column of a dynamic column with its declared type and default,
+// and an index when asked. Nothing is done when the column already exists.
+func (r *dynamicColumnService) addTargetColumn(ctx context.Context, col *DynamicColumn, withIndex bool) ([]schemaChange, error) {
+	dataType, err := r.dynamicColumnRepo.GetColumnDataType(ctx, col.TableName, col.Name)
+	if err != nil {
+		return nil, err
+	}
+	if dataType != "" {
+		return nil, nil
+	}
+
+	sqlType := constants.DYNAMIC_COLUMN_SQL_TYPES[constants.DynamicColumnType(col.Type)]
+	statement := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.TableName, col.Name, sqlType)
+	if col.DefaultValue != "" {
+		statement += fmt.Sprintf(" DEFAULT '%s'", strings.ReplaceAll(col.DefaultValue, "'", "''"))
+	}
+	changes := []schemaChange{{operation: constants.DdlOperationAddColumn, statement: statement}}
+	if withIndex {
+		changes = append(changes, schemaChange{
+			operation: constants.DdlOperationCreateIndex,
+			statement: fmt.Sprintf("CREATE INDEX idx_%s_%s ON %s(%s)", col.TableName, col.Name, col.TableName, col.Name),
+		})
+	}
+
+	for _, change := range changes {
+		err = r.dynamicColumnRepo.ExecDDL(ctx, change.statement)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", change.statement, err)
+		}
+	}
+	return changes, nil
+}
+
+// dropTargetColumn drops the physical column of a dynamic column, its indexes are dropped with it.
+// A view mode or a virtual column has no physical column, nothing is dropped.
+func (r *dynamicColumnService) dropTargetColumn(ctx context.Context, col *DynamicColumn) ([]schemaChange, error) {
+	if !col.IsStored() {
+		return nil, nil
+	}
+	change := schemaChange{
+		operation: constants.DdlOperationDropColumn,
+		statement: fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s", col.TableName, col.Name),
+	}
+	err := r.dynamicColumnRepo.ExecDDL(ctx, change.statement)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", change.statement, err)
+	}
+	return []schemaChange{change}, nil
+}
+
+// logSchemaChanges records the DDL statements issued for a dynamic column in the DDL log
+func (r *dynamicColumnService) logSchemaChanges(ctx context.Context, col *DynamicColumn, changes []schemaChange) error {
+	for _, change := range changes {
+		err := r.dynamicColumnRepo.CreateDdlLog(ctx, &DynamicColumnDdlLog{
+			DynamicColumnID: col.ID,
+			TableName:       col.TableName,
+			ColumnName:      col.Name,
+			Operation:       change.operation,
+			Statement:       change.statement,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *dynamicColumnService) GetAllDdlLogs(ctx context.Context) []DynamicColumnDdlLog {
+	return r.dynamicColumnRepo.GetAllDdlLogs(ctx)
+}
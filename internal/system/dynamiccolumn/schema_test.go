@@ -0,0 +1,98 @@
+package dynamiccolumn
+
+import (
+	"context"
+	"errors"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/types"
+	"strings"
+	"testing"
+)
+
+func TestCompileDynamicColumnName(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload DynamicColumnCreateRequest
+		wantErr string
+	}{
+		{
+			name:    "model column",
+			payload: DynamicColumnCreateRequest{Name: "ends_at", Formula: "NOW()", Type: "timestamp"},
+		},
+		{
+			name:    "new column",
+			payload: DynamicColumnCreateRequest{Name: "ends_soon", Formula: "true", Type: "bool", ManageColumn: true, CreateIndex: true},
+		},
+		{
+			name:    "invalid identifier",
+			payload: DynamicColumnCreateRequest{Name: "Ends_At", Formula: "NOW()", Type: "timestamp"},
+			wantErr: "must be a lower case identifier",
+		},
+		{
+			name:    "id",
+			payload: DynamicColumnCreateRequest{Name: "id", Formula: "1", Type: "int"},
+			wantErr: `column name "id" is reserved`,
+		},
+		{
+			name:    "is_deleted",
+			payload: DynamicColumnCreateRequest{Name: "is_deleted", Formula: "true", Type: "bool"},
+			wantErr: `column name "is_deleted" is reserved`,
+		},
+		{
+			name:    "created_at",
+			payload: DynamicColumnCreateRequest{Name: "created_at", Formula: "NOW()", Type: "timestamp", ManageColumn: true},
+			wantErr: `column name "created_at" is reserved`,
+		},
+		{
+			name:    "foreign key",
+			payload: DynamicColumnCreateRequest{Name: "company_id", Formula: "1", Type: "int"},
+			wantErr: "contract.company_id is the foreign key of relation company",
+		},
+		{
+			name:    "model column added",
+			payload: DynamicColumnCreateRequest{Name: "start_date", Formula: "CURRENT_DATE", Type: "date", ManageColumn: true},
+			wantErr: "contract.start_date is a column of the model, manage_column only adds new columns",
+		},
+		{
+			name:    "model column shadowed",
+			payload: DynamicColumnCreateRequest{Name: "start_date", Formula: "CURRENT_DATE", Type: "date", RefreshMode: constants.RefreshModeVirtual},
+			wantErr: "contract.start_date is a column of the model, a virtual column is not stored in its table",
+		},
+		{
+			name:    "index without manage_column",
+			payload: DynamicColumnCreateRequest{Name: "ends_at", Formula: "NOW()", Type: "timestamp", CreateIndex: true},
+			wantErr: "create_index only applies to the column added with manage_column",
+		},
+	}
+	r := &dynamicColumnService{
+		modelsMap:        types.ModelsMap{"contract": testContract{}},
+		relationRegistry: types.RelationRegistry{{Name: "company", Table: "contract", Column: "company_id", TargetTable: "company", TargetKey: "id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := tt.payload
+			payload.TableName = "contract"
+			_, err := r.compileDynamicColumn(&payload)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("compileDynamicColumn(%s) returned %v", payload.Name, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidFormula) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("compileDynamicColumn(%s) error = %v, want %q", payload.Name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDropTargetColumnNotStored(t *testing.T) {
+	// No DDL may run, the service has no repository to run it
+	r := &dynamicColumnService{}
+	for _, mode := range []constants.RefreshMode{constants.RefreshModeView, constants.RefreshModeVirtual} {
+		changes, err := r.dropTargetColumn(context.Background(), &DynamicColumn{TableName: "contract", Name: "ends_soon", RefreshMode: mode})
+		if err != nil || len(changes) != 0 {
+			t.Fatalf("dropTargetColumn() of a %s column = %v, %v, want no change", mode, changes, err)
+		}
+	}
+}
@@ -2,29 +2,85 @@ package dynamiccolumn
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"gin-demo/internal/shared/base"
 	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/metrics"
 	"gin-demo/internal/shared/types"
 	"gin-demo/internal/shared/utils"
+	"gin-demo/internal/system/dynamiccolumn/formula"
 	"log/slog"
-	"regexp"
+	"slices"
 	"strings"
+	"time"
 )
 
 type DynamicColumnService interface {
-	RefreshDynamicColumnsOfRecordIds(ctx context.Context, table constants.TableName, ids []int64, action constants.Action, originalRecordId *int64, actionPayload interface{}) error
+	RefreshDynamicColumnsOfRecordIds(ctx context.Context, table constants.TableName, ids []int64, action constants.Action, originalRecordId *int64, actionPayload interface{}) (*RefreshResult, error)
 	CheckShouldRefreshDynamicColumn(ctx context.Context, table constants.TableName, action constants.Action, payload interface{}) (bool, map[constants.TableName]Dependency)
-	BuildFormula(table constants.TableName, col string, userFormula string, userVars string) (string, error)
-	ResolveTablesRelationLink(comparor constants.TableName, target constants.TableName, prev []RelationLink, visited map[constants.TableName]bool) ([]RelationLink, error)
+	BuildFormula(payload *DynamicColumnCreateRequest) (string, error)
+	ResolveTablesRelationLink(from constants.TableName, to constants.TableName, via []constants.TableName) ([]RelationLink, error)
+	GetAll(ctx context.Context) ([]DynamicColumn, error)
+	GetById(ctx context.Context, id int64) (*DynamicColumn, error)
+	Validate(ctx context.Context, payload *DynamicColumnCreateRequest) (*DynamicColumn, error)
+	Preview(ctx context.Context, payload *DynamicColumnPreviewRequest) (*DynamicColumnPreview, error)
 	Create(ctx context.Context, payload *DynamicColumnCreateRequest) (*DynamicColumn, error)
+	Update(ctx context.Context, id int64, payload *DynamicColumnUpdateRequest) (*DynamicColumn, error)
+	Delete(ctx context.Context, id int64, dropColumn bool) error
+	GetAllDdlLogs(ctx context.Context) []DynamicColumnDdlLog
+	GetScheduledColumns(ctx context.Context) ([]DynamicColumn, error)
+	RefreshDynamicColumnOfRecordIds(ctx context.Context, col DynamicColumn, ids []int64) error
+	PlanRefresh(ctx context.Context, table constants.TableName, ids []int64, changes map[constants.TableName]Dependency, originalRecordId *int64) (*RefreshPlan, error)
+	GetRefreshPlan(ctx context.Context, payload *RefreshPlanRequest) (*RefreshPlan, error)
+	SetBackfillScheduler(scheduler BackfillScheduler)
+	SetRefreshQueue(queue RefreshQueue)
+	SetChangeCapture(capture ChangeCapture)
+	SetHistoryRecorder(recorder HistoryRecorder)
+	SetChangePublisher(publisher ChangePublisher)
+	RefreshDeferredChanges(ctx context.Context, table constants.TableName, ids []int64, columns []string) error
+	RefreshCapturedChanges(ctx context.Context, table constants.TableName, ids []int64, columns []string) error
+	GetRefreshModes(ctx context.Context) ([]DynamicColumnRefreshMode, error)
+	SyncDbTriggers(ctx context.Context) error
+	RefreshView(ctx context.Context, id int64) (*DynamicColumn, error)
+	RecompileFormulas(ctx context.Context) (*FormulaRecompileResult, error)
+}
+
+// BackfillScheduler schedules the computation of a dynamic column for every existing row of its table
+type BackfillScheduler interface {
+	ScheduleBackfill(ctx context.Context, col DynamicColumn) error
+}
+
+// RefreshQueue records, in the transaction of a change, the change whose async dynamic columns are refreshed later by a worker
+type RefreshQueue interface {
+	EnqueueRefresh(ctx context.Context, table constants.TableName, ids []int64, columns []string) error
+}
+
+// ChangeCapture watches the dependencies of the dynamic columns for writes made outside the application,
+// it is synced in the transaction of every change of a definition
+type ChangeCapture interface {
+	SyncTriggers(ctx context.Context) error
+}
+
+// HistoryRecorder records the values changed by every refresh with the change it was caused by, in the transaction of the refresh
+type HistoryRecorder interface {
+	RecordChanges(ctx context.Context, col DynamicColumn, changes []ValueChange, origin RefreshOrigin) error
+}
+
+// ChangePublisher publishes the values changed by every refresh to the subscribers of their column, in the transaction of the refresh
+type ChangePublisher interface {
+	PublishChanges(ctx context.Context, col DynamicColumn, changes []ValueChange, origin RefreshOrigin) error
 }
 
 type dynamicColumnService struct {
 	dynamicColumnRepo DynamicColumnRepository
 	modelsMap         types.ModelsMap
 	modelRelationsMap types.ModelRelationsMap
+	relationRegistry  types.RelationRegistry
+	backfillScheduler BackfillScheduler
+	refreshQueue      RefreshQueue
+	changeCapture     ChangeCapture
+	historyRecorder   HistoryRecorder
+	changePublisher   ChangePublisher
 	logger            *slog.Logger
 	base.BaseHelper
 }
@@ -32,15 +88,56 @@ type dynamicColumnService struct {
 func NewDynamicColumnService(dynamicColumnRepo DynamicColumnRepository,
 	modelsMap types.ModelsMap,
 	modelRelationsMap types.ModelRelationsMap,
+	relationRegistry types.RelationRegistry,
 ) DynamicColumnService {
 	return &dynamicColumnService{dynamicColumnRepo: dynamicColumnRepo,
 		modelsMap:         modelsMap,
 		modelRelationsMap: modelRelationsMap,
+		relationRegistry:  relationRegistry,
+	}
+}
+
+// SetBackfillScheduler makes Create and Update schedule a chunked backfill instead of
+// recomputing the whole table inside the current transaction, when the table has more than BACKFILL_SYNC_MAX_ROWS rows
+func (r *dynamicColumnService) SetBackfillScheduler(scheduler BackfillScheduler) {
+	r.backfillScheduler = scheduler
+}
+
+// SetRefreshQueue defers the steps of async dynamic columns to the queue.
+// Without a queue every dynamic column is refreshed in the transaction of the change.
+func (r *dynamicColumnService) SetRefreshQueue(queue RefreshQueue) {
+	r.refreshQueue = queue
+}
+
+// SetChangeCapture makes Create, Update and Delete sync the change capture with the new dependencies
+func (r *dynamicColumnService) SetChangeCapture(capture ChangeCapture) {
+	r.changeCapture = capture
+}
+
+// SetHistoryRecorder records the values changed by the refreshes run by the application.
+// The triggers of trigger mode columns write their changes to the history table themselves.
+func (r *dynamicColumnService) SetHistoryRecorder(recorder HistoryRecorder) {
+	r.historyRecorder = recorder
+}
+
+// SetChangePublisher publishes the values changed by the refreshes run by the application.
+// The triggers of trigger mode columns write the deliveries of their changes themselves.
+func (r *dynamicColumnService) SetChangePublisher(publisher ChangePublisher) {
+	r.changePublisher = publisher
+}
+
+// syncChangeCapture syncs the change capture when one is set
+func (r *dynamicColumnService) syncChangeCapture(ctx context.Context) error {
+	if r.changeCapture == nil {
+		return nil
 	}
+	return r.changeCapture.SyncTriggers(ctx)
 }
 
+// RefreshDynamicColumnsOfRecordIds refreshes the dynamic columns depending on a change of the records of the table,
+// and reports the values the refresh changed
 func (r *dynamicColumnService) RefreshDynamicColumnsOfRecordIds(
-	ctx context.Context, table constants.TableName, ids []int64, action constants.Action, originalRecordId *int64, actionPayload interface{}) error {
+	ctx context.Context, table constants.TableName, ids []int64, action constants.Action, originalRecordId *int64, actionPayload interface{}) (*RefreshResult, error) {
 	logPayload := r.GetLogPayload(ctx)
 	(*logPayload)["refresh_table"] = table
 	(*logPayload)["action_lead_to_refresh"] = action
@@ -52,55 +149,105 @@ func (r *dynamicColumnService) RefreshDynamicColumnsOfRecordIds(
 	(*logPayload)["changes"] = changes
 
 	if !shouldCheck {
-		return nil
+		return newRefreshResult(), nil
 	}
+	ctx = withRefreshOrigin(ctx, table, action, ids)
 
-	// Get all dynamic columns affected by the changes
-	dynamicCols := r.getAllDynamicColumnsFromChanges(ctx, table, changes)
-	if len(dynamicCols) == 0 {
-		return nil
+	// A refresh writes nothing, the triggers of trigger mode columns did not run
+	start := time.Now()
+	result, err := r.refreshDependantsOfChanges(ctx, table, ids, changes, originalRecordId, action != constants.ActionRefresh)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.ChangeRefreshes.WithLabelValues(string(table), string(action), outcome).Inc()
+	metrics.ChangeRefreshDuration.WithLabelValues(string(table), string(action)).Observe(metrics.Since(start))
+	if err != nil {
+		return nil, err
 	}
+	(*logPayload)["refresh_rows_affected"] = result.RowsAffected
+	return result, nil
+}
 
-	// Create a temp table to store ids that need refreshing
-	err := r.dynamicColumnRepo.CreateTempIdsTable(ctx)
+// refreshDependantsOfChanges refreshes every dynamic column that depends on the given changes of the table records.
+// written reports whether the changes were written to the database, the triggers of trigger mode columns then already refreshed them.
+func (r *dynamicColumnService) refreshDependantsOfChanges(
+	ctx context.Context, table constants.TableName, ids []int64, changes map[constants.TableName]Dependency, originalRecordId *int64, written bool) (*RefreshResult, error) {
+	logPayload := r.GetLogPayload(ctx)
+
+	columns, err := r.dynamicColumnRepo.GetAll(ctx)
 	if err != nil {
-		(*logPayload)["error"] = fmt.Sprintf("Error creating temp ids table: %v", err)
-		return err
+		(*logPayload)["error"] = fmt.Sprintf("Error loading dynamic columns: %v", err)
+		return nil, err
+	}
+	plan, err := buildRefreshPlan(columns, table, changes)
+	if err != nil {
+		(*logPayload)["error"] = fmt.Sprintf("Error planning refresh: %v", err)
+		return nil, err
+	}
+	if written {
+		markInDatabaseSteps(plan.Steps)
 	}
 
-	// Determine the order of refreshing dynamic columns based on their dependencies
-	orderedDynamicCols, err := r.determineRefreshOrder(ctx, table, ids, dynamicCols, originalRecordId)
+	// Async steps are neither resolved nor run here, the worker plans the change again and runs them
+	deferred := r.refreshQueue != nil && slices.ContainsFunc(plan.Steps, func(step RefreshStep) bool { return step.Async })
+	if deferred {
+		plan.Steps = slices.DeleteFunc(plan.Steps, func(step RefreshStep) bool { return step.Async })
+	}
+	err = r.resolveRefreshPlan(ctx, plan, ids, originalRecordId)
 	if err != nil {
-		return err
+		(*logPayload)["error"] = fmt.Sprintf("Error planning refresh: %v", err)
+		return nil, err
 	}
+	(*logPayload)["refresh_plan"] = plan
 
-	(*logPayload)["to_refresh"] = make(map[string]interface{})
+	result, err := r.executeRefreshPlan(ctx, plan)
+	if err != nil || !deferred {
+		return result, err
+	}
 
-	for _, col := range orderedDynamicCols {
-		toRefresh := (*logPayload)["to_refresh"].(map[string]interface{})
-		toRefresh[fmt.Sprintf("%s.%s", col.TableName, col.Name)] = len(col.Ids)
-		(*logPayload)["to_refresh"] = toRefresh
+	changedIds := slices.Clone(ids)
+	if originalRecordId != nil {
+		changedIds = utils.AppendUnique(changedIds, *originalRecordId)
+	}
+	(*logPayload)["refresh_deferred"] = true
+	err = r.refreshQueue.EnqueueRefresh(ctx, table, changedIds, changes[table].Columns)
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	for _, col := range orderedDynamicCols {
-		err := r.dynamicColumnRepo.CopyIdsToTempTable(ctx, col.Ids)
-		if err != nil {
-			(*logPayload)["error"] = fmt.Sprintf("Error copying ids to temp ids table: %v", err)
-			return err
-		}
-		err = r.dynamicColumnRepo.RefreshDynamicColumn(ctx, col)
-		if err != nil {
-			(*logPayload)["error"] = fmt.Sprintf("Error refreshing dynamic column %s.%s: %v", col.TableName, col.Name, err)
-			return err
-		}
-		err = r.dynamicColumnRepo.TruncateTempTable(ctx)
-		if err != nil {
-			(*logPayload)["error"] = fmt.Sprintf("Error truncating temp ids table: %v", err)
-			return err
-		}
+// RefreshDeferredChanges runs the async steps of the plan of changed columns of the records, for the refresh worker.
+// The sync steps already ran in the transaction of the change, their ids are still resolved since async steps may read them.
+func (r *dynamicColumnService) RefreshDeferredChanges(ctx context.Context, table constants.TableName, ids []int64, columns []string) error {
+	ctx = withRefreshOrigin(ctx, table, constants.ActionDeferred, ids)
+	changes := make(map[constants.TableName]Dependency)
+	r.addColumnsToDependency(changes, table, columns)
+
+	plan, err := r.PlanRefresh(ctx, table, ids, changes, nil)
+	if err != nil {
+		return err
 	}
+	plan.Steps = slices.DeleteFunc(plan.Steps, func(step RefreshStep) bool { return !step.Async })
+	markInDatabaseSteps(plan.Steps)
+	_, err = r.executeRefreshPlan(ctx, plan)
+	return err
+}
 
-	return nil
+// RefreshCapturedChanges refreshes the dependants of changed columns of the records written outside the application,
+// the same way as a change made through a domain service
+func (r *dynamicColumnService) RefreshCapturedChanges(ctx context.Context, table constants.TableName, ids []int64, columns []string) error {
+	logPayload := r.GetLogPayload(ctx)
+	(*logPayload)["refresh_table"] = table
+	(*logPayload)["action_lead_to_refresh"] = constants.ActionCapture
+
+	ctx = withRefreshOrigin(ctx, table, constants.ActionCapture, ids)
+	changes := make(map[constants.TableName]Dependency)
+	r.addColumnsToDependency(changes, table, columns)
+	(*logPayload)["changes"] = changes
+	_, err := r.refreshDependantsOfChanges(ctx, table, ids, changes, nil, true)
+	return err
 }
 
 // CheckShouldRefreshDynamicColumn checks if the action requires refreshing dynamic columns
@@ -141,212 +288,46 @@ func (r *dynamicColumnService) addColumnsToDependency(changes map[constants.Tabl
 	changes[table] = dep
 }
 
-func (r *dynamicColumnService) getAllDynamicColumnsFromChanges(ctx context.Context, table constants.TableName, changes map[constants.TableName]Dependency) []DynamicColumn {
-	var result []DynamicColumn
-	currentChanges := changes
-	for {
-		dynamicColumns := r.dynamicColumnRepo.GetAllDependantsByChanges(ctx, table, currentChanges)
-		if len(dynamicColumns) == 0 {
-			break
-		}
-		result = append(result, dynamicColumns...)
-		for _, colChange := range dynamicColumns {
-			currentChanges = make(map[constants.TableName]Dependency)
-			tableChange := currentChanges[colChange.TableName]
-			tableChange.Columns = append(tableChange.Columns, colChange.Name)
-			currentChanges[colChange.TableName] = tableChange
-		}
-	}
-	return result
-}
-
-/*
-* determineRefreshOrder determines the sequence of refreshing dynamic columns based on their dependencies.
-* Params:
-* - table: the original table where the changes happened
-* - ids: the list of changed record IDs in the original table
-* - dynamicCols: the list of dynamic columns that need to be refreshed due to the changes of the original table record
-* - originalRecordId: the ID of the original changed record (if any)
- */
-func (r *dynamicColumnService) determineRefreshOrder(
-	ctx context.Context,
-	table constants.TableName,
-	ids []int64,
-	dynamicCols []DynamicColumn,
-	originalRecordId *int64,
-) ([]DynamicColumnWithMetadata, error) {
-	result := make([]DynamicColumnWithMetadata, 0)
-	processed := make(map[string]bool)
-	refreshColNames := r.buildRefreshColumnNames(dynamicCols)
-
-	// Use index-based loop so appending to dynamicCols extends the loop
-	for i := 0; i < len(dynamicCols); i++ {
-		col := dynamicCols[i]
-		colName := (string(col.TableName) + "." + col.Name)
-
-		// Skip if already processed
-		if processed[colName] {
-			continue
-		}
-
-		deps := r.extractDependencyColumnNames(col.Dependencies)
-
-		// Case 1: Independent column - no dependencies on columns being refreshed
-		// This column can be processed immediately because it doesn't wait for other dynamic columns.
-		// Example: A column that only depends on static fields (company.name, invoice.created_at)
-		if intersect := utils.StringSlicesIntersect(refreshColNames, deps); len(intersect) == 0 {
-			ids, err := r.resolveIdsFromOriginalTable(ctx, ids, col.Dependencies[table].RecordIdsSelector, originalRecordId)
-			if err != nil {
-				return nil, err
-			}
-			result = append(result, DynamicColumnWithMetadata{
-				DynamicColumn: col,
-				Ids:           ids,
-			})
-			processed[colName] = true
-			continue
-		}
-
-		// Case 2: Dependencies satisfied - at least one dependency has been processed
-		// This column can now be calculated because the columns it depends on are ready.
-		// Example: companies.status depends on invoices.status (which was just calculated)
-		// We resolve IDs by querying based on the already-processed dependency IDs
-		if intersect := r.getProcessedDependencies(deps, processed); len(intersect) > 0 {
-			ids := r.resolveIdsFromMatchingDependencies(ctx, col, result, intersect)
-			result = append(result, DynamicColumnWithMetadata{
-				DynamicColumn: col,
-				Ids:           ids,
-			})
-			processed[colName] = true
-			continue
-		}
-
-		// Case 3: Dependencies pending - defer until dependencies are processed
-		// This column depends on other dynamic columns that haven't been calculated yet.
-		// Push it to the end of the queue and try again later in the next iteration
-		dynamicCols = append(dynamicCols, col)
-	}
-	return result, nil
-}
-
-// getProcessedDependencies returns which dependencies have been processed
-func (r *dynamicColumnService) getProcessedDependencies(deps []string, processed map[string]bool) []string {
-	result := make([]string, 0)
-	for _, dep := range deps {
-		if processed[dep] {
-			result = append(result, dep)
-		}
-	}
-	return result
-}
-
-// buildRefreshColumnNames builds list of "table.column" names from dynamic columns
-func (r *dynamicColumnService) buildRefreshColumnNames(dynamicCols []DynamicColumn) []string {
-	refreshColNames := make([]string, 0, len(dynamicCols))
-	for _, col := range dynamicCols {
-		refreshColNames = append(refreshColNames, string(col.TableName)+"."+col.Name)
-	}
-	return refreshColNames
-}
-
-// extractDependencyColumnNames extracts all dependency column names in "table.column" format
-func (r *dynamicColumnService) extractDependencyColumnNames(dependencies map[constants.TableName]Dependency) []string {
-	deps := make([]string, 0)
-	for depTable, dep := range dependencies {
-		for _, depCol := range dep.Columns {
-			deps = append(deps, string(depTable)+"."+depCol)
-		}
-	}
-	return deps
+func (r *dynamicColumnService) BuildFormula(payload *DynamicColumnCreateRequest) (string, error) {
+	return r.buildFormulaFromTemplate(constants.FORMULA_TEMPLATE, payload)
 }
 
-// resolveIdsFromOriginalTable resolves IDs based on the original table and record selector
-// This is used for the first level of dynamic columns that directly depend on the original changed record
-// For example:
-// 1. if invoice.status changes, we use the id of the changed invoice.
-// 2. if invoice.status changes, due to this change, we also need to refresh companies.status. To get the list of company IDs to refresh, we use the record selector defined in the dependency which queries based on the changed invoice ID.
-// Params:
-// - table: the original changed table (e.g. invoice)
-// - ids: the list of changed record IDs (e.g. invoice IDs that changed)
-// - selector: the record selector defined in the dynamic column dependency
-// - originalRecordId: the ID of the original changed record (if any)
-func (r *dynamicColumnService) resolveIdsFromOriginalTable(ctx context.Context, ids []int64, selector string, originalRecordId *int64) ([]int64, error) {
-	// Build the list of IDs to process
-	result := make([]int64, 0)
-	if len(ids) > 0 {
-		result = append(result, ids...)
-	}
-	if originalRecordId != nil {
-		result = utils.AppendUnique(result, *originalRecordId)
-	}
-
-	// If no selector, return the IDs directly
-	if selector == "" {
-		return result, nil
-	}
+// buildFormulaFromTemplate compiles the user formula and variables into the CTEs and expression of a formula template.
+// The expression is cast to the declared type, when there is one, with the default value for NULL results.
+func (r *dynamicColumnService) buildFormulaFromTemplate(template string, payload *DynamicColumnCreateRequest) (string, error) {
+	table := payload.TableName
+	col := payload.Name
+	userFormula := payload.Formula
+	userVars := payload.Variables
 
-	r.dynamicColumnRepo.CopyIdsToTempTable(ctx, result)
-	foundIds, err := r.dynamicColumnRepo.GetAllSelectorIds(ctx, selector, nil)
-	r.dynamicColumnRepo.TruncateTempTable(ctx)
+	// Step 1: Parse and validate variables
+	vars, err := r.resolveVariables(userVars, table)
 	if err != nil {
-		return nil, err
-	}
-	if len(foundIds) > 0 {
-		return foundIds, nil
-	}
-	return []int64{}, nil
-}
-
-// resolveIdsFromMatchingDependencies resolves IDs from matching dependencies already in result
-func (r *dynamicColumnService) resolveIdsFromMatchingDependencies(ctx context.Context, col DynamicColumn, result []DynamicColumnWithMetadata, matchNames []string) []int64 {
-	payload := *r.GetLogPayload(ctx)
-	ids := make([]int64, 0)
-
-	for _, matchName := range matchNames {
-		for _, depCol := range result {
-			if matchName != string(depCol.TableName)+"."+depCol.Name {
-				continue
-			}
-
-			query := col.Dependencies[constants.TableName(depCol.TableName)].RecordIdsSelector
-
-			if query == "" {
-				ids = utils.AppendUnique(ids, depCol.Ids...)
-			} else {
-				r.dynamicColumnRepo.CopyIdsToTempTable(ctx, depCol.Ids)
-				foundIds, err := r.dynamicColumnRepo.GetAllSelectorIds(ctx, query, nil)
-				r.dynamicColumnRepo.TruncateTempTable(ctx)
-				if err != nil {
-					payload["error"] = fmt.Sprintf("Error getting selector ids for %s.%s: %v", depCol.TableName, depCol.Name, err)
-				}
-				if len(foundIds) > 0 {
-					ids = utils.AppendUnique(ids, foundIds...)
-				}
-			}
-		}
+		return "", err
 	}
 
-	return ids
-}
-
-func (r *dynamicColumnService) BuildFormula(table constants.TableName, col string, userFormula string, userVars string) (string, error) {
-	// Step 1: Validate and parse variables
-	vars, err := r.resolveVariables(userVars)
+	// Step 2: Resolve formula and related tables
+	resolvedFormula, relatedTables, err := r.resolveFormula(userFormula, table, vars)
 	if err != nil {
 		return "", err
 	}
 
-	// Step 2: Resolve formula and related tables
-	resolvedFormula, relatedTables := r.resolveFormula(userFormula, table, vars)
+	if payload.Type != "" {
+		resolvedFormula, err = castFormula(resolvedFormula, payload.Type, payload.DefaultValue)
+		if err != nil {
+			return "", err
+		}
+	}
 
-	// Step 3: Resolve CTEs for related tables
-	resolvedCtes, err := r.resolveCte(relatedTables, table, vars)
+	// Step 3: Resolve CTEs for related tables, for the rows of the temp ids table when the template reads them
+	scoped := strings.Contains(template, constants.TEMP_TABLE_NAME)
+	resolvedCtes, err := r.resolveCte(relatedTables, table, vars, scoped)
 	if err != nil {
 		return "", err
 	}
 
 	// Step 4: Build final formula string from template and built components
-	res := constants.FORMULA_TEMPLATE
+	res := template
 	res = strings.ReplaceAll(res, "{{t_name}}", string(table))
 	res = strings.ReplaceAll(res, "{{c_name}}", col)
 	res = strings.ReplaceAll(res, "{{formula}}", resolvedFormula)
@@ -355,100 +336,263 @@ func (r *dynamicColumnService) BuildFormula(table constants.TableName, col strin
 	return res, nil
 }
 
-// resolveVariables parses the variable definitions from a string and returns a slice of Variable structs
-func (r *dynamicColumnService) resolveVariables(varStr string) ([]Variable, error) {
+// resolveVariables parses the variable definitions from a string and returns a slice of Variable structs.
+// A variable either aggregates columns of related tables, or combines other variables and root table columns.
+// Variables are returned in dependency order, whatever their definition order.
+func (r *dynamicColumnService) resolveVariables(varStr string, rootTable constants.TableName) ([]Variable, error) {
 	res := make([]Variable, 0)
-	if varStr == "" {
-		return res, nil
+
+	decls, err := formula.ParseVariables(varStr)
+	if err != nil {
+		return nil, fmt.Errorf("variables: %w", err)
 	}
 
-	splitVarStr := strings.Split(varStr, "\n")
+	// Inside the variable CTE the related table is selected under its own name, or its alias for a named relation
+	renderer := &formula.Renderer{
+		TableColumn: func(ref *formula.TableColumnRef) string {
+			return tableKey(constants.TableName(ref.Table)) + "." + ref.Column
+		},
+	}
 
-	for _, i := range splitVarStr {
-		i = strings.TrimSpace(i)
-		if i == "" {
-			continue
+	defined := make(map[string]bool)
+	for _, decl := range decls {
+		if defined[decl.Name] {
+			return nil, fmt.Errorf("variables: %w", formula.Errorf(decl.NamePos, "variable %q is already defined", decl.Name))
 		}
+		defined[decl.Name] = true
+	}
 
-		if !strings.HasPrefix(i, "var") {
-			return nil, errors.New("Variable definition error: No 'var' keyword")
+	for _, decl := range decls {
+		varRefs, err := r.resolveVariableReferences(decl.Expr, defined)
+		if err != nil {
+			return nil, fmt.Errorf("variables: %w", err)
 		}
 
-		if !strings.Contains(i, "=") {
-			return nil, errors.New("Variable definition error: No assign operator '='")
+		refs := formula.TableColumnRefs(decl.Expr)
+		if len(refs) == 0 && len(varRefs) == 0 {
+			return nil, fmt.Errorf("variables: %w", formula.Errorf(decl.Pos, "variable %q must reference a table column, e.g. COUNT({{table}}.id)", decl.Name))
+		}
+		tables := make([]constants.TableName, 0)
+		for _, ref := range refs {
+			if _, err := r.resolveTableRef(rootTable, ref.Table); err != nil {
+				return nil, fmt.Errorf("variables: %w", formula.Errorf(ref.Pos, "%s", err))
+			}
+			// A variable combining other variables is computed on the root row, it cannot aggregate other tables
+			if len(varRefs) > 0 && constants.TableName(ref.Table) != rootTable {
+				return nil, fmt.Errorf("variables: %w", formula.Errorf(ref.Pos,
+					"variable %q uses other variables so it can only read columns of %q, not %q", decl.Name, rootTable, ref.Table))
+			}
+			tables = utils.AppendUnique(tables, constants.TableName(ref.Table))
+		}
+		if len(tables) == 0 {
+			tables = append(tables, rootTable)
 		}
 
-		var v Variable
-
-		re := regexp.MustCompile(`var\s+(.+?)\s*=\s*(.+)`)
+		res = append(res, Variable{
+			Name:   decl.Name,
+			Value:  renderer.Render(decl.Expr),
+			Table:  r.farthestTable(rootTable, tables),
+			Tables: tables,
+			Refs:   varRefs,
+			Expr:   decl.Expr,
+			Pos:    decl.Pos,
+		})
+	}
 
-		matches := re.FindStringSubmatch(i)
+	return r.orderVariables(res)
+}
 
-		if len(matches) != 3 {
-			return nil, errors.New("Variable definition error: Regex no match")
+// resolveVariableReferences returns the variables used by an expression.
+// Any other bare identifier is an undefined variable, except SQL value functions like CURRENT_DATE.
+func (r *dynamicColumnService) resolveVariableReferences(expr formula.Expr, defined map[string]bool) ([]string, error) {
+	varRefs := make([]string, 0)
+	for _, ident := range formula.Idents(expr) {
+		name := ident.Name()
+		if name == "" || formula.IsValueKeyword(name) {
+			continue
 		}
+		if !defined[name] {
+			return nil, formula.Errorf(ident.Pos, "undefined variable %q", name)
+		}
+		varRefs = utils.AppendUnique(varRefs, name)
+	}
+	return varRefs, nil
+}
+
+// orderVariables sorts variables so every variable comes after the variables it uses, and rejects circular references
+func (r *dynamicColumnService) orderVariables(vars []Variable) ([]Variable, error) {
+	byName := make(map[string]Variable)
+	for _, v := range vars {
+		byName[v.Name] = v
+	}
 
-		v.Name = matches[1]
-		vValue := matches[2]
-		valueRegex := regexp.MustCompile(`{{(\w+)}}\.\w+`)
+	res := make([]Variable, 0, len(vars))
+	done := make(map[string]bool)
+	path := make([]string, 0)
 
-		valueMatches := valueRegex.FindAllStringSubmatch(vValue, -1)
-		prev := valueMatches[0][1]
-		for i := 1; i < len(valueMatches); i++ {
-			if valueMatches[i][1] != prev {
-				return nil, errors.New("Variable definition error: Multiple table references in one variable is not supported")
+	var visit func(v Variable) error
+	visit = func(v Variable) error {
+		if done[v.Name] {
+			return nil
+		}
+		if i := slices.Index(path, v.Name); i >= 0 {
+			cycle := append(slices.Clone(path[i:]), v.Name)
+			return fmt.Errorf("variables: %w", formula.Errorf(v.Pos, "circular variable reference %s", strings.Join(cycle, " -> ")))
+		}
+		path = append(path, v.Name)
+		for _, ref := range v.Refs {
+			if err := visit(byName[ref]); err != nil {
+				return err
 			}
 		}
-		vValue = strings.ReplaceAll(vValue, "{{", "")
-		vValue = strings.ReplaceAll(vValue, "}}", "")
-		v.Value = vValue
-		v.Table = constants.TableName(valueMatches[0][1])
-
+		path = path[:len(path)-1]
+		done[v.Name] = true
 		res = append(res, v)
+		return nil
 	}
 
+	for _, v := range vars {
+		if err := visit(v); err != nil {
+			return nil, err
+		}
+	}
 	return res, nil
 }
 
-// resolveFormula replaces table and column placeholders in the formula string
-// placeholders are in the format {{table}}.column
-func (r *dynamicColumnService) resolveFormula(formulaStr string, table constants.TableName, vars []Variable) (string, RelatedTables) {
-	re := regexp.MustCompile(`{{(\w+)}}\.(\w+)`)
-	relatedTables := make(RelatedTables)
+// farthestTable returns the table with the longest relation path from the root table.
+// A variable aggregates the rows of that table, the other tables it references are joined along the way.
+// Example: for root table company, COUNT({{deployment}}.id) FILTER (WHERE {{contract}}.end_date > CURRENT_DATE) aggregates deployments.
+func (r *dynamicColumnService) farthestTable(rootTable constants.TableName, tables []constants.TableName) constants.TableName {
+	result := tables[0]
+	longest := -1
+	for _, table := range tables {
+		ref, err := r.resolveTableRef(rootTable, string(table))
+		if err != nil {
+			continue
+		}
+		length := len(ref.Links)
+		if length > longest {
+			result = table
+			longest = length
+		}
+	}
+	return result
+}
+
+// resolveFormula parses the formula and replaces table and column placeholders.
+// placeholders are in the format {{table}}.column, columns of other tables and variables are read from their CTE.
+func (r *dynamicColumnService) resolveFormula(formulaStr string, table constants.TableName, vars []Variable) (string, RelatedTables, error) {
+	expr, err := formula.ParseExpression(formulaStr)
+	if err != nil {
+		return "", nil, fmt.Errorf("formula: %w", err)
+	}
 
-	replacedFormula := re.ReplaceAllStringFunc(formulaStr, func(m string) string {
-		sub := re.FindStringSubmatch(m)
-		t := constants.TableName(sub[1])
-		col := sub[2]
-		res := string(t) + "." + col
-		if t != table {
-			relatedTables[t] = utils.AppendUnique(relatedTables[t], col)
-			res = "cte_" + res
+	for _, ref := range formula.TableColumnRefs(expr) {
+		err := r.checkTableReference(table, constants.TableName(ref.Table), ref.Pos)
+		if err != nil {
+			return "", nil, fmt.Errorf("formula: %w", err)
 		}
-		return res
-	})
+	}
+	for _, v := range vars {
+		for _, varTable := range v.Tables {
+			err := r.checkTableReference(table, varTable, v.Pos)
+			if err != nil {
+				return "", nil, fmt.Errorf("variables: %w", err)
+			}
+		}
+	}
+
+	varsByName := make(map[string]Variable)
+	defined := make(map[string]bool)
+	for _, v := range vars {
+		varsByName[v.Name] = v
+		defined[v.Name] = true
+	}
+	if _, err := r.resolveVariableReferences(expr, defined); err != nil {
+		return "", nil, fmt.Errorf("formula: %w", err)
+	}
+
+	relatedTables := make(RelatedTables)
+	var renderer *formula.Renderer
+	renderer = &formula.Renderer{
+		TableColumn: func(ref *formula.TableColumnRef) string {
+			t := constants.TableName(ref.Table)
+			if t == table {
+				return ref.Table + "." + ref.Column
+			}
+			relatedTables[t] = utils.AppendUnique(relatedTables[t], ref.Column)
+			return "cte_" + tableKey(t) + "." + ref.Column
+		},
+		Ident: func(ident *formula.Ident) string {
+			v, exists := varsByName[ident.Name()]
+			if exists && len(v.Refs) > 0 {
+				// Variables combining other variables are inlined on the root row
+				return "(" + renderer.Render(v.Expr) + ")"
+			}
+			if exists {
+				return "cte_" + v.cteKey() + "." + v.Name
+			}
+			return strings.Join(ident.Parts, ".")
+		},
+	}
+	replacedFormula := renderer.Render(expr)
 
+	// Variables over several tables get their own CTE, see resolveCte
 	for _, v := range vars {
-		relatedTables[v.Table] = utils.AppendUnique(relatedTables[v.Table], v.Name)
+		if len(v.Tables) == 1 && len(v.Refs) == 0 {
+			relatedTables[v.Table] = utils.AppendUnique(relatedTables[v.Table], v.Name)
+		}
 	}
 
-	return replacedFormula, relatedTables
+	return replacedFormula, relatedTables, nil
+}
+
+// checkTableReference verifies a referenced table exists and can be joined to the root table
+func (r *dynamicColumnService) checkTableReference(rootTable constants.TableName, table constants.TableName, pos formula.Position) error {
+	if _, err := r.resolveTableRef(rootTable, string(table)); err != nil {
+		return formula.Errorf(pos, "%s", err)
+	}
+	return nil
 }
 
-// resolveCte builds CTE strings for related tables
-func (r *dynamicColumnService) resolveCte(relatedTables RelatedTables, rootTable constants.TableName, vars []Variable) (*CteStrings, error) {
+// resolveCte builds CTE strings for related tables.
+// Columns and single table variables of a related table share one CTE, a variable over several tables has its own CTE
+// because the extra joins could change the rows the other variables aggregate.
+// scoped restricts the CTEs to the root rows of the temp ids table, they cover every root row otherwise.
+func (r *dynamicColumnService) resolveCte(relatedTables RelatedTables, rootTable constants.TableName, vars []Variable, scoped bool) (*CteStrings, error) {
 	ctes := make([]FormulaCte, 0)
-	for relatedTable, relatedCol := range relatedTables {
-		cteLinks, err := r.ResolveTablesRelationLink(rootTable, relatedTable, nil, nil)
+
+	// Sort tables so the compiled SQL is stable between runs
+	tables := make([]constants.TableName, 0, len(relatedTables))
+	for relatedTable := range relatedTables {
+		tables = append(tables, relatedTable)
+	}
+	slices.Sort(tables)
+
+	for _, relatedTable := range tables {
+		tableVars := make([]Variable, 0)
+		for _, v := range vars {
+			if len(v.Tables) == 1 && v.Table == relatedTable {
+				tableVars = append(tableVars, v)
+			}
+		}
+		cte, err := r.createCte(rootTable, tableKey(relatedTable), []constants.TableName{relatedTable}, relatedTables[relatedTable], tableVars, scoped)
 		if err != nil {
 			return nil, err
 		}
-		cte, err := r.createCte(rootTable, cteLinks, relatedCol, vars)
+		ctes = append(ctes, *cte)
+	}
+	for _, v := range vars {
+		if len(v.Tables) == 1 || len(v.Refs) > 0 {
+			continue
+		}
+		cte, err := r.createCte(rootTable, v.cteKey(), v.joinOrder(), []string{v.Name}, []Variable{v}, scoped)
 		if err != nil {
 			return nil, err
 		}
 		ctes = append(ctes, *cte)
 	}
+
 	result := &CteStrings{}
 	for _, cte := range ctes {
 		result.CteValues += cte.Value + ",\n"
@@ -457,232 +601,777 @@ func (r *dynamicColumnService) resolveCte(relatedTables RelatedTables, rootTable
 	return result, nil
 }
 
+/*
+* createCte builds a CTE selecting the columns and variables of the joined tables for every root record.
+* Params:
+* - key: suffix of the CTE name, the CTE is joined to the formula as cte_<key>
+* - tables: table placeholders to join to the root table, plain columns are read from the first one
+* - joinCols: plain columns and variable names to select
+* - scoped: joins the root table to the temp ids table, every root record is selected otherwise
+ */
 func (r *dynamicColumnService) createCte(
 	rootTable constants.TableName,
-	cteLinks []RelationLink,
+	key string,
+	tables []constants.TableName,
 	joinCols []string,
 	vars []Variable,
+	scoped bool,
 ) (*FormulaCte, error) {
 	var cte FormulaCte
-	joinTable := cteLinks[len(cteLinks)-1].Table
-	cte.Name = string(rootTable) + "_" + string(joinTable)
-	cte.Join = fmt.Sprintf("LEFT JOIN %s cte_%s ON cte_%s.id = %s.id", cte.Name, joinTable, joinTable, rootTable)
-	for i, col := range joinCols {
-		joinCols[i] = string(joinTable) + "." + col
-	}
-	selectCols := strings.Join(joinCols, ", ")
-	if selectCols != "" {
-		selectCols = ", " + selectCols
-	}
+	joinTable := tables[0]
+	cte.Name = string(rootTable) + "_" + key
+	cte.Join = fmt.Sprintf("LEFT JOIN %s cte_%s ON cte_%s.id = %s.id", cte.Name, key, key, rootTable)
+
+	tableVars := make(map[string]Variable)
 	for _, v := range vars {
-		selectCols = strings.ReplaceAll(selectCols, string(joinTable)+"."+v.Name, v.Value+" AS "+v.Name)
+		tableVars[v.Name] = v
 	}
 
+	// Variables are aggregated, plain columns are selected as is and grouped by
+	selectList := make([]string, 0, len(joinCols))
 	groupByCols := make([]string, 0)
-	for _, joinCol := range joinCols {
-		foundVar := false
-		for _, variable := range vars {
-			if string(variable.Table)+"."+variable.Name == joinCol {
-				foundVar = true
-				break
-			}
-		}
-		if !foundVar {
-			groupByCols = utils.AppendUnique(groupByCols, joinCol)
+	for _, col := range joinCols {
+		if v, isVar := tableVars[col]; isVar {
+			selectList = append(selectList, v.Value+" AS "+v.Name)
+			continue
 		}
+		qualifiedCol := tableKey(joinTable) + "." + col
+		selectList = append(selectList, qualifiedCol)
+		groupByCols = utils.AppendUnique(groupByCols, qualifiedCol)
+	}
+	selectCols := strings.Join(selectList, ", ")
+	if selectCols != "" {
+		selectCols = ", " + selectCols
 	}
 	groupByColsStr := strings.Join(groupByCols, ", ")
 	if groupByColsStr != "" {
 		groupByColsStr = ", " + groupByColsStr
 	}
 
+	joinStms, err := r.createJoinStmsForTables(rootTable, tables)
+	if err != nil {
+		return nil, err
+	}
 	selectId := rootTable + "." + "id"
-	joinStms := r.createJoinStmFromCteLinks(cteLinks, rootTable)
+	idsJoin := ""
+	if scoped {
+		idsJoin = fmt.Sprintf("JOIN %s tdi ON %s.id = tdi.id", constants.TEMP_TABLE_NAME, rootTable)
+	}
 	cte.Value = fmt.Sprintf(`
-			%s_%s AS (
+			%s AS (
 				SELECT %s %s
 				FROM %s
-				JOIN %s tdi ON %s.id = tdi.id
+				%s
 				%s
 				GROUP BY %s %s
 			)
-		`, rootTable, joinTable, selectId, selectCols, rootTable, constants.TEMP_TABLE_NAME, rootTable, strings.Join(joinStms, " \n"), selectId, groupByColsStr)
+		`, cte.Name, selectId, selectCols, rootTable, idsJoin, strings.Join(joinStms, " \n"), selectId, groupByColsStr)
 	return &cte, nil
 }
 
-func (r *dynamicColumnService) ResolveTablesRelationLink(comparor constants.TableName, target constants.TableName, prev []RelationLink, visited map[constants.TableName]bool) ([]RelationLink, error) {
-	if prev == nil {
-		prev = make([]RelationLink, 0)
+// createJoinStmsForTables joins every table placeholder to the root table along its relation path.
+// Tables shared by several paths, like contract on the way to deployment, are joined once.
+// A table the paths reach by different foreign keys cannot be shared and fails.
+func (r *dynamicColumnService) createJoinStmsForTables(rootTable constants.TableName, tables []constants.TableName) ([]string, error) {
+	joinStms := make([]string, 0)
+	joined := map[string][]RelationLink{string(rootTable): nil}
+	for _, table := range tables {
+		ref, err := r.resolveTableRef(rootTable, string(table))
+		if err != nil {
+			return nil, err
+		}
+		linkStms := r.createJoinStmFromCteLinks(ref.Links, rootTable)
+		for i, link := range ref.Links {
+			if prefix, exists := joined[link.name()]; exists {
+				if !slices.Equal(prefix, ref.Links[:i+1]) {
+					return nil, fmt.Errorf("table %q is joined by two paths in one variable: %s; %s", link.name(),
+						describePath(rootTable, prefix), describePath(rootTable, ref.Links[:i+1]))
+				}
+				continue
+			}
+			joined[link.name()] = ref.Links[:i+1]
+			joinStms = append(joinStms, linkStms[i])
+		}
 	}
+	return joinStms, nil
+}
 
-	// Build set of all visited tables including the start node
-	if visited == nil {
-		visited = make(map[constants.TableName]bool)
+// ResolveTablesRelationLink returns the relation path from a table to another table going through the via clauses, in order.
+// Every route between the tables is considered, so the path does not depend on the search order:
+// a table reached by several routes, or by several foreign keys, must be qualified with via, the error lists every candidate.
+func (r *dynamicColumnService) ResolveTablesRelationLink(from constants.TableName, to constants.TableName, via []constants.TableName) ([]RelationLink, error) {
+	candidates := make([][]RelationLink, 0)
+	for _, path := range r.relationPaths(from, to) {
+		if passesThrough(path, via) {
+			candidates = append(candidates, path)
+		}
 	}
-	visited[comparor] = true
 
-	for _, link := range prev {
-		visited[link.Table] = true
+	switch len(candidates) {
+	case 0:
+		if len(via) > 0 {
+			return nil, fmt.Errorf("table %q is not related to %q via %s", to, from, joinTableNames(via, ", "))
+		}
+		return nil, fmt.Errorf("table %q is not related to %q", to, from)
+	case 1:
+		return candidates[0], nil
 	}
 
-	cases := []constants.TableRelation{constants.TableRelationOneToMany, constants.TableRelationManyToOne}
-	for _, caseType := range cases {
-		relatedTables, exists := r.modelRelationsMap[caseType][comparor]
-		if exists {
-			// Direct match
-			if utils.SliceContains(relatedTables, target) {
-				prev = append(prev, RelationLink{
-					Table:    target,
-					Relation: caseType,
-				})
-				return prev, nil
-			}
+	descriptions := make([]string, 0, len(candidates))
+	for _, path := range candidates {
+		descriptions = append(descriptions, describePath(from, path))
+	}
+	return nil, fmt.Errorf("table %q is reached from %q by %d paths, qualify it with via, e.g. {{%s via %s}}: %s",
+		to, from, len(candidates), to, viaExample(candidates), strings.Join(descriptions, "; "))
+}
 
-			// Recursive search
-			for _, relatedTable := range relatedTables {
-				// Skip if already visited (prevent circular references)
-				if visited[relatedTable] {
-					continue
-				}
+// viaExample returns a via clause selecting the first candidate path: a middle table no other candidate goes through,
+// or else a relation no other candidate uses
+func viaExample(candidates [][]RelationLink) string {
+	others := make([]pathHop, 0)
+	for _, path := range candidates[1:] {
+		others = append(others, pathHops(path)...)
+	}
+	hops := pathHops(candidates[0])
+	for _, hop := range hops[:len(hops)-1] {
+		if !slices.ContainsFunc(others, func(other pathHop) bool { return other.Table == hop.Table }) {
+			return string(hop.Table)
+		}
+	}
+	for _, hop := range hops {
+		if !slices.Contains(others, hop) {
+			return string(hop.Relation.Table) + "." + hop.Relation.Name
+		}
+	}
+	return string(hops[0].Relation.Table) + "." + hops[0].Relation.Name
+}
 
-				tmpPrev := make([]RelationLink, len(prev))
-				copy(tmpPrev, prev)
-				tmpPrev = append(tmpPrev, RelationLink{
-					Table:    relatedTable,
-					Relation: caseType,
-				})
-				res, err := r.ResolveTablesRelationLink(relatedTable, target, tmpPrev, visited)
-				if err == nil && len(res) != 0 {
-					return res, nil
-				}
+// newRelationLinks builds a link from a table to a related table for every foreign key joining them,
+// e.g. an approval links to employee by its approver and by its requester
+func (r *dynamicColumnService) newRelationLinks(from constants.TableName, to constants.TableName, relation constants.TableRelation) []RelationLink {
+	links := make([]RelationLink, 0)
+	switch relation {
+	case constants.TableRelationManyToOne:
+		for _, foreign := range utils.FindRelations(r.relationRegistry, from, to) {
+			links = append(links, RelationLink{Table: to, Relation: relation, Foreign: foreign})
+		}
+	case constants.TableRelationOneToMany:
+		for _, foreign := range utils.FindRelations(r.relationRegistry, to, from) {
+			links = append(links, RelationLink{Table: to, Relation: relation, Foreign: foreign})
+		}
+	case constants.TableRelationManyToMany:
+		for _, junction := range utils.FindJunctionTables(r.relationRegistry, from, to) {
+			viaForeign := utils.FindRelations(r.relationRegistry, junction, from)
+			foreign := utils.FindRelations(r.relationRegistry, junction, to)
+			if len(viaForeign) == 1 && len(foreign) == 1 {
+				links = append(links, RelationLink{Table: to, Relation: relation, Via: junction, Foreign: foreign[0], ViaForeign: viaForeign[0]})
 			}
 		}
 	}
-	return nil, errors.New("No relation found")
+	return links
 }
 
+// createJoinStmFromCteLinks returns the join statement of every link of a relation path, on the foreign key of the link
 func (r *dynamicColumnService) createJoinStmFromCteLinks(cteLinks []RelationLink, rootTable constants.TableName) []string {
 	joinStms := make([]string, 0)
 	for i, cteLink := range cteLinks {
-		joinerCol := ""
-		joineeCol := ""
-		prevTableName := ""
-		if cteLink.Relation == constants.TableRelationManyToOne {
-			if i == 0 {
-				prevTableName = string(rootTable)
-			} else {
-				prevTableName = string(cteLinks[i-1].Table)
-			}
-			joinerCol = prevTableName + "." + string(cteLink.Table) + "_id"
-			joineeCol = string(cteLink.Table) + ".id"
-		}
-		if cteLink.Relation == constants.TableRelationOneToMany {
-			if i == 0 {
-				prevTableName = string(rootTable)
-			} else {
-				prevTableName = string(cteLinks[i-1].Table)
-			}
-			joinerCol = string(cteLink.Table) + "." + string(prevTableName) + "_id"
-			joineeCol = prevTableName + ".id"
+		prevTableName := string(rootTable)
+		if i > 0 {
+			prevTableName = cteLinks[i-1].name()
+		}
+		tableName := cteLink.name()
+		joinTable := string(cteLink.Table)
+		if cteLink.Alias != "" {
+			joinTable += " " + cteLink.Alias
 		}
-		joinStms = append(joinStms, fmt.Sprintf("LEFT JOIN %s ON %s = %s AND %s.is_deleted = false", cteLink.Table, joinerCol, joineeCol, cteLink.Table))
+
+		joinerCol := ""
+		joineeCol := ""
+		switch cteLink.Relation {
+		case constants.TableRelationManyToOne:
+			joinerCol = prevTableName + "." + cteLink.Foreign.Column
+			joineeCol = tableName + "." + cteLink.Foreign.TargetKey
+		case constants.TableRelationOneToMany:
+			joinerCol = tableName + "." + cteLink.Foreign.Column
+			joineeCol = prevTableName + "." + cteLink.Foreign.TargetKey
+		case constants.TableRelationManyToMany:
+			// Two hops through the junction table: previous table -> junction -> linked table
+			joinStms = append(joinStms, fmt.Sprintf("LEFT JOIN %s ON %s.%s = %s.%s AND %s.is_deleted = false LEFT JOIN %s ON %s.%s = %s.%s AND %s.is_deleted = false",
+				cteLink.Via, cteLink.Via, cteLink.ViaForeign.Column, prevTableName, cteLink.ViaForeign.TargetKey, cteLink.Via,
+				joinTable, tableName, cteLink.Foreign.TargetKey, cteLink.Via, cteLink.Foreign.Column, tableName))
+			continue
+		}
+		joinStms = append(joinStms, fmt.Sprintf("LEFT JOIN %s ON %s = %s AND %s.is_deleted = false", joinTable, joinerCol, joineeCol, tableName))
 	}
 	return joinStms
 }
 
-func (r *dynamicColumnService) buildDependencies(formula string, variables string, rootTable constants.TableName) (map[constants.TableName]Dependency, error) {
-	resolvedVars, err := r.resolveVariables(variables)
+func (r *dynamicColumnService) buildDependencies(formulaStr string, variables string, rootTable constants.TableName) (map[constants.TableName]Dependency, error) {
+	resolvedVars, err := r.resolveVariables(variables, rootTable)
 	if err != nil {
 		return nil, err
 	}
-	for _, v := range resolvedVars {
-		formula = strings.ReplaceAll(formula, v.Name, v.Value)
+	expr, err := formula.ParseExpression(formulaStr)
+	if err != nil {
+		return nil, fmt.Errorf("formula: %w", err)
 	}
 
-	re := regexp.MustCompile(`{{(\w+)}}\.(\w+)`)
-
-	// Find All tables in the formula
-	matches := re.FindAllStringSubmatch(formula+variables, -1)
+	// Find all table columns referenced by the formula and the variables
+	refs := formula.TableColumnRefs(expr)
+	for _, v := range resolvedVars {
+		refs = append(refs, formula.TableColumnRefs(v.Expr)...)
+	}
 
 	dependencies := make(map[constants.TableName]Dependency)
 
 	// Loop through all tables to start building dependencies
-	for _, match := range matches {
-		tableName := constants.TableName(match[1])
+	for _, ref := range refs {
+		tableRef, err := r.resolveTableRef(rootTable, ref.Table)
+		if err != nil {
+			return nil, formula.Errorf(ref.Pos, "%s", err)
+		}
 
-		// find out how table is related to root table (maybe through some middle tables)
-		cteLinks := make([]RelationLink, 0)
-		if tableName != rootTable {
-			cteLinks, err = r.ResolveTablesRelationLink(tableName, rootTable, nil, nil)
-			if err != nil {
-				return nil, err
-			}
+		// root table does not need record selector because it's directly refreshed based on the changed record ids
+		// while other tables need to be joined back to root table to select the affected records
+		if len(tableRef.Links) == 0 {
+			addDependency(dependencies, rootTable, "", ref.Column)
+			continue
+		}
+		r.addPathDependencies(dependencies, rootTable, tableRef, ref.Column)
+	}
+
+	return dependencies, nil
+}
+
+func (r *dynamicColumnService) GetAll(ctx context.Context) ([]DynamicColumn, error) {
+	return r.dynamicColumnRepo.GetAll(ctx)
+}
+
+func (r *dynamicColumnService) GetById(ctx context.Context, id int64) (*DynamicColumn, error) {
+	return r.dynamicColumnRepo.GetById(ctx, id)
+}
+
+// Validate compiles a dynamic column definition without saving it
+func (r *dynamicColumnService) Validate(ctx context.Context, payload *DynamicColumnCreateRequest) (*DynamicColumn, error) {
+	dynamicColumn, err := r.compileDynamicColumn(payload)
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkColumnType(ctx, dynamicColumn)
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkDependencyCycle(ctx, dynamicColumn)
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkViewColumns(ctx, dynamicColumn)
+	if err != nil {
+		return nil, err
+	}
+	return dynamicColumn, nil
+}
+
+// Preview computes a draft definition for sample rows and compares it with the stored values of its column,
+// which must exist in the table. Only the temp ids table is written, the dynamic column itself is left untouched.
+func (r *dynamicColumnService) Preview(ctx context.Context, payload *DynamicColumnPreviewRequest) (*DynamicColumnPreview, error) {
+	if _, exists := r.modelsMap[payload.TableName]; !exists {
+		return nil, fmt.Errorf("%w: unknown table %q", ErrInvalidFormula, payload.TableName)
+	}
+	// The name is written into the query, it is validated like the name of a created column
+	if err := r.checkColumnName(payload.TableName, payload.Name); err != nil {
+		return nil, err
+	}
+	dataType, err := r.dynamicColumnRepo.GetColumnDataType(ctx, payload.TableName, payload.Name)
+	if err != nil {
+		return nil, err
+	}
+	if dataType == "" {
+		return nil, fmt.Errorf("%w: column %s.%s does not exist", ErrInvalidFormula, payload.TableName, payload.Name)
+	}
+	if _, err := r.buildDependencies(payload.Formula, payload.Variables, payload.TableName); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormula, err)
+	}
+	query, err := r.buildFormulaFromTemplate(constants.FORMULA_PREVIEW_TEMPLATE, &DynamicColumnCreateRequest{
+		TableName:    payload.TableName,
+		Name:         payload.Name,
+		Formula:      payload.Formula,
+		Variables:    payload.Variables,
+		Type:         payload.Type,
+		DefaultValue: payload.DefaultValue,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormula, err)
+	}
+
+	query = strings.ReplaceAll(query, "{{current_value}}", string(payload.TableName)+"."+payload.Name)
+
+	ids := payload.Ids
+	if len(ids) == 0 {
+		limit := payload.Limit
+		if limit == 0 {
+			limit = constants.PREVIEW_DEFAULT_LIMIT
 		}
-		names := []constants.TableName{tableName}
-		for _, link := range cteLinks {
-			names = append(names, link.Table)
+		limit = min(limit, constants.PREVIEW_MAX_LIMIT)
+		ids, err = r.dynamicColumnRepo.GetFirstRecordIds(ctx, payload.TableName, limit)
+		if err != nil {
+			return nil, err
 		}
+	}
+	if len(ids) > constants.PREVIEW_MAX_LIMIT {
+		return nil, fmt.Errorf("%w: at most %d ids can be previewed", ErrInvalidFormula, constants.PREVIEW_MAX_LIMIT)
+	}
 
-		for _, name := range names {
-			dep := dependencies[name]
+	preview := &DynamicColumnPreview{Formula: query, Rows: make([]DynamicColumnPreviewRow, 0)}
+	if len(ids) == 0 {
+		return preview, nil
+	}
 
-			// if table (name) is in the formula, add the column of the table to columns
-			// if not, it must be middle table, so id and is_deleted are added as dependencies
-			if name == constants.TableName(match[1]) {
-				dep.Columns = utils.AppendUnique(dep.Columns, match[2])
-			} else {
-				dep.Columns = utils.AppendUnique(dep.Columns, "is_deleted", "id")
-			}
-			joinStm := ""
-
-			// root table does not need record selector because it's directly refreshed based on the changed record ids
-			// while other tables need to be joined back to root table to select the affected records
-			if name != rootTable {
-				joinStm, err = r.buildDependencySelector(name, rootTable)
-				if err != nil {
-					return nil, err
-				}
-			}
-			dep.RecordIdsSelector = joinStm
-			dependencies[name] = dep
+	err = r.dynamicColumnRepo.CreateTempIdsTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = r.dynamicColumnRepo.CopyIdsToTempTable(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.dynamicColumnRepo.PreviewDynamicColumn(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	err = r.dynamicColumnRepo.TruncateTempTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Rows = rows
+	preview.Total = len(rows)
+	for _, row := range rows {
+		if row.Changed {
+			preview.Changed++
 		}
 	}
+	preview.Unchanged = preview.Total - preview.Changed
+	return preview, nil
+}
 
-	return dependencies, nil
+// compileDynamicColumn builds the SQL formula and dependencies of a dynamic column definition.
+// Every error returned here wraps ErrInvalidFormula.
+func (r *dynamicColumnService) compileDynamicColumn(payload *DynamicColumnCreateRequest) (*DynamicColumn, error) {
+	if _, exists := r.modelsMap[payload.TableName]; !exists {
+		return nil, fmt.Errorf("%w: unknown table %q", ErrInvalidFormula, payload.TableName)
+	}
+	if payload.Name == "" {
+		return nil, fmt.Errorf("%w: column name is required", ErrInvalidFormula)
+	}
+	if err := r.checkColumnName(payload.TableName, payload.Name); err != nil {
+		return nil, err
+	}
+	if payload.MaxIterations < 0 || payload.MaxIterations > constants.MAX_CYCLE_ITERATIONS {
+		return nil, fmt.Errorf("%w: max_iterations must be between 0 and %d", ErrInvalidFormula, constants.MAX_CYCLE_ITERATIONS)
+	}
+	if payload.RefreshMode == "" {
+		payload.RefreshMode = constants.RefreshModeSync
+	}
+	if !slices.Contains([]constants.RefreshMode{
+		constants.RefreshModeSync, constants.RefreshModeAsync, constants.RefreshModeTrigger, constants.RefreshModeView, constants.RefreshModeVirtual}, payload.RefreshMode) {
+		return nil, fmt.Errorf("%w: refresh_mode must be %s, %s, %s, %s or %s", ErrInvalidFormula,
+			constants.RefreshModeSync, constants.RefreshModeAsync, constants.RefreshModeTrigger, constants.RefreshModeView, constants.RefreshModeVirtual)
+	}
+	// Triggers refresh the columns reading a trigger mode column as they fire, a cycle would never stop,
+	// and no formula can read a column that is not stored
+	if (payload.RefreshMode == constants.RefreshModeTrigger || !isStored(payload.RefreshMode)) && payload.MaxIterations > 0 {
+		return nil, fmt.Errorf("%w: a %s column cannot take part in a dependency cycle", ErrInvalidFormula, payload.RefreshMode)
+	}
+	if !isStored(payload.RefreshMode) && payload.ManageColumn {
+		return nil, fmt.Errorf("%w: a %s column is not stored in its table, manage_column does not apply", ErrInvalidFormula, payload.RefreshMode)
+	}
+	if payload.CreateIndex && !payload.ManageColumn {
+		return nil, fmt.Errorf("%w: create_index only applies to the column added with manage_column", ErrInvalidFormula)
+	}
+	// The columns of the model are created by the migrations, an added or a not stored column must not shadow one
+	if slices.Contains(utils.GormColumns(r.modelsMap[payload.TableName]), payload.Name) {
+		if payload.ManageColumn {
+			return nil, fmt.Errorf("%w: column %s.%s is a column of the model, manage_column only adds new columns", ErrInvalidFormula, payload.TableName, payload.Name)
+		}
+		if !isStored(payload.RefreshMode) {
+			return nil, fmt.Errorf("%w: column %s.%s is a column of the model, a %s column is not stored in its table",
+				ErrInvalidFormula, payload.TableName, payload.Name, payload.RefreshMode)
+		}
+	}
+	if payload.RefreshMode == constants.RefreshModeView {
+		if name := viewName(DynamicColumn{TableName: payload.TableName, Name: payload.Name}); len(name) > maxViewNameLength {
+			return nil, fmt.Errorf("%w: view name %s is longer than %d characters", ErrInvalidFormula, name, maxViewNameLength)
+		}
+	}
+	if payload.RefreshMode == constants.RefreshModeVirtual && payload.RefreshCron != "" {
+		return nil, fmt.Errorf("%w: a %s column is computed when read, refresh_cron does not apply", ErrInvalidFormula, payload.RefreshMode)
+	}
+	if payload.RefreshCron != "" {
+		if _, err := utils.ParseCron(payload.RefreshCron); err != nil {
+			return nil, fmt.Errorf("%w: refresh_cron: %w", ErrInvalidFormula, err)
+		}
+	}
+
+	if payload.Type == "" {
+		return nil, fmt.Errorf("%w: type is required, expected one of %s", ErrInvalidFormula, strings.Join(supportedTypes(), ", "))
+	}
+
+	// A view mode column compiles into the definition of its view, refreshed as a whole without tracking transitions,
+	// and a virtual column into the subquery joined when its table is read
+	var formula, transitionFormula string
+	var err error
+	if !isStored(payload.RefreshMode) {
+		formula, err = r.buildViewFormula(payload)
+	} else {
+		formula, err = r.BuildFormula(payload)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormula, err)
+	}
+	dependencies, err := r.buildDependencies(payload.Formula, payload.Variables, payload.TableName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormula, err)
+	}
+	if isStored(payload.RefreshMode) {
+		transitionFormula, err = r.buildTransitionFormula(payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFormula, err)
+		}
+	}
+	return &DynamicColumn{
+		TableName:         payload.TableName,
+		Name:              payload.Name,
+		Type:              payload.Type,
+		DefaultValue:      payload.DefaultValue,
+		Formula:           formula,
+		UserFormula:       payload.Formula,
+		Dependencies:      dependencies,
+		Variables:         payload.Variables,
+		MaxIterations:     payload.MaxIterations,
+		RefreshCron:       payload.RefreshCron,
+		TransitionFormula: transitionFormula,
+		RefreshMode:       payload.RefreshMode,
+	}, nil
 }
 
-func (r *dynamicColumnService) buildDependencySelector(depTable constants.TableName, rootTable constants.TableName) (string, error) {
-	cteLinks, err := r.ResolveTablesRelationLink(depTable, rootTable, nil, nil)
+// checkDependencyCycle rejects a definition that closes a cycle between dynamic columns,
+// unless every column of the cycle declares max_iterations.
+func (r *dynamicColumnService) checkDependencyCycle(ctx context.Context, col *DynamicColumn) error {
+	key := columnKey(*col)
+	all, err := r.dynamicColumnRepo.GetAll(ctx)
 	if err != nil {
-		return "", err
+		return err
+	}
+	cols := make([]DynamicColumn, 0)
+	for _, existing := range all {
+		if columnKey(existing) != key {
+			cols = append(cols, existing)
+		}
+	}
+	cols = append(cols, *col)
+
+	graph := newDependencyGraph(cols)
+	members := graph.cycleMembers(key)
+	if members == nil {
+		return nil
 	}
-	joinStms := r.createJoinStmFromCteLinks(cteLinks, depTable)
-	joinStr := fmt.Sprintf("SELECT DISTINCT %s.id FROM %s tdi JOIN %s ON %s.id = tdi.id %s", rootTable, constants.TEMP_TABLE_NAME, depTable, depTable, strings.Join(joinStms, " "))
-	joinStr = strings.ReplaceAll(joinStr, "LEFT JOIN", "JOIN") // Use INNER JOIN for selector to ensure only matching records are returned
-	return joinStr, nil
+	_, err = graph.cycleIterations(key, members)
+	return err
 }
 
 func (r *dynamicColumnService) Create(ctx context.Context, payload *DynamicColumnCreateRequest) (*DynamicColumn, error) {
-	formula, err := r.BuildFormula(payload.TableName, payload.Name, payload.Formula, payload.Variables)
+	dynamicColumn, err := r.compileDynamicColumn(payload)
 	if err != nil {
 		return nil, err
 	}
-	dependencies, err := r.buildDependencies(payload.Formula, payload.Variables, payload.TableName)
+
+	// The DDL runs in the request transaction, a failure below rolls the column back too
+	var schemaChanges []schemaChange
+	if payload.ManageColumn {
+		schemaChanges, err = r.addTargetColumn(ctx, dynamicColumn, payload.CreateIndex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = r.checkColumnType(ctx, dynamicColumn)
 	if err != nil {
 		return nil, err
 	}
-	dynamicColumn := &DynamicColumn{
-		TableName:    payload.TableName,
-		Name:         payload.Name,
-		Type:         payload.Type,
-		Formula:      formula,
-		Dependencies: dependencies,
-		Variables:    payload.Variables,
+	err = r.checkDependencyCycle(ctx, dynamicColumn)
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkViewColumns(ctx, dynamicColumn)
+	if err != nil {
+		return nil, err
 	}
 
 	created, err := r.dynamicColumnRepo.Create(ctx, dynamicColumn)
 	if err != nil {
 		return nil, err
 	}
+	viewChanges, err := r.syncView(ctx, nil, created)
+	if err != nil {
+		return nil, err
+	}
+	err = r.logSchemaChanges(ctx, created, append(schemaChanges, viewChanges...))
+	if err != nil {
+		return nil, err
+	}
+	err = r.syncDbTriggers(ctx, *created)
+	if err != nil {
+		return nil, err
+	}
+	err = r.syncChangeCapture(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Existing rows have no value yet, compute them. A view is populated when created,
+	// a virtual column has no stored value.
+	if created.IsStored() {
+		err = r.recomputeDynamicColumn(ctx, *created)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return created, nil
 }
+
+func (r *dynamicColumnService) Update(ctx context.Context, id int64, payload *DynamicColumnUpdateRequest) (*DynamicColumn, error) {
+	existing, err := r.dynamicColumnRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Table and name identify the physical column, so only the definition can change
+	createPayload := &DynamicColumnCreateRequest{
+		TableName:     existing.TableName,
+		Name:          existing.Name,
+		Formula:       existing.UserFormula,
+		Variables:     existing.Variables,
+		Type:          existing.Type,
+		DefaultValue:  existing.DefaultValue,
+		MaxIterations: existing.MaxIterations,
+		RefreshCron:   existing.RefreshCron,
+		RefreshMode:   existing.RefreshMode,
+	}
+	if payload.Formula != nil {
+		createPayload.Formula = *payload.Formula
+	}
+	if payload.Variables != nil {
+		createPayload.Variables = *payload.Variables
+	}
+	if payload.Type != nil {
+		createPayload.Type = *payload.Type
+	}
+	if payload.DefaultValue != nil {
+		createPayload.DefaultValue = *payload.DefaultValue
+	}
+	if payload.MaxIterations != nil {
+		createPayload.MaxIterations = *payload.MaxIterations
+	}
+	if payload.RefreshCron != nil {
+		createPayload.RefreshCron = *payload.RefreshCron
+	}
+	if payload.RefreshMode != nil {
+		createPayload.RefreshMode = *payload.RefreshMode
+	}
+	if createPayload.Formula == "" {
+		return nil, fmt.Errorf("%w: formula is required", ErrInvalidFormula)
+	}
+
+	dynamicColumn, err := r.compileDynamicColumn(createPayload)
+	if err != nil {
+		return nil, err
+	}
+	dynamicColumn.ID = existing.ID
+	err = r.checkColumnType(ctx, dynamicColumn)
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkDependencyCycle(ctx, dynamicColumn)
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkViewColumns(ctx, dynamicColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := r.dynamicColumnRepo.Update(ctx, dynamicColumn)
+	if err != nil {
+		return nil, err
+	}
+	viewChanges, err := r.syncView(ctx, existing, updated)
+	if err != nil {
+		return nil, err
+	}
+	err = r.logSchemaChanges(ctx, updated, viewChanges)
+	if err != nil {
+		return nil, err
+	}
+	if updated.TransitionFormula == "" {
+		err = r.dynamicColumnRepo.DeleteTransitions(ctx, updated.TableName, updated.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = r.syncDbTriggers(ctx, *updated)
+	if err != nil {
+		return nil, err
+	}
+	err = r.syncChangeCapture(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stored values were computed with the old formula, so recompute the whole table. A view is populated when created.
+	if !updated.IsStored() {
+		return updated, nil
+	}
+	err = r.recomputeDynamicColumn(ctx, *updated)
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// recomputeDynamicColumn refreshes a dynamic column for every row of its table in the current transaction.
+// With a backfill scheduler, a table of more than BACKFILL_SYNC_MAX_ROWS rows is left to a backfill job instead.
+func (r *dynamicColumnService) recomputeDynamicColumn(ctx context.Context, col DynamicColumn) error {
+	if r.backfillScheduler == nil {
+		ids, err := r.dynamicColumnRepo.GetAllRecordIds(ctx, col.TableName)
+		if err != nil {
+			return err
+		}
+		return r.RefreshDynamicColumnOfRecordIds(ctx, col, ids)
+	}
+
+	ids, err := r.dynamicColumnRepo.GetFirstRecordIds(ctx, col.TableName, constants.BACKFILL_SYNC_MAX_ROWS+1)
+	if err != nil {
+		return err
+	}
+	if len(ids) > constants.BACKFILL_SYNC_MAX_ROWS {
+		return r.backfillScheduler.ScheduleBackfill(ctx, col)
+	}
+	return r.RefreshDynamicColumnOfRecordIds(ctx, col, ids)
+}
+
+// RefreshDynamicColumnOfRecordIds refreshes a single dynamic column for the given rows of its table,
+// then refreshes the dynamic columns depending on it.
+// The view of a view mode column is refreshed as a whole instead, no dynamic column depends on it.
+// A virtual column is computed whenever its table is read, there is nothing to refresh.
+func (r *dynamicColumnService) RefreshDynamicColumnOfRecordIds(ctx context.Context, col DynamicColumn, ids []int64) error {
+	logPayload := r.GetLogPayload(ctx)
+	if col.RefreshMode == constants.RefreshModeView {
+		return r.refreshView(ctx, col)
+	}
+	if len(ids) == 0 || !col.IsStored() {
+		return nil
+	}
+	ctx = withRefreshOrigin(ctx, col.TableName, constants.ActionRefresh, ids)
+
+	err := r.dynamicColumnRepo.CreateTempIdsTable(ctx)
+	if err != nil {
+		(*logPayload)["error"] = fmt.Sprintf("Error creating temp ids table: %v", err)
+		return err
+	}
+	err = r.dynamicColumnRepo.CopyIdsToTempTable(ctx, ids)
+	if err != nil {
+		(*logPayload)["error"] = fmt.Sprintf("Error copying ids to temp ids table: %v", err)
+		return err
+	}
+	_, err = r.refreshColumn(ctx, DynamicColumnWithMetadata{DynamicColumn: col, Ids: ids})
+	if err != nil {
+		(*logPayload)["error"] = fmt.Sprintf("Error refreshing dynamic column %s.%s: %v", col.TableName, col.Name, err)
+		return err
+	}
+	err = r.dynamicColumnRepo.TruncateTempTable(ctx)
+	if err != nil {
+		(*logPayload)["error"] = fmt.Sprintf("Error truncating temp ids table: %v", err)
+		return err
+	}
+
+	changes := make(map[constants.TableName]Dependency)
+	r.addColumnsToDependency(changes, col.TableName, []string{col.Name})
+	_, err = r.refreshDependantsOfChanges(ctx, col.TableName, ids, changes, nil, true)
+	return err
+}
+
+// Delete removes a dynamic column definition, and its physical column when dropColumn is set
+func (r *dynamicColumnService) Delete(ctx context.Context, id int64, dropColumn bool) error {
+	existing, err := r.dynamicColumnRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Refuse to delete a column that other dynamic columns read from
+	dependants, err := r.getDirectDependants(ctx, *existing)
+	if err != nil {
+		return err
+	}
+	if len(dependants) > 0 {
+		names := make([]string, 0, len(dependants))
+		for _, dependant := range dependants {
+			names = append(names, string(dependant.TableName)+"."+dependant.Name)
+		}
+		return fmt.Errorf("%w: %s.%s is used by %s", ErrDynamicColumnInUse, existing.TableName, existing.Name, strings.Join(names, ", "))
+	}
+
+	err = r.dynamicColumnRepo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = r.dynamicColumnRepo.DeleteTransitions(ctx, existing.TableName, existing.Name)
+	if err != nil {
+		return err
+	}
+	// The view of a view mode column is its value, it goes with the definition
+	viewChanges, err := r.syncView(ctx, existing, nil)
+	if err != nil {
+		return err
+	}
+	err = r.logSchemaChanges(ctx, existing, viewChanges)
+	if err != nil {
+		return err
+	}
+	existing.RefreshMode = constants.RefreshModeSync // drops its triggers
+	err = r.syncDbTriggers(ctx, *existing)
+	if err != nil {
+		return err
+	}
+	err = r.syncChangeCapture(ctx)
+	if err != nil {
+		return err
+	}
+	if !dropColumn {
+		return nil
+	}
+	schemaChanges, err := r.dropTargetColumn(ctx, existing)
+	if err != nil {
+		return err
+	}
+	return r.logSchemaChanges(ctx, existing, schemaChanges)
+}
+
+// getDirectDependants returns the other dynamic columns whose dependencies include the given column
+func (r *dynamicColumnService) getDirectDependants(ctx context.Context, col DynamicColumn) ([]DynamicColumn, error) {
+	changes := make(map[constants.TableName]Dependency)
+	r.addColumnsToDependency(changes, col.TableName, []string{col.Name})
+
+	dependants, err := r.dynamicColumnRepo.GetAllDependantsByChanges(ctx, col.TableName, changes)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]DynamicColumn, 0)
+	for _, dependant := range dependants {
+		if dependant.ID == col.ID {
+			continue
+		}
+		result = append(result, dependant)
+	}
+	return result, nil
+}
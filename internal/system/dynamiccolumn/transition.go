@@ -0,0 +1,58 @@
+package dynamiccolumn
+
+import (
+	"fmt"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn/formula"
+	"strings"
+)
+
+/*
+* buildTransitionFormula compiles the statement storing the next instant the value of each row may change at,
+* the earliest upcoming threshold the formula compares the current time to.
+* e.g. CURRENT_DATE - created_at > payment_terms * INTERVAL '1 day' changes on the first midnight after
+* created_at + payment_terms days, and CURRENT_DATE < start_date on start_date.
+* It returns "" when the formula does not read the current time, or reads it in a way thresholds cannot predict,
+* e.g. in a variable aggregating related rows. Such a column is left to the scheduled refresh.
+ */
+func (r *dynamicColumnService) buildTransitionFormula(payload *DynamicColumnCreateRequest) (string, error) {
+	expr, err := formula.ParseExpression(payload.Formula)
+	if err != nil {
+		return "", fmt.Errorf("formula: %w", err)
+	}
+	thresholds, complete := formula.TimeThresholds(expr)
+	if !complete || len(thresholds) == 0 {
+		return "", nil
+	}
+	decls, err := formula.ParseVariables(payload.Variables)
+	if err != nil {
+		return "", fmt.Errorf("variables: %w", err)
+	}
+	for _, decl := range decls {
+		if formula.UsesCurrentTime(decl.Expr) {
+			return "", nil
+		}
+	}
+
+	candidates := make([]string, 0, len(thresholds)*2)
+	for _, threshold := range thresholds {
+		instant := formula.Render(threshold.Expr)
+		if threshold.Daily {
+			// A date compared to CURRENT_DATE flips at the start of its day, or of the next day for a strict comparison
+			day := fmt.Sprintf("date_trunc('day', CAST((%s) AS timestamp))", instant)
+			candidates = append(candidates, day, day+" + INTERVAL '1 day'")
+			continue
+		}
+		candidates = append(candidates, instant)
+	}
+	upcoming := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidate = fmt.Sprintf("CAST((%s) AS timestamptz)", candidate)
+		upcoming = append(upcoming, fmt.Sprintf("CASE WHEN %s > CURRENT_TIMESTAMP THEN %s END", candidate, candidate))
+	}
+
+	transitionPayload := *payload
+	transitionPayload.Formula = fmt.Sprintf("LEAST(%s)", strings.Join(upcoming, ", "))
+	transitionPayload.Type = ""
+	return r.buildFormulaFromTemplate(constants.TRANSITION_TEMPLATE, &transitionPayload)
+}
@@ -0,0 +1,101 @@
+package dynamiccolumn
+
+import (
+	"gin-demo/internal/shared/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testContract struct {
+	types.GormModel
+	ComputedValues
+	StartDate *time.Time `gorm:"column:start_date"`
+	EndsAt    *time.Time `gorm:"column:ends_at"`
+}
+
+func (testContract) TableName() string { return "contract" }
+
+func TestBuildTransitionFormula(t *testing.T) {
+	tests := []struct {
+		name      string
+		formula   string
+		variables string
+		want      []string // parts of the transition, none when the column has no transition
+		wantErr   bool
+	}{
+		{
+			name:    "start of the day and of the next day",
+			formula: "CURRENT_DATE >= {{contract}}.start_date",
+			want: []string{
+				"CASE WHEN CAST((date_trunc('day', CAST((contract.start_date) AS timestamp))) AS timestamptz) > CURRENT_TIMESTAMP",
+				"CASE WHEN CAST((date_trunc('day', CAST((contract.start_date) AS timestamp)) + INTERVAL '1 day') AS timestamptz) > CURRENT_TIMESTAMP",
+			},
+		},
+		{
+			name:    "instant",
+			formula: "{{contract}}.ends_at <= NOW()",
+			want:    []string{"LEAST(CASE WHEN CAST((contract.ends_at) AS timestamptz) > CURRENT_TIMESTAMP THEN CAST((contract.ends_at) AS timestamptz) END) AS transition_at"},
+		},
+		{
+			name:    "interval",
+			formula: "{{contract}}.ends_at < NOW() + INTERVAL '1 day'",
+			want:    []string{"CAST(((contract.ends_at) - (INTERVAL '1 day')) AS timestamptz)"},
+		},
+		{
+			name:    "between",
+			formula: "NOW() BETWEEN {{contract}}.created_at AND {{contract}}.ends_at",
+			want:    []string{"CAST((contract.created_at) AS timestamptz) > CURRENT_TIMESTAMP", "CAST((contract.ends_at) AS timestamptz) > CURRENT_TIMESTAMP"},
+		},
+		{
+			name:    "current time not read",
+			formula: "{{contract}}.ends_at > {{contract}}.start_date",
+		},
+		{
+			name:    "current time read by age",
+			formula: "AGE({{contract}}.start_date) > INTERVAL '1 year'",
+		},
+		{
+			name:      "current time read by a variable",
+			formula:   "NOW() > {{contract}}.ends_at AND elapsed > 1",
+			variables: "var elapsed = DATE_PART('day', NOW())",
+		},
+		{
+			name:    "invalid formula",
+			formula: "NOW() >",
+			wantErr: true,
+		},
+	}
+	r := &dynamicColumnService{modelsMap: types.ModelsMap{"contract": testContract{}}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.buildTransitionFormula(&DynamicColumnCreateRequest{
+				TableName: "contract",
+				Name:      "status",
+				Formula:   tt.formula,
+				Variables: tt.variables,
+				Type:      "boolean",
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("buildTransitionFormula(%q) = %q, want an error", tt.formula, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildTransitionFormula(%q) returned %v", tt.formula, err)
+			}
+			if len(tt.want) == 0 {
+				if got != "" {
+					t.Fatalf("buildTransitionFormula(%q) = %q, want no transition", tt.formula, got)
+				}
+				return
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Fatalf("buildTransitionFormula(%q) = %s, want it to contain %q", tt.formula, got, want)
+				}
+			}
+		})
+	}
+}
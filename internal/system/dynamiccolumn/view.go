@@ -0,0 +1,133 @@
+package dynamiccolumn
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/internal/shared/constants"
+	"slices"
+)
+
+// Postgres truncates longer identifiers, two views could end up with the same name
+const maxViewNameLength = 63
+
+// IsStored reports whether the values of the column are stored in its table, a view mode column is read from its view
+// and a virtual column is computed by the query reading its table
+func (c DynamicColumn) IsStored() bool {
+	return isStored(c.RefreshMode)
+}
+
+func isStored(mode constants.RefreshMode) bool {
+	return mode != constants.RefreshModeView && mode != constants.RefreshModeVirtual
+}
+
+// viewName names the materialized view of a view mode column
+func viewName(col DynamicColumn) string {
+	return fmt.Sprintf("%s_%s_%s", constants.VIEW_PREFIX, col.TableName, col.Name)
+}
+
+// buildViewFormula compiles a view mode column into the definition of its materialized view, keyed by the root id
+func (r *dynamicColumnService) buildViewFormula(payload *DynamicColumnCreateRequest) (string, error) {
+	if payload.RefreshMode == constants.RefreshModeVirtual {
+		return r.buildFormulaFromTemplate(constants.VIRTUAL_TEMPLATE, payload)
+	}
+	return r.buildFormulaFromTemplate(constants.VIEW_TEMPLATE, payload)
+}
+
+/*
+* syncView replaces the materialized view of a dynamic column after its definition changed, in the transaction of the change.
+* The view of previous is dropped when it was a view mode column, the view of col is created when it is one.
+* Either may be nil, for a created or a deleted column.
+* The view is populated when created, and gets the unique index on id a concurrent refresh requires.
+ */
+func (r *dynamicColumnService) syncView(ctx context.Context, previous *DynamicColumn, col *DynamicColumn) ([]schemaChange, error) {
+	changes := make([]schemaChange, 0)
+	if previous != nil && previous.RefreshMode == constants.RefreshModeView {
+		changes = append(changes, schemaChange{
+			operation: constants.DdlOperationDropView,
+			statement: fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS %s", viewName(*previous)),
+		})
+	}
+	if col != nil && col.RefreshMode == constants.RefreshModeView {
+		name := viewName(*col)
+		changes = append(changes,
+			schemaChange{
+				operation: constants.DdlOperationCreateView,
+				statement: fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS %s", name, col.Formula),
+			},
+			schemaChange{
+				operation: constants.DdlOperationCreateIndex,
+				statement: fmt.Sprintf("CREATE UNIQUE INDEX %s_id ON %s(id)", name, name),
+			},
+		)
+	}
+
+	for _, change := range changes {
+		err := r.dynamicColumnRepo.ExecDDL(ctx, change.statement)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", change.statement, err)
+		}
+	}
+	return changes, nil
+}
+
+/*
+* checkViewColumns rejects the definitions that would read a view mode or a virtual column:
+* its value is not stored in its table, so neither a formula nor a refresh can read it.
+* - a formula reading a view mode or a virtual column
+* - a view mode or a virtual column read by the formula of another dynamic column
+ */
+func (r *dynamicColumnService) checkViewColumns(ctx context.Context, col *DynamicColumn) error {
+	columns, err := r.dynamicColumnRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	key := columnKey(*col)
+	for _, existing := range columns {
+		if columnKey(existing) == key {
+			continue
+		}
+		if !existing.IsStored() && readsColumn(*col, existing) {
+			return fmt.Errorf("%w: %s.%s is a %s column, formulas cannot read it",
+				ErrInvalidFormula, existing.TableName, existing.Name, existing.RefreshMode)
+		}
+		if !col.IsStored() && readsColumn(existing, *col) {
+			return fmt.Errorf("%w: %s.%s is read by %s.%s, it cannot be a %s column",
+				ErrDynamicColumnInUse, col.TableName, col.Name, existing.TableName, existing.Name, col.RefreshMode)
+		}
+	}
+	return nil
+}
+
+// readsColumn checks whether the formula of a dynamic column reads another dynamic column
+func readsColumn(col DynamicColumn, other DynamicColumn) bool {
+	dep, exists := col.Dependencies[other.TableName]
+	return exists && slices.Contains(dep.Columns, other.Name)
+}
+
+// RefreshView refreshes the materialized view of a view mode column now, without waiting for its schedule
+func (r *dynamicColumnService) RefreshView(ctx context.Context, id int64) (*DynamicColumn, error) {
+	col, err := r.dynamicColumnRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if col.RefreshMode != constants.RefreshModeView {
+		return nil, fmt.Errorf("%w: %s.%s is a %s column", ErrNotViewColumn, col.TableName, col.Name, col.RefreshMode)
+	}
+	err = r.refreshView(ctx, *col)
+	if err != nil {
+		return nil, err
+	}
+	return col, nil
+}
+
+// refreshView recomputes every row of the materialized view of a view mode column.
+// The refresh is concurrent, the view keeps serving the previous values to readers until it commits.
+func (r *dynamicColumnService) refreshView(ctx context.Context, col DynamicColumn) error {
+	logPayload := r.GetLogPayload(ctx)
+	err := r.dynamicColumnRepo.RefreshView(ctx, viewName(col))
+	if err != nil {
+		(*logPayload)["error"] = fmt.Sprintf("Error refreshing the view of dynamic column %s.%s: %v", col.TableName, col.Name, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package history
+
+import (
+	"gin-demo/internal/shared/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HistoryHandler interface {
+	GetAll(c *gin.Context)
+	GetTimeline(c *gin.Context)
+}
+
+type historyHandler struct {
+	historyService HistoryService
+}
+
+func NewHistoryHandler(historyService HistoryService) HistoryHandler {
+	return &historyHandler{historyService: historyService}
+}
+
+// GetAll lists the latest value changes, filtered by ?table_name=, record_id=, column_name=, origin_table= and request_id=
+func (h *historyHandler) GetAll(c *gin.Context) {
+	var query HistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	changes := h.historyService.GetAll(c.Request.Context(), query)
+	c.JSON(200, types.NewListResponse(changes, nil, ""))
+}
+
+// GetTimeline lists the values the dynamic columns of a row held, e.g. ?table_name=contract&record_id=1&column_name=status
+func (h *historyHandler) GetTimeline(c *gin.Context) {
+	var query TimelineQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	entries := h.historyService.GetTimeline(c.Request.Context(), query)
+	c.JSON(200, types.NewListResponse(entries, nil, ""))
+}
@@ -0,0 +1,46 @@
+package history
+
+import (
+	"gin-demo/internal/shared/constants"
+	"time"
+)
+
+// DynamicColumnHistory is a value of a dynamic column changed by a refresh, with the change the refresh was caused by.
+// Values are stored as text whatever the type of the column.
+type DynamicColumnHistory struct {
+	ID              int64               `json:"id" gorm:"primaryKey;column:id"`
+	DynamicColumnID int64               `json:"dynamic_column_id" gorm:"column:dynamic_column_id"`
+	TableName       constants.TableName `json:"table_name" gorm:"column:table_name"`
+	ColumnName      string              `json:"column_name" gorm:"column:column_name"`
+	RecordId        int64               `json:"record_id" gorm:"column:record_id"`
+	OldValue        *string             `json:"old_value" gorm:"column:old_value"`
+	NewValue        *string             `json:"new_value" gorm:"column:new_value"`
+	OriginTable     constants.TableName `json:"origin_table" gorm:"column:origin_table"`
+	OriginAction    constants.Action    `json:"origin_action" gorm:"column:origin_action"`
+	OriginIds       []int64             `json:"origin_ids" gorm:"column:origin_ids;type:jsonb;serializer:json"` // at most HISTORY_ORIGIN_IDS_LIMIT
+	RequestId       *string             `json:"request_id" gorm:"column:request_id"`                            // empty for workers and writes made outside the application
+	ChangedAt       time.Time           `json:"changed_at" gorm:"column:changed_at;autoCreateTime"`
+}
+
+// HistoryQuery filters the history, latest changes first
+type HistoryQuery struct {
+	TableName   constants.TableName `form:"table_name"`
+	RecordId    int64               `form:"record_id"`
+	ColumnName  string              `form:"column_name"`
+	OriginTable constants.TableName `form:"origin_table"`
+	RequestId   string              `form:"request_id"`
+	Limit       int                 `form:"limit" binding:"omitempty,min=1"`
+}
+
+// TimelineQuery selects the timeline of a row, of every dynamic column of its table when ColumnName is empty
+type TimelineQuery struct {
+	TableName  constants.TableName `form:"table_name" binding:"required"`
+	RecordId   int64               `form:"record_id" binding:"required"`
+	ColumnName string              `form:"column_name"`
+}
+
+// TimelineEntry is a value a column of a row held, from the change setting it until the next change, nil while it is current
+type TimelineEntry struct {
+	DynamicColumnHistory
+	Until *time.Time `json:"until"`
+}
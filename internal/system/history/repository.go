@@ -0,0 +1,60 @@
+package history
+
+import (
+	"context"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+)
+
+type HistoryRepository interface {
+	CreateInBatches(ctx context.Context, changes []DynamicColumnHistory) error
+	GetAll(ctx context.Context, query HistoryQuery) []DynamicColumnHistory
+	GetTimeline(ctx context.Context, query TimelineQuery) []DynamicColumnHistory
+}
+
+type historyRepository struct {
+	base.BaseHelper
+}
+
+func NewHistoryRepository() HistoryRepository {
+	return &historyRepository{}
+}
+
+func (r *historyRepository) CreateInBatches(ctx context.Context, changes []DynamicColumnHistory) error {
+	tx := r.GetDbTx(ctx)
+	return tx.CreateInBatches(changes, constants.HISTORY_MAX_LIMIT).Error
+}
+
+// GetAll returns the changes matching the query, latest first
+func (r *historyRepository) GetAll(ctx context.Context, query HistoryQuery) []DynamicColumnHistory {
+	tx := r.GetDbTx(ctx)
+	if query.TableName != "" {
+		tx = tx.Where("table_name = ?", query.TableName)
+	}
+	if query.RecordId != 0 {
+		tx = tx.Where("record_id = ?", query.RecordId)
+	}
+	if query.ColumnName != "" {
+		tx = tx.Where("column_name = ?", query.ColumnName)
+	}
+	if query.OriginTable != "" {
+		tx = tx.Where("origin_table = ?", query.OriginTable)
+	}
+	if query.RequestId != "" {
+		tx = tx.Where("request_id = ?", query.RequestId)
+	}
+	changes := make([]DynamicColumnHistory, 0)
+	tx.Order("changed_at DESC, id DESC").Limit(query.Limit).Find(&changes)
+	return changes
+}
+
+// GetTimeline returns the changes of a row by column, oldest first
+func (r *historyRepository) GetTimeline(ctx context.Context, query TimelineQuery) []DynamicColumnHistory {
+	tx := r.GetDbTx(ctx).Where("table_name = ? AND record_id = ?", query.TableName, query.RecordId)
+	if query.ColumnName != "" {
+		tx = tx.Where("column_name = ?", query.ColumnName)
+	}
+	changes := make([]DynamicColumnHistory, 0)
+	tx.Order("column_name, changed_at, id").Find(&changes)
+	return changes
+}
@@ -0,0 +1,77 @@
+package history
+
+import (
+	"context"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn"
+)
+
+type HistoryService interface {
+	RecordChanges(ctx context.Context, col dynamiccolumn.DynamicColumn, changes []dynamiccolumn.ValueChange, origin dynamiccolumn.RefreshOrigin) error
+	GetAll(ctx context.Context, query HistoryQuery) []DynamicColumnHistory
+	GetTimeline(ctx context.Context, query TimelineQuery) []TimelineEntry
+}
+
+type historyService struct {
+	historyRepo HistoryRepository
+	base.BaseHelper
+}
+
+func NewHistoryService(historyRepo HistoryRepository) HistoryService {
+	return &historyService{historyRepo: historyRepo}
+}
+
+// RecordChanges writes the changed values of a dynamic column to the history, with the origin of the refresh and the request id.
+// It implements dynamiccolumn.HistoryRecorder, the history is written in the transaction of the refresh.
+func (s *historyService) RecordChanges(
+	ctx context.Context, col dynamiccolumn.DynamicColumn, changes []dynamiccolumn.ValueChange, origin dynamiccolumn.RefreshOrigin) error {
+	var requestId *string
+	if id := s.GetRequestId(ctx); id != "" {
+		requestId = &id
+	}
+	originIds := origin.Ids
+	if originIds == nil {
+		originIds = []int64{}
+	}
+
+	rows := make([]DynamicColumnHistory, 0, len(changes))
+	for _, change := range changes {
+		rows = append(rows, DynamicColumnHistory{
+			DynamicColumnID: col.ID,
+			TableName:       col.TableName,
+			ColumnName:      col.Name,
+			RecordId:        change.RecordId,
+			OldValue:        change.OldValue,
+			NewValue:        change.NewValue,
+			OriginTable:     origin.Table,
+			OriginAction:    origin.Action,
+			OriginIds:       originIds,
+			RequestId:       requestId,
+		})
+	}
+	return s.historyRepo.CreateInBatches(ctx, rows)
+}
+
+func (s *historyService) GetAll(ctx context.Context, query HistoryQuery) []DynamicColumnHistory {
+	if query.Limit == 0 {
+		query.Limit = constants.HISTORY_DEFAULT_LIMIT
+	}
+	query.Limit = min(query.Limit, constants.HISTORY_MAX_LIMIT)
+	return s.historyRepo.GetAll(ctx, query)
+}
+
+// GetTimeline returns the values every dynamic column of a row held over time, by column, oldest first.
+// Every value holds until the next change of the same column.
+func (s *historyService) GetTimeline(ctx context.Context, query TimelineQuery) []TimelineEntry {
+	changes := s.historyRepo.GetTimeline(ctx, query)
+	entries := make([]TimelineEntry, 0, len(changes))
+	for i, change := range changes {
+		entry := TimelineEntry{DynamicColumnHistory: change}
+		if i+1 < len(changes) && changes[i+1].ColumnName == change.ColumnName {
+			entry.Until = &changes[i+1].ChangedAt
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
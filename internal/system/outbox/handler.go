@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/types"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OutboxHandler interface {
+	GetAll(c *gin.Context)
+	Retry(c *gin.Context)
+}
+
+type outboxHandler struct {
+	outboxService OutboxService
+}
+
+func NewOutboxHandler(outboxService OutboxService) OutboxHandler {
+	return &outboxHandler{outboxService: outboxService}
+}
+
+// GetAll lists the latest changes, ?limit= bounds the number of rows
+func (h *outboxHandler) GetAll(c *gin.Context) {
+	limit := constants.OUTBOX_BATCH_SIZE
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			c.JSON(400, types.NewErrorResponse("Invalid limit", limitStr))
+			return
+		}
+	}
+
+	changes := h.outboxService.GetAll(c.Request.Context(), limit)
+	c.JSON(200, types.NewListResponse(changes, nil, ""))
+}
+
+func (h *outboxHandler) Retry(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	change, err := h.outboxService.Retry(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to retry refresh outbox change", err.Error()))
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(change, "Refresh outbox change queued again"))
+}
@@ -0,0 +1,29 @@
+package outbox
+
+import (
+	"gin-demo/internal/shared/constants"
+	"time"
+)
+
+// DynamicColumnRefreshOutbox is a change of records whose async dynamic columns are still to refresh.
+// It is written in the transaction of the change, so a rolled back change leaves nothing to refresh.
+type DynamicColumnRefreshOutbox struct {
+	ID            int64                  `json:"id" gorm:"primaryKey;column:id"`
+	TableName     constants.TableName    `json:"table_name" gorm:"column:table_name"`
+	RecordIds     []int64                `json:"record_ids" gorm:"column:record_ids;type:jsonb;serializer:json"`
+	Columns       []string               `json:"columns" gorm:"column:columns;type:jsonb;serializer:json"` // changed columns
+	Status        constants.OutboxStatus `json:"status" gorm:"column:status"`
+	Attempts      int                    `json:"attempts" gorm:"column:attempts"`
+	Error         string                 `json:"error,omitempty" gorm:"column:error"`
+	NextAttemptAt time.Time              `json:"next_attempt_at" gorm:"column:next_attempt_at;default:CURRENT_TIMESTAMP"`
+	ProcessedAt   *time.Time             `json:"processed_at" gorm:"column:processed_at"`
+	CreatedAt     time.Time              `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// coalescedChange merges the pending changes of a table, every changed column is refreshed for every changed record
+type coalescedChange struct {
+	table     constants.TableName
+	recordIds []int64
+	columns   []string
+	outboxIds []int64
+}
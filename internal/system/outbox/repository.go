@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"context"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository interface {
+	GetAll(ctx context.Context, limit int) []DynamicColumnRefreshOutbox
+	GetById(ctx context.Context, id int64) (*DynamicColumnRefreshOutbox, error)
+	Create(ctx context.Context, change *DynamicColumnRefreshOutbox) error
+	Update(ctx context.Context, change *DynamicColumnRefreshOutbox) error
+	ClaimPending(ctx context.Context, limit int) ([]DynamicColumnRefreshOutbox, error)
+	MarkProcessed(ctx context.Context, ids []int64) error
+	MarkAttemptFailed(ctx context.Context, ids []int64, cause string) error
+}
+
+type outboxRepository struct {
+	base.BaseHelper
+}
+
+func NewOutboxRepository() OutboxRepository {
+	return &outboxRepository{}
+}
+
+// GetAll returns the latest changes first
+func (r *outboxRepository) GetAll(ctx context.Context, limit int) []DynamicColumnRefreshOutbox {
+	tx := r.GetDbTx(ctx)
+	var changes []DynamicColumnRefreshOutbox
+	tx.Order("id DESC").Limit(limit).Find(&changes)
+	return changes
+}
+
+func (r *outboxRepository) GetById(ctx context.Context, id int64) (*DynamicColumnRefreshOutbox, error) {
+	tx := r.GetDbTx(ctx)
+	var change DynamicColumnRefreshOutbox
+	err := tx.First(&change, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (r *outboxRepository) Create(ctx context.Context, change *DynamicColumnRefreshOutbox) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Create(change).Error
+}
+
+func (r *outboxRepository) Update(ctx context.Context, change *DynamicColumnRefreshOutbox) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Save(change).Error
+}
+
+// ClaimPending locks at most limit pending changes whose next attempt is due until the end of the transaction, oldest first.
+// Changes locked by another worker are skipped.
+func (r *outboxRepository) ClaimPending(ctx context.Context, limit int) ([]DynamicColumnRefreshOutbox, error) {
+	tx := r.GetDbTx(ctx)
+	var changes []DynamicColumnRefreshOutbox
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= now()", constants.OutboxStatusPending).
+		Order("id").
+		Limit(limit).
+		Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *outboxRepository) MarkProcessed(ctx context.Context, ids []int64) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Model(&DynamicColumnRefreshOutbox{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":       constants.OutboxStatusProcessed,
+		"processed_at": time.Now(),
+		"error":        "",
+	}).Error
+}
+
+// MarkAttemptFailed counts a failed attempt of the changes, the ones out of attempts are marked failed
+// and the others are delayed by a backoff doubling with every attempt
+func (r *outboxRepository) MarkAttemptFailed(ctx context.Context, ids []int64, cause string) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Model(&DynamicColumnRefreshOutbox{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"attempts": gorm.Expr("attempts + 1"),
+		"error":    cause,
+		"next_attempt_at": gorm.Expr("now() + LEAST(? * power(2, attempts), ?) * interval '1 second'",
+			constants.OUTBOX_BACKOFF_BASE.Seconds(), constants.OUTBOX_BACKOFF_MAX.Seconds()),
+		"status": gorm.Expr("CASE WHEN attempts + 1 >= ? THEN ? ELSE status END",
+			constants.OUTBOX_MAX_ATTEMPTS, constants.OutboxStatusFailed),
+	}).Error
+}
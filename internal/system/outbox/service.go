@@ -0,0 +1,177 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/utils"
+	"gin-demo/internal/system/dynamiccolumn"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OutboxService interface {
+	GetAll(ctx context.Context, limit int) []DynamicColumnRefreshOutbox
+	EnqueueRefresh(ctx context.Context, table constants.TableName, ids []int64, columns []string) error
+	Retry(ctx context.Context, id int64) (*DynamicColumnRefreshOutbox, error)
+	RunPending(ctx context.Context, batchSize int) (*OutboxRunResult, error)
+	Run(ctx context.Context, interval time.Duration, batchSize int, logger *slog.Logger)
+}
+
+// OutboxRunResult counts the changes a run went through
+type OutboxRunResult struct {
+	Processed int `json:"processed"`
+	Failed    int `json:"failed"`
+}
+
+type outboxService struct {
+	outboxRepo           OutboxRepository
+	dynamicColumnService dynamiccolumn.DynamicColumnService
+	base.BaseHelper
+}
+
+func NewOutboxService(outboxRepo OutboxRepository, dynamicColumnService dynamiccolumn.DynamicColumnService) OutboxService {
+	return &outboxService{
+		outboxRepo:           outboxRepo,
+		dynamicColumnService: dynamicColumnService,
+	}
+}
+
+func (s *outboxService) GetAll(ctx context.Context, limit int) []DynamicColumnRefreshOutbox {
+	return s.outboxRepo.GetAll(ctx, limit)
+}
+
+// EnqueueRefresh implements dynamiccolumn.RefreshQueue, the change is written in the transaction of ctx
+func (s *outboxService) EnqueueRefresh(ctx context.Context, table constants.TableName, ids []int64, columns []string) error {
+	return s.outboxRepo.Create(ctx, &DynamicColumnRefreshOutbox{
+		TableName: table,
+		RecordIds: ids,
+		Columns:   columns,
+		Status:    constants.OutboxStatusPending,
+	})
+}
+
+// Retry puts a failed change back in the queue with fresh attempts
+func (s *outboxService) Retry(ctx context.Context, id int64) (*DynamicColumnRefreshOutbox, error) {
+	change, err := s.outboxRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != constants.OutboxStatusFailed {
+		return nil, fmt.Errorf("refresh outbox change %d is %s, only failed changes can be retried", id, change.Status)
+	}
+	change.Status = constants.OutboxStatusPending
+	change.Attempts = 0
+	change.NextAttemptAt = time.Now()
+	change.Error = ""
+	err = s.outboxRepo.Update(ctx, change)
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// RunPending drains the pending changes batch by batch.
+// ctx must carry the root database connection, not a request transaction.
+func (s *outboxService) RunPending(ctx context.Context, batchSize int) (*OutboxRunResult, error) {
+	if batchSize <= 0 {
+		batchSize = constants.OUTBOX_BATCH_SIZE
+	}
+	result := &OutboxRunResult{}
+	for {
+		claimed, err := s.runBatch(ctx, batchSize, result)
+		if err != nil {
+			return result, err
+		}
+		if claimed < batchSize {
+			return result, nil
+		}
+	}
+}
+
+/*
+* runBatch claims a batch of pending changes and refreshes them in one transaction.
+* Changes of the same table are coalesced, so a record changed by many requests is refreshed once.
+* Every table runs in its own savepoint: a failing table counts an attempt on its changes without undoing the others.
+ */
+func (s *outboxService) runBatch(ctx context.Context, batchSize int, result *OutboxRunResult) (int, error) {
+	claimed := 0
+	err := s.GetDbTx(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := context.WithValue(ctx, config.ContextKeyDB, tx)
+
+		changes, err := s.outboxRepo.ClaimPending(txCtx, batchSize)
+		if err != nil {
+			return err
+		}
+		claimed = len(changes)
+
+		for _, change := range coalesce(changes) {
+			err = tx.Transaction(func(tableTx *gorm.DB) error {
+				tableCtx := context.WithValue(ctx, config.ContextKeyDB, tableTx)
+				return s.dynamicColumnService.RefreshDeferredChanges(tableCtx, change.table, change.recordIds, change.columns)
+			})
+			if err != nil {
+				result.Failed += len(change.outboxIds)
+				err = s.outboxRepo.MarkAttemptFailed(txCtx, change.outboxIds, err.Error())
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			result.Processed += len(change.outboxIds)
+			err = s.outboxRepo.MarkProcessed(txCtx, change.outboxIds)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return claimed, nil
+}
+
+// coalesce merges the changes by table, in the order the tables were first changed
+func coalesce(changes []DynamicColumnRefreshOutbox) []*coalescedChange {
+	result := make([]*coalescedChange, 0)
+	byTable := make(map[constants.TableName]*coalescedChange)
+	for _, change := range changes {
+		merged, exists := byTable[change.TableName]
+		if !exists {
+			merged = &coalescedChange{table: change.TableName}
+			byTable[change.TableName] = merged
+			result = append(result, merged)
+		}
+		merged.recordIds = utils.AppendUnique(merged.recordIds, change.RecordIds...)
+		merged.columns = utils.AppendUnique(merged.columns, change.Columns...)
+		merged.outboxIds = append(merged.outboxIds, change.ID)
+	}
+	return result
+}
+
+// Run drains the outbox at every interval until ctx is done.
+// ctx must carry the root database connection, not a request transaction.
+func (s *outboxService) Run(ctx context.Context, interval time.Duration, batchSize int, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := s.RunPending(ctx, batchSize)
+		if result.Processed > 0 || result.Failed > 0 {
+			logger.Info("dynamic column refresh outbox drained", "processed", result.Processed, "failed", result.Failed)
+		}
+		if err != nil {
+			logger.Error("dynamic column refresh outbox failed", "error", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package refreshschedule
+
+import (
+	"gin-demo/internal/shared/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RefreshScheduleHandler interface {
+	GetAll(c *gin.Context)
+	GetAllRuns(c *gin.Context)
+	Run(c *gin.Context)
+}
+
+type refreshScheduleHandler struct {
+	refreshScheduleService RefreshScheduleService
+}
+
+func NewRefreshScheduleHandler(refreshScheduleService RefreshScheduleService) RefreshScheduleHandler {
+	return &refreshScheduleHandler{refreshScheduleService: refreshScheduleService}
+}
+
+func (h *refreshScheduleHandler) GetAll(c *gin.Context) {
+	schedules, err := h.refreshScheduleService.GetSchedules(c.Request.Context())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to get refresh schedules", err.Error()))
+		return
+	}
+	c.JSON(200, types.NewListResponse(schedules, nil, ""))
+}
+
+func (h *refreshScheduleHandler) GetAllRuns(c *gin.Context) {
+	runs := h.refreshScheduleService.GetAllRuns(c.Request.Context())
+	c.JSON(200, types.NewListResponse(runs, nil, ""))
+}
+
+// Run refreshes the columns of a schedule now, without waiting for its next occurrence
+func (h *refreshScheduleHandler) Run(c *gin.Context) {
+	var payload RefreshRunRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	run, err := h.refreshScheduleService.RunNow(c.Request.Context(), payload.Cron, time.Now())
+	if err != nil {
+		c.JSON(500, types.NewErrorResponse("Failed to run refresh schedule", err.Error()))
+		return
+	}
+	if run == nil {
+		c.JSON(409, types.NewErrorResponse("Refresh schedule is already running", payload.Cron))
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(run, "Refresh schedule run successfully"))
+}
@@ -0,0 +1,36 @@
+package refreshschedule
+
+import (
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/utils"
+	"gin-demo/internal/system/dynamiccolumn"
+	"time"
+)
+
+// DynamicColumnRefreshRun records one occurrence of a refresh schedule.
+// The unique (cron, scheduled_at) pair keeps two server instances from running the same occurrence.
+type DynamicColumnRefreshRun struct {
+	ID            int64                      `json:"id" gorm:"primaryKey;column:id"`
+	Cron          string                     `json:"cron" gorm:"column:cron"`
+	ScheduledAt   time.Time                  `json:"scheduled_at" gorm:"column:scheduled_at"`
+	Columns       string                     `json:"columns" gorm:"column:columns"` // comma separated table.column list
+	ProcessedRows int64                      `json:"processed_rows" gorm:"column:processed_rows"`
+	Status        constants.RefreshRunStatus `json:"status" gorm:"column:status"`
+	Error         string                     `json:"error,omitempty" gorm:"column:error"`
+	StartedAt     *time.Time                 `json:"started_at" gorm:"column:started_at"`
+	CompletedAt   *time.Time                 `json:"completed_at" gorm:"column:completed_at"`
+	CreatedAt     time.Time                  `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// RefreshSchedule groups the time dependent and view mode dynamic columns refreshed on the same cron expression
+type RefreshSchedule struct {
+	Cron           string                        `json:"cron"`
+	Columns        []string                      `json:"columns"`
+	NextRunAt      time.Time                     `json:"next_run_at"`
+	schedule       *utils.CronSchedule           `json:"-"`
+	dynamicColumns []dynamiccolumn.DynamicColumn `json:"-"`
+}
+
+type RefreshRunRequest struct {
+	Cron string `json:"cron" binding:"required"`
+}
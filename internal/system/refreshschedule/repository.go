@@ -0,0 +1,69 @@
+package refreshschedule
+
+import (
+	"context"
+	"gin-demo/internal/shared/base"
+	"hash/fnv"
+	"time"
+)
+
+type RefreshScheduleRepository interface {
+	TryLock(ctx context.Context, cron string) (bool, error)
+	ExistsRun(ctx context.Context, cron string, scheduledAt time.Time) (bool, error)
+	GetAllRuns(ctx context.Context) []DynamicColumnRefreshRun
+	CreateRun(ctx context.Context, run *DynamicColumnRefreshRun) (*DynamicColumnRefreshRun, error)
+	UpdateRun(ctx context.Context, run *DynamicColumnRefreshRun) error
+}
+
+type refreshScheduleRepository struct {
+	base.BaseHelper
+}
+
+func NewRefreshScheduleRepository() RefreshScheduleRepository {
+	return &refreshScheduleRepository{}
+}
+
+// TryLock takes the advisory lock of a schedule until the end of the transaction,
+// it returns false without waiting when another instance holds it
+func (r *refreshScheduleRepository) TryLock(ctx context.Context, cron string) (bool, error) {
+	tx := r.GetDbTx(ctx)
+	hash := fnv.New64a()
+	hash.Write([]byte("dynamic_column_refresh:" + cron))
+	var locked bool
+	err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", int64(hash.Sum64())).Scan(&locked).Error
+	if err != nil {
+		return false, err
+	}
+	return locked, nil
+}
+
+func (r *refreshScheduleRepository) ExistsRun(ctx context.Context, cron string, scheduledAt time.Time) (bool, error) {
+	tx := r.GetDbTx(ctx)
+	var count int64
+	err := tx.Model(&DynamicColumnRefreshRun{}).Where("cron = ? AND scheduled_at = ?", cron, scheduledAt).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *refreshScheduleRepository) GetAllRuns(ctx context.Context) []DynamicColumnRefreshRun {
+	tx := r.GetDbTx(ctx)
+	var runs []DynamicColumnRefreshRun
+	tx.Order("id DESC").Find(&runs)
+	return runs
+}
+
+func (r *refreshScheduleRepository) CreateRun(ctx context.Context, run *DynamicColumnRefreshRun) (*DynamicColumnRefreshRun, error) {
+	tx := r.GetDbTx(ctx)
+	err := tx.Create(run).Error
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (r *refreshScheduleRepository) UpdateRun(ctx context.Context, run *DynamicColumnRefreshRun) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Save(run).Error
+}
@@ -0,0 +1,232 @@
+package refreshschedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/utils"
+	"gin-demo/internal/system/dynamiccolumn"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefreshScheduleService interface {
+	GetSchedules(ctx context.Context) ([]RefreshSchedule, error)
+	GetAllRuns(ctx context.Context) []DynamicColumnRefreshRun
+	SetDefaultCron(cron string) error
+	RunSchedule(ctx context.Context, schedule RefreshSchedule, scheduledAt time.Time) (*DynamicColumnRefreshRun, error)
+	RunNow(ctx context.Context, cron string, scheduledAt time.Time) (*DynamicColumnRefreshRun, error)
+	RunDue(ctx context.Context, at time.Time) ([]DynamicColumnRefreshRun, error)
+	Run(ctx context.Context, logger *slog.Logger)
+}
+
+type refreshScheduleService struct {
+	refreshScheduleRepo  RefreshScheduleRepository
+	dynamicColumnRepo    dynamiccolumn.DynamicColumnRepository
+	dynamicColumnService dynamiccolumn.DynamicColumnService
+	defaultCron          string
+	base.BaseHelper
+}
+
+func NewRefreshScheduleService(
+	refreshScheduleRepo RefreshScheduleRepository,
+	dynamicColumnRepo dynamiccolumn.DynamicColumnRepository,
+	dynamicColumnService dynamiccolumn.DynamicColumnService,
+) RefreshScheduleService {
+	return &refreshScheduleService{
+		refreshScheduleRepo:  refreshScheduleRepo,
+		dynamicColumnRepo:    dynamicColumnRepo,
+		dynamicColumnService: dynamicColumnService,
+		defaultCron:          constants.DEFAULT_REFRESH_CRON,
+	}
+}
+
+// SetDefaultCron sets the schedule of the time dependent and view mode columns declaring no refresh_cron
+func (s *refreshScheduleService) SetDefaultCron(cron string) error {
+	if _, err := utils.ParseCron(cron); err != nil {
+		return err
+	}
+	s.defaultCron = cron
+	return nil
+}
+
+// GetSchedules groups the scheduled dynamic columns by cron expression, sorted by cron
+func (s *refreshScheduleService) GetSchedules(ctx context.Context) ([]RefreshSchedule, error) {
+	columns, err := s.dynamicColumnService.GetScheduledColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byCron := make(map[string]*RefreshSchedule)
+	for _, col := range columns {
+		cron := col.RefreshCron
+		if cron == "" {
+			cron = s.defaultCron
+		}
+		schedule, exists := byCron[cron]
+		if !exists {
+			parsed, err := utils.ParseCron(cron)
+			if err != nil {
+				return nil, fmt.Errorf("dynamic column %s.%s: %w", col.TableName, col.Name, err)
+			}
+			schedule = &RefreshSchedule{Cron: cron, NextRunAt: parsed.Next(time.Now()), schedule: parsed}
+			byCron[cron] = schedule
+		}
+		schedule.Columns = append(schedule.Columns, string(col.TableName)+"."+col.Name)
+		schedule.dynamicColumns = append(schedule.dynamicColumns, col)
+	}
+
+	result := make([]RefreshSchedule, 0, len(byCron))
+	for _, schedule := range byCron {
+		result = append(result, *schedule)
+	}
+	slices.SortFunc(result, func(a, b RefreshSchedule) int {
+		return strings.Compare(a.Cron, b.Cron)
+	})
+	return result, nil
+}
+
+func (s *refreshScheduleService) GetAllRuns(ctx context.Context) []DynamicColumnRefreshRun {
+	return s.refreshScheduleRepo.GetAllRuns(ctx)
+}
+
+/*
+* RunSchedule refreshes every row of the columns of a schedule, and their dependants, in one transaction.
+* The view of a view mode column is refreshed concurrently, its readers keep reading the previous values until the commit.
+* The transaction holds the advisory lock of the schedule, so an instance finding it taken skips the occurrence,
+* and the run recorded for the occurrence keeps an instance starting after the commit from running it again.
+* A nil run without error means the occurrence was skipped.
+* ctx must carry the root database connection, or a request transaction for a manual run.
+ */
+func (s *refreshScheduleService) RunSchedule(ctx context.Context, schedule RefreshSchedule, scheduledAt time.Time) (*DynamicColumnRefreshRun, error) {
+	var run *DynamicColumnRefreshRun
+	err := s.GetDbTx(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := context.WithValue(ctx, config.ContextKeyDB, tx)
+
+		locked, err := s.refreshScheduleRepo.TryLock(txCtx, schedule.Cron)
+		if err != nil || !locked {
+			return err
+		}
+		exists, err := s.refreshScheduleRepo.ExistsRun(txCtx, schedule.Cron, scheduledAt)
+		if err != nil || exists {
+			return err
+		}
+
+		now := time.Now()
+		run, err = s.refreshScheduleRepo.CreateRun(txCtx, &DynamicColumnRefreshRun{
+			Cron:        schedule.Cron,
+			ScheduledAt: scheduledAt,
+			Columns:     strings.Join(schedule.Columns, ","),
+			Status:      constants.RefreshRunStatusRunning,
+			StartedAt:   &now,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, col := range schedule.dynamicColumns {
+			ids, err := s.dynamicColumnRepo.GetAllRecordIds(txCtx, col.TableName)
+			if err != nil {
+				return err
+			}
+			err = s.dynamicColumnService.RefreshDynamicColumnOfRecordIds(txCtx, col, ids)
+			if err != nil {
+				return fmt.Errorf("dynamic column %s.%s: %w", col.TableName, col.Name, err)
+			}
+			run.ProcessedRows += int64(len(ids))
+		}
+
+		completedAt := time.Now()
+		run.Status = constants.RefreshRunStatusCompleted
+		run.CompletedAt = &completedAt
+		return s.refreshScheduleRepo.UpdateRun(txCtx, run)
+	})
+	if err != nil {
+		return nil, s.fail(ctx, schedule, scheduledAt, err)
+	}
+	return run, nil
+}
+
+// fail records a failed run of the occurrence, the refresh itself was rolled back, and returns the error
+func (s *refreshScheduleService) fail(ctx context.Context, schedule RefreshSchedule, scheduledAt time.Time, cause error) error {
+	now := time.Now()
+	s.refreshScheduleRepo.CreateRun(ctx, &DynamicColumnRefreshRun{
+		Cron:        schedule.Cron,
+		ScheduledAt: scheduledAt,
+		Columns:     strings.Join(schedule.Columns, ","),
+		Status:      constants.RefreshRunStatusFailed,
+		Error:       cause.Error(),
+		CompletedAt: &now,
+	})
+	return cause
+}
+
+// RunNow runs the schedule of a cron expression outside of its occurrences
+func (s *refreshScheduleService) RunNow(ctx context.Context, cron string, scheduledAt time.Time) (*DynamicColumnRefreshRun, error) {
+	schedules, err := s.GetSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, schedule := range schedules {
+		if schedule.Cron == cron {
+			return s.RunSchedule(ctx, schedule, scheduledAt)
+		}
+	}
+	return nil, fmt.Errorf("no dynamic column is refreshed on %q", cron)
+}
+
+// RunDue runs the schedules with an occurrence at the minute of at.
+// A failing schedule does not keep the others from running, the errors are joined.
+func (s *refreshScheduleService) RunDue(ctx context.Context, at time.Time) ([]DynamicColumnRefreshRun, error) {
+	schedules, err := s.GetSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledAt := at.Truncate(time.Minute)
+	runs := make([]DynamicColumnRefreshRun, 0)
+	errs := make([]error, 0)
+	for _, schedule := range schedules {
+		if !schedule.schedule.Matches(scheduledAt) {
+			continue
+		}
+		run, err := s.RunSchedule(ctx, schedule, scheduledAt)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %q: %w", schedule.Cron, err))
+			continue
+		}
+		if run != nil {
+			runs = append(runs, *run)
+		}
+	}
+	return runs, errors.Join(errs...)
+}
+
+// Run checks the schedules at the start of every minute until ctx is done.
+// ctx must carry the root database connection, not a request transaction.
+func (s *refreshScheduleService) Run(ctx context.Context, logger *slog.Logger) {
+	for {
+		next := time.Now().Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		runs, err := s.RunDue(ctx, next)
+		for _, run := range runs {
+			logger.Info("dynamic column refresh completed",
+				"cron", run.Cron, "scheduled_at", run.ScheduledAt, "columns", run.Columns, "processed_rows", run.ProcessedRows)
+		}
+		if err != nil {
+			logger.Error("dynamic column refresh failed", "error", err.Error())
+		}
+	}
+}
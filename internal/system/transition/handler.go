@@ -0,0 +1,37 @@
+package transition
+
+import (
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/types"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransitionHandler interface {
+	GetUpcoming(c *gin.Context)
+}
+
+type transitionHandler struct {
+	transitionService TransitionService
+}
+
+func NewTransitionHandler(transitionService TransitionService) TransitionHandler {
+	return &transitionHandler{transitionService: transitionService}
+}
+
+// GetUpcoming lists the next transitions, ?limit= bounds the number of rows
+func (h *transitionHandler) GetUpcoming(c *gin.Context) {
+	limit := constants.TRANSITION_BATCH_SIZE
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			c.JSON(400, types.NewErrorResponse("Invalid limit", limitStr))
+			return
+		}
+	}
+
+	transitions := h.transitionService.GetUpcoming(c.Request.Context(), limit)
+	c.JSON(200, types.NewListResponse(transitions, nil, ""))
+}
@@ -0,0 +1,14 @@
+package transition
+
+import (
+	"gin-demo/internal/shared/constants"
+	"time"
+)
+
+// DynamicColumnTransition is the next instant the value of a dynamic column may change at for a row
+type DynamicColumnTransition struct {
+	TableName    constants.TableName `json:"table_name" gorm:"primaryKey;column:table_name"`
+	ColumnName   string              `json:"column_name" gorm:"primaryKey;column:column_name"`
+	RecordId     int64               `json:"record_id" gorm:"primaryKey;column:record_id"`
+	TransitionAt time.Time           `json:"transition_at" gorm:"column:transition_at"`
+}
@@ -0,0 +1,51 @@
+package transition
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+)
+
+type TransitionRepository interface {
+	GetUpcoming(ctx context.Context, limit int) []DynamicColumnTransition
+	ClaimDue(ctx context.Context, limit int) ([]DynamicColumnTransition, error)
+}
+
+type transitionRepository struct {
+	base.BaseHelper
+}
+
+func NewTransitionRepository() TransitionRepository {
+	return &transitionRepository{}
+}
+
+// GetUpcoming returns the earliest transitions first
+func (r *transitionRepository) GetUpcoming(ctx context.Context, limit int) []DynamicColumnTransition {
+	tx := r.GetDbTx(ctx)
+	var transitions []DynamicColumnTransition
+	tx.Order("transition_at").Limit(limit).Find(&transitions)
+	return transitions
+}
+
+// ClaimDue removes and returns at most limit passed transitions, earliest first.
+// Rows claimed by another transaction are skipped, and come back if that transaction rolls back.
+func (r *transitionRepository) ClaimDue(ctx context.Context, limit int) ([]DynamicColumnTransition, error) {
+	tx := r.GetDbTx(ctx)
+	var transitions []DynamicColumnTransition
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE (table_name, column_name, record_id) IN (
+			SELECT table_name, column_name, record_id FROM %[1]s
+			WHERE transition_at <= CURRENT_TIMESTAMP
+			ORDER BY transition_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING table_name, column_name, record_id, transition_at`, constants.TRANSITION_TABLE_NAME)
+	err := tx.Raw(query, limit).Scan(&transitions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
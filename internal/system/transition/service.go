@@ -0,0 +1,115 @@
+package transition
+
+import (
+	"context"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/utils"
+	"gin-demo/internal/system/dynamiccolumn"
+	"log/slog"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TransitionService interface {
+	GetUpcoming(ctx context.Context, limit int) []DynamicColumnTransition
+	RunDue(ctx context.Context) (int, error)
+	Run(ctx context.Context, interval time.Duration, logger *slog.Logger)
+}
+
+type transitionService struct {
+	transitionRepo       TransitionRepository
+	dynamicColumnService dynamiccolumn.DynamicColumnService
+	base.BaseHelper
+}
+
+func NewTransitionService(transitionRepo TransitionRepository, dynamicColumnService dynamiccolumn.DynamicColumnService) TransitionService {
+	return &transitionService{
+		transitionRepo:       transitionRepo,
+		dynamicColumnService: dynamicColumnService,
+	}
+}
+
+func (s *transitionService) GetUpcoming(ctx context.Context, limit int) []DynamicColumnTransition {
+	return s.transitionRepo.GetUpcoming(ctx, limit)
+}
+
+// RunDue refreshes the rows whose transition has passed, batch by batch, and returns the number of refreshed rows.
+// Refreshing a row stores its following transition, if any.
+// ctx must carry the root database connection, not a request transaction.
+func (s *transitionService) RunDue(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		count, err := s.runBatch(ctx)
+		if err != nil {
+			return total, err
+		}
+		total += count
+		if count < constants.TRANSITION_BATCH_SIZE {
+			return total, nil
+		}
+	}
+}
+
+// runBatch claims a batch of passed transitions and refreshes their rows in the same transaction,
+// so a failed refresh leaves the transitions to the next run
+func (s *transitionService) runBatch(ctx context.Context) (int, error) {
+	count := 0
+	err := s.GetDbTx(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := context.WithValue(ctx, config.ContextKeyDB, tx)
+
+		transitions, err := s.transitionRepo.ClaimDue(txCtx, constants.TRANSITION_BATCH_SIZE)
+		if err != nil {
+			return err
+		}
+		count = len(transitions)
+
+		// Several columns of a row may pass a transition, the row is refreshed once
+		idsByTable := make(map[constants.TableName][]int64)
+		for _, transition := range transitions {
+			idsByTable[transition.TableName] = utils.AppendUnique(idsByTable[transition.TableName], transition.RecordId)
+		}
+		tables := make([]constants.TableName, 0, len(idsByTable))
+		for table := range idsByTable {
+			tables = append(tables, table)
+		}
+		slices.Sort(tables)
+
+		for _, table := range tables {
+			_, err = s.dynamicColumnService.RefreshDynamicColumnsOfRecordIds(txCtx, table, idsByTable[table], constants.ActionRefresh, nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Run refreshes the rows whose transition has passed at every interval until ctx is done.
+// ctx must carry the root database connection, not a request transaction.
+func (s *transitionService) Run(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		count, err := s.RunDue(ctx)
+		if count > 0 {
+			logger.Info("dynamic column transitions refreshed", "rows", count)
+		}
+		if err != nil {
+			logger.Error("dynamic column transition refresh failed", "error", err.Error())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gin-demo/internal/shared/constants"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Sign returns the signature of a body sent at timestamp, the value of WEBHOOK_SIGNATURE_HEADER.
+// Receivers recompute it from the raw body and WEBHOOK_TIMESTAMP_HEADER with their copy of the secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature in constant time
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+/*
+* deliver POSTs the signed payload of a delivery to the url of its subscription.
+* Any status outside 2xx fails the attempt, the status code is returned whenever a response was received.
+ */
+func (s *webhookService) deliver(ctx context.Context, subscription WebhookSubscription, delivery *WebhookDelivery) (*int, error) {
+	body, err := json.Marshal(delivery.Payload)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.WEBHOOK_EVENT_HEADER, delivery.Payload.Event)
+	req.Header.Set(constants.WEBHOOK_DELIVERY_HEADER, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(constants.WEBHOOK_TIMESTAMP_HEADER, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(constants.WEBHOOK_SIGNATURE_HEADER, Sign(subscription.Secret, timestamp, body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	// The connection is reused only once the body was read
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &res.StatusCode, fmt.Errorf("%s answered %s", subscription.Url, res.Status)
+	}
+	return &res.StatusCode, nil
+}
@@ -0,0 +1,258 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/shared/constants"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := "0123456789abcdef"
+	timestamp := int64(1767225600)
+	body := []byte(`{"event":"dynamic_column.changed","record_id":1}`)
+	signature := Sign(secret, timestamp, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		want      bool
+	}{
+		{"same request", secret, timestamp, body, true},
+		{"tampered body", secret, timestamp, []byte(`{"event":"dynamic_column.changed","record_id":2}`), false},
+		{"wrong secret", "fedcba9876543210", timestamp, body, false},
+		{"wrong timestamp", secret, timestamp + 1, body, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.timestamp, tt.body, signature); got != tt.want {
+				t.Fatalf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, constants.WEBHOOK_BACKOFF_BASE},
+		{1, constants.WEBHOOK_BACKOFF_BASE},
+		{2, 2 * constants.WEBHOOK_BACKOFF_BASE},
+		{3, 4 * constants.WEBHOOK_BACKOFF_BASE},
+		{5, 16 * constants.WEBHOOK_BACKOFF_BASE},
+		{10, 512 * constants.WEBHOOK_BACKOFF_BASE},
+		{11, constants.WEBHOOK_BACKOFF_MAX},
+		{100, constants.WEBHOOK_BACKOFF_MAX},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempts), func(t *testing.T) {
+			if got := backoff(tt.attempts); got != tt.want {
+				t.Fatalf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeConnPool lets the claim transaction begin and commit without a database, the fake repository runs no query
+type fakeConnPool struct{}
+
+func (fakeConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("no database")
+}
+func (fakeConnPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return nil, errors.New("no database")
+}
+func (fakeConnPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return nil, errors.New("no database")
+}
+func (fakeConnPool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return nil
+}
+func (p fakeConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+func (fakeConnPool) Commit() error   { return nil }
+func (fakeConnPool) Rollback() error { return nil }
+
+func newFakeDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: fakeConnPool{}}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// fakeWebhookRepository keeps the deliveries in memory, the methods the worker does not call are not implemented
+type fakeWebhookRepository struct {
+	WebhookRepository
+	subscriptions []WebhookSubscription
+	deliveries    []WebhookDelivery
+}
+
+func (r *fakeWebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	claimed := make([]WebhookDelivery, 0)
+	now := time.Now()
+	for i := range r.deliveries {
+		delivery := &r.deliveries[i]
+		if len(claimed) == limit || delivery.Status != constants.WebhookDeliveryStatusPending || delivery.NextAttemptAt.After(now) ||
+			(delivery.LockedUntil != nil && delivery.LockedUntil.After(now)) {
+			continue
+		}
+		lockedUntil := now.Add(lease)
+		delivery.LockedUntil = &lockedUntil
+		claimed = append(claimed, *delivery)
+	}
+	return claimed, nil
+}
+
+func (r *fakeWebhookRepository) GetByIds(ctx context.Context, ids []int64) ([]WebhookSubscription, error) {
+	return r.subscriptions, nil
+}
+
+func (r *fakeWebhookRepository) UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	for i := range r.deliveries {
+		if r.deliveries[i].ID == delivery.ID {
+			r.deliveries[i] = *delivery
+			return nil
+		}
+	}
+	return errors.New("unknown delivery")
+}
+
+func TestRunPending(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		attempts      int
+		wantStatus    constants.WebhookDeliveryStatus
+		wantResult    DeliveryRunResult
+		wantNextAfter time.Duration
+	}{
+		{
+			name:       "delivered",
+			status:     http.StatusNoContent,
+			wantStatus: constants.WebhookDeliveryStatusDelivered,
+			wantResult: DeliveryRunResult{Delivered: 1},
+		},
+		{
+			name:          "retried",
+			status:        http.StatusInternalServerError,
+			attempts:      2,
+			wantStatus:    constants.WebhookDeliveryStatusPending,
+			wantResult:    DeliveryRunResult{Retried: 1},
+			wantNextAfter: backoff(3),
+		},
+		{
+			name:       "dead after the last attempt",
+			status:     http.StatusServiceUnavailable,
+			attempts:   constants.WEBHOOK_MAX_ATTEMPTS - 1,
+			wantStatus: constants.WebhookDeliveryStatusDead,
+			wantResult: DeliveryRunResult{Dead: 1},
+		},
+	}
+	secret := "0123456789abcdef"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verified bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				body, _ := io.ReadAll(req.Body)
+				timestamp, _ := strconv.ParseInt(req.Header.Get(constants.WEBHOOK_TIMESTAMP_HEADER), 10, 64)
+				verified = Verify(secret, timestamp, body, req.Header.Get(constants.WEBHOOK_SIGNATURE_HEADER)) &&
+					req.Header.Get(constants.WEBHOOK_DELIVERY_HEADER) == "7"
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			repo := &fakeWebhookRepository{
+				subscriptions: []WebhookSubscription{{ID: 1, Url: server.URL, Secret: secret, IsActive: true}},
+				deliveries: []WebhookDelivery{{
+					ID:             7,
+					SubscriptionID: 1,
+					Payload:        WebhookPayload{Event: constants.WEBHOOK_EVENT, RecordId: 1},
+					Status:         constants.WebhookDeliveryStatusPending,
+					Attempts:       tt.attempts,
+					NextAttemptAt:  time.Now(),
+				}},
+			}
+			s := &webhookService{webhookRepo: repo, client: server.Client()}
+			ctx := context.WithValue(context.Background(), config.ContextKeyDB, newFakeDB(t))
+
+			start := time.Now()
+			result, err := s.RunPending(ctx, 10)
+			if err != nil {
+				t.Fatalf("RunPending() returned %v", err)
+			}
+			if *result != tt.wantResult {
+				t.Fatalf("RunPending() = %+v, want %+v", *result, tt.wantResult)
+			}
+			if !verified {
+				t.Fatal("the receiver could not verify the signature of the delivery")
+			}
+
+			delivery := repo.deliveries[0]
+			if delivery.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", delivery.Status, tt.wantStatus)
+			}
+			if delivery.Attempts != tt.attempts+1 {
+				t.Fatalf("attempts = %d, want %d", delivery.Attempts, tt.attempts+1)
+			}
+			if delivery.LastStatusCode == nil || *delivery.LastStatusCode != tt.status {
+				t.Fatalf("last status code = %v, want %d", delivery.LastStatusCode, tt.status)
+			}
+			if delivery.LockedUntil != nil {
+				t.Fatalf("locked until = %v, want the lease released", delivery.LockedUntil)
+			}
+			if (delivery.Error == "") != (tt.wantStatus == constants.WebhookDeliveryStatusDelivered) {
+				t.Fatalf("error = %q for a %s delivery", delivery.Error, delivery.Status)
+			}
+			if (delivery.DeliveredAt != nil) != (tt.wantStatus == constants.WebhookDeliveryStatusDelivered) {
+				t.Fatalf("delivered at = %v for a %s delivery", delivery.DeliveredAt, delivery.Status)
+			}
+			if tt.wantNextAfter > 0 && delivery.NextAttemptAt.Before(start.Add(tt.wantNextAfter)) {
+				t.Fatalf("next attempt at = %v, want at least %v after %v", delivery.NextAttemptAt, tt.wantNextAfter, start)
+			}
+		})
+	}
+}
+
+func TestRunPendingUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	repo := &fakeWebhookRepository{
+		subscriptions: []WebhookSubscription{{ID: 1, Url: url, Secret: "0123456789abcdef", IsActive: true}},
+		deliveries: []WebhookDelivery{{
+			ID:             1,
+			SubscriptionID: 1,
+			Status:         constants.WebhookDeliveryStatusPending,
+			NextAttemptAt:  time.Now(),
+		}},
+	}
+	s := &webhookService{webhookRepo: repo, client: &http.Client{Timeout: time.Second}}
+	result, err := s.RunPending(context.WithValue(context.Background(), config.ContextKeyDB, newFakeDB(t)), 10)
+	if err != nil {
+		t.Fatalf("RunPending() returned %v", err)
+	}
+	if result.Retried != 1 {
+		t.Fatalf("RunPending() = %+v, want one retried delivery", *result)
+	}
+	delivery := repo.deliveries[0]
+	if delivery.LastStatusCode != nil || delivery.Attempts != 1 || delivery.Status != constants.WebhookDeliveryStatusPending {
+		t.Fatalf("delivery = %+v, want a pending delivery without status code after one attempt", delivery)
+	}
+}
@@ -0,0 +1,9 @@
+package webhook
+
+import "errors"
+
+// ErrInvalidSubscription is returned when a subscription names a column whose changes are not published
+var ErrInvalidSubscription = errors.New("invalid webhook subscription")
+
+// ErrDeliveryPending is returned when replaying a delivery that is still waiting for its next attempt
+var ErrDeliveryPending = errors.New("webhook delivery is pending")
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"errors"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/shared/types"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler interface {
+	GetAll(c *gin.Context)
+	GetById(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+	GetAllDeliveries(c *gin.Context)
+	GetDeadLetters(c *gin.Context)
+	Replay(c *gin.Context)
+}
+
+type webhookHandler struct {
+	webhookService WebhookService
+}
+
+func NewWebhookHandler(webhookService WebhookService) WebhookHandler {
+	return &webhookHandler{webhookService: webhookService}
+}
+
+// writeError maps the errors of the service to a status: invalid subscriptions and replays are the caller's fault
+func (h *webhookHandler) writeError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(404, types.NewErrorResponse("Not found", err.Error()))
+	case errors.Is(err, ErrInvalidSubscription):
+		c.JSON(400, types.NewErrorResponse(message, err.Error()))
+	case errors.Is(err, ErrDeliveryPending):
+		c.JSON(409, types.NewErrorResponse(message, err.Error()))
+	default:
+		c.JSON(500, types.NewErrorResponse(message, err.Error()))
+	}
+}
+
+func (h *webhookHandler) GetAll(c *gin.Context) {
+	subscriptions := h.webhookService.GetAll(c.Request.Context())
+	c.JSON(200, types.NewListResponse(subscriptions, nil, ""))
+}
+
+func (h *webhookHandler) GetById(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	subscription, err := h.webhookService.GetById(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(404, types.NewErrorResponse("Not found", err.Error()))
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(subscription, ""))
+}
+
+func (h *webhookHandler) Create(c *gin.Context) {
+	var payload WebhookSubscriptionCreateRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	subscription, err := h.webhookService.Create(c.Request.Context(), &payload)
+	if err != nil {
+		h.writeError(c, "Failed to create webhook subscription", err)
+		return
+	}
+
+	c.JSON(201, types.NewSingleResponse(subscription, "Webhook subscription created successfully"))
+}
+
+func (h *webhookHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	var payload WebhookSubscriptionUpdateRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	subscription, err := h.webhookService.Update(c.Request.Context(), id, &payload)
+	if err != nil {
+		h.writeError(c, "Failed to update webhook subscription", err)
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(subscription, "Webhook subscription updated successfully"))
+}
+
+func (h *webhookHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	err = h.webhookService.Delete(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, "Failed to delete webhook subscription", err)
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse[WebhookSubscription](nil, "Webhook subscription deleted successfully"))
+}
+
+// GetAllDeliveries lists the latest deliveries, filtered by ?subscription_id= and status=
+func (h *webhookHandler) GetAllDeliveries(c *gin.Context) {
+	var query DeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+
+	deliveries := h.webhookService.GetAllDeliveries(c.Request.Context(), query)
+	c.JSON(200, types.NewListResponse(deliveries, nil, ""))
+}
+
+// GetDeadLetters lists the latest deliveries out of attempts, filtered by ?subscription_id=
+func (h *webhookHandler) GetDeadLetters(c *gin.Context) {
+	var query DeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid request", err.Error()))
+		return
+	}
+	query.Status = constants.WebhookDeliveryStatusDead
+
+	deliveries := h.webhookService.GetAllDeliveries(c.Request.Context(), query)
+	c.JSON(200, types.NewListResponse(deliveries, nil, ""))
+}
+
+func (h *webhookHandler) Replay(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, types.NewErrorResponse("Invalid ID", err.Error()))
+		return
+	}
+
+	delivery, err := h.webhookService.Replay(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, "Failed to replay webhook delivery", err)
+		return
+	}
+
+	c.JSON(200, types.NewSingleResponse(delivery, "Webhook delivery queued again"))
+}
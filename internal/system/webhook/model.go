@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"gin-demo/internal/shared/constants"
+	"time"
+)
+
+// WebhookSubscription registers a url notified of the changes of a dynamic column,
+// of the changes to NewValue only when it is set
+type WebhookSubscription struct {
+	ID         int64               `json:"id" gorm:"primaryKey;column:id"`
+	Name       string              `json:"name" gorm:"column:name"`
+	TableName  constants.TableName `json:"table_name" gorm:"column:table_name"`
+	ColumnName string              `json:"column_name" gorm:"column:column_name"`
+	NewValue   *string             `json:"new_value" gorm:"column:new_value"` // compared as text, like the values of the history
+	Url        string              `json:"url" gorm:"column:url"`
+	Secret     string              `json:"-" gorm:"column:secret"` // signs the payloads, never returned
+	IsActive   bool                `json:"is_active" gorm:"column:is_active"`
+	CreatedAt  time.Time           `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt  time.Time           `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type WebhookSubscriptionCreateRequest struct {
+	Name       string              `json:"name" binding:"required"`
+	TableName  constants.TableName `json:"table_name" binding:"required"`
+	ColumnName string              `json:"column_name" binding:"required"`
+	NewValue   *string             `json:"new_value"` // every change when empty
+	Url        string              `json:"url" binding:"required,url"`
+	Secret     string              `json:"secret" binding:"required,min=16"`
+	IsActive   *bool               `json:"is_active"` // active when empty
+}
+
+type WebhookSubscriptionUpdateRequest struct {
+	Name       *string              `json:"name,omitempty"`
+	TableName  *constants.TableName `json:"table_name,omitempty"`
+	ColumnName *string              `json:"column_name,omitempty"`
+	NewValue   *string              `json:"new_value,omitempty"` // an empty value removes the filter
+	Url        *string              `json:"url,omitempty" binding:"omitempty,url"`
+	Secret     *string              `json:"secret,omitempty" binding:"omitempty,min=16"`
+	IsActive   *bool                `json:"is_active,omitempty"`
+}
+
+// WebhookDelivery is a payload to POST to the url of a subscription.
+// It is written in the transaction of the refresh that changed the value, so a rolled back refresh delivers nothing.
+type WebhookDelivery struct {
+	ID             int64                           `json:"id" gorm:"primaryKey;column:id"`
+	SubscriptionID int64                           `json:"subscription_id" gorm:"column:subscription_id"`
+	Payload        WebhookPayload                  `json:"payload" gorm:"column:payload;type:jsonb;serializer:json"`
+	Status         constants.WebhookDeliveryStatus `json:"status" gorm:"column:status"`
+	Attempts       int                             `json:"attempts" gorm:"column:attempts"`
+	NextAttemptAt  time.Time                       `json:"next_attempt_at" gorm:"column:next_attempt_at"`
+	LastStatusCode *int                            `json:"last_status_code" gorm:"column:last_status_code"` // nil when no response was received
+	Error          string                          `json:"error,omitempty" gorm:"column:error"`
+	DeliveredAt    *time.Time                      `json:"delivered_at" gorm:"column:delivered_at"`
+	LockedUntil    *time.Time                      `json:"locked_until,omitempty" gorm:"column:locked_until"` // set while a worker is sending it
+	CreatedAt      time.Time                       `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// WebhookPayload is the JSON body POSTed for a changed value, the history row of the change.
+// The triggers of trigger mode columns build the same object, see buildDbTriggerFormulas.
+type WebhookPayload struct {
+	Event           string              `json:"event"`
+	DynamicColumnID int64               `json:"dynamic_column_id"`
+	TableName       constants.TableName `json:"table_name"`
+	ColumnName      string              `json:"column_name"`
+	RecordId        int64               `json:"record_id"`
+	OldValue        *string             `json:"old_value"`
+	NewValue        *string             `json:"new_value"`
+	OriginTable     constants.TableName `json:"origin_table"`
+	OriginAction    constants.Action    `json:"origin_action"`
+	OriginIds       []int64             `json:"origin_ids"`
+	RequestId       *string             `json:"request_id"`
+	ChangedAt       time.Time           `json:"changed_at"`
+}
+
+// DeliveryQuery filters the deliveries, latest first
+type DeliveryQuery struct {
+	SubscriptionID int64                           `form:"subscription_id"`
+	Status         constants.WebhookDeliveryStatus `form:"status"`
+	Limit          int                             `form:"limit" binding:"omitempty,min=1"`
+}
@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository interface {
+	GetAll(ctx context.Context) []WebhookSubscription
+	GetById(ctx context.Context, id int64) (*WebhookSubscription, error)
+	GetByIds(ctx context.Context, ids []int64) ([]WebhookSubscription, error)
+	GetActiveByColumn(ctx context.Context, table constants.TableName, column string) ([]WebhookSubscription, error)
+	Create(ctx context.Context, subscription *WebhookSubscription) error
+	Update(ctx context.Context, subscription *WebhookSubscription) error
+	Delete(ctx context.Context, id int64) error
+	GetAllDeliveries(ctx context.Context, query DeliveryQuery) []WebhookDelivery
+	GetDeliveryById(ctx context.Context, id int64) (*WebhookDelivery, error)
+	CreateDeliveries(ctx context.Context, deliveries []WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error)
+}
+
+type webhookRepository struct {
+	base.BaseHelper
+}
+
+func NewWebhookRepository() WebhookRepository {
+	return &webhookRepository{}
+}
+
+func (r *webhookRepository) GetAll(ctx context.Context) []WebhookSubscription {
+	tx := r.GetDbTx(ctx)
+	subscriptions := make([]WebhookSubscription, 0)
+	tx.Order("id").Find(&subscriptions)
+	return subscriptions
+}
+
+func (r *webhookRepository) GetById(ctx context.Context, id int64) (*WebhookSubscription, error) {
+	tx := r.GetDbTx(ctx)
+	var subscription WebhookSubscription
+	err := tx.First(&subscription, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *webhookRepository) GetByIds(ctx context.Context, ids []int64) ([]WebhookSubscription, error) {
+	tx := r.GetDbTx(ctx)
+	var subscriptions []WebhookSubscription
+	err := tx.Where("id IN ?", ids).Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *webhookRepository) GetActiveByColumn(ctx context.Context, table constants.TableName, column string) ([]WebhookSubscription, error) {
+	tx := r.GetDbTx(ctx)
+	var subscriptions []WebhookSubscription
+	err := tx.Where("table_name = ? AND column_name = ? AND is_active", table, column).Order("id").Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *webhookRepository) Create(ctx context.Context, subscription *WebhookSubscription) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Create(subscription).Error
+}
+
+func (r *webhookRepository) Update(ctx context.Context, subscription *WebhookSubscription) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Save(subscription).Error
+}
+
+// Delete removes a subscription, its deliveries are removed with it
+func (r *webhookRepository) Delete(ctx context.Context, id int64) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Delete(&WebhookSubscription{}, id).Error
+}
+
+// GetAllDeliveries returns the deliveries matching the query, latest first
+func (r *webhookRepository) GetAllDeliveries(ctx context.Context, query DeliveryQuery) []WebhookDelivery {
+	tx := r.GetDbTx(ctx)
+	if query.SubscriptionID != 0 {
+		tx = tx.Where("subscription_id = ?", query.SubscriptionID)
+	}
+	if query.Status != "" {
+		tx = tx.Where("status = ?", query.Status)
+	}
+	deliveries := make([]WebhookDelivery, 0)
+	tx.Order("id DESC").Limit(query.Limit).Find(&deliveries)
+	return deliveries
+}
+
+func (r *webhookRepository) GetDeliveryById(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	tx := r.GetDbTx(ctx)
+	var delivery WebhookDelivery
+	err := tx.First(&delivery, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookRepository) CreateDeliveries(ctx context.Context, deliveries []WebhookDelivery) error {
+	tx := r.GetDbTx(ctx)
+	return tx.CreateInBatches(deliveries, constants.HISTORY_MAX_LIMIT).Error
+}
+
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	tx := r.GetDbTx(ctx)
+	return tx.Save(delivery).Error
+}
+
+/*
+* ClaimDue leases at most limit pending deliveries whose next attempt is due, oldest first: they are locked, skipping the
+* deliveries locked by another worker, and their locked_until is set lease ahead so they are not claimed again once the
+* transaction commits, until a result is recorded or the lease expires because the worker stopped.
+* The deliveries of inactive subscriptions are not claimed, they wait for their subscription to be activated again.
+ */
+func (r *webhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	tx := r.GetDbTx(ctx)
+	var deliveries []WebhookDelivery
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= now()", constants.WebhookDeliveryStatusPending).
+		Where("locked_until IS NULL OR locked_until <= now()").
+		Where(fmt.Sprintf("subscription_id IN (SELECT id FROM %s WHERE is_active)", constants.WEBHOOK_SUBSCRIPTION_TABLE_NAME)).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.ID)
+	}
+	err = tx.Model(&WebhookDelivery{}).Where("id IN ?", ids).
+		Update("locked_until", gorm.Expr("now() + ? * interval '1 second'", lease.Seconds())).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
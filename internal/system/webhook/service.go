@@ -0,0 +1,352 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/internal/application/config"
+	"gin-demo/internal/shared/base"
+	"gin-demo/internal/shared/constants"
+	"gin-demo/internal/system/dynamiccolumn"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookService interface {
+	GetAll(ctx context.Context) []WebhookSubscription
+	GetById(ctx context.Context, id int64) (*WebhookSubscription, error)
+	Create(ctx context.Context, payload *WebhookSubscriptionCreateRequest) (*WebhookSubscription, error)
+	Update(ctx context.Context, id int64, payload *WebhookSubscriptionUpdateRequest) (*WebhookSubscription, error)
+	Delete(ctx context.Context, id int64) error
+	PublishChanges(ctx context.Context, col dynamiccolumn.DynamicColumn, changes []dynamiccolumn.ValueChange, origin dynamiccolumn.RefreshOrigin) error
+	GetAllDeliveries(ctx context.Context, query DeliveryQuery) []WebhookDelivery
+	Replay(ctx context.Context, id int64) (*WebhookDelivery, error)
+	RunPending(ctx context.Context, batchSize int) (*DeliveryRunResult, error)
+	Run(ctx context.Context, interval time.Duration, batchSize int, logger *slog.Logger)
+}
+
+// DeliveryRunResult counts the deliveries a run went through
+type DeliveryRunResult struct {
+	Delivered int `json:"delivered"`
+	Retried   int `json:"retried"`
+	Dead      int `json:"dead"`
+}
+
+type webhookService struct {
+	webhookRepo       WebhookRepository
+	dynamicColumnRepo dynamiccolumn.DynamicColumnRepository
+	client            *http.Client
+	base.BaseHelper
+}
+
+func NewWebhookService(webhookRepo WebhookRepository, dynamicColumnRepo dynamiccolumn.DynamicColumnRepository) WebhookService {
+	return &webhookService{
+		webhookRepo:       webhookRepo,
+		dynamicColumnRepo: dynamicColumnRepo,
+		client:            &http.Client{Timeout: constants.WEBHOOK_TIMEOUT},
+	}
+}
+
+func (s *webhookService) GetAll(ctx context.Context) []WebhookSubscription {
+	return s.webhookRepo.GetAll(ctx)
+}
+
+func (s *webhookService) GetById(ctx context.Context, id int64) (*WebhookSubscription, error) {
+	return s.webhookRepo.GetById(ctx, id)
+}
+
+func (s *webhookService) Create(ctx context.Context, payload *WebhookSubscriptionCreateRequest) (*WebhookSubscription, error) {
+	subscription := &WebhookSubscription{
+		Name:       payload.Name,
+		TableName:  payload.TableName,
+		ColumnName: payload.ColumnName,
+		NewValue:   payload.NewValue,
+		Url:        payload.Url,
+		Secret:     payload.Secret,
+		IsActive:   payload.IsActive == nil || *payload.IsActive,
+	}
+	err := s.checkColumn(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	err = s.webhookRepo.Create(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *webhookService) Update(ctx context.Context, id int64, payload *WebhookSubscriptionUpdateRequest) (*WebhookSubscription, error) {
+	subscription, err := s.webhookRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Name != nil {
+		subscription.Name = *payload.Name
+	}
+	if payload.TableName != nil {
+		subscription.TableName = *payload.TableName
+	}
+	if payload.ColumnName != nil {
+		subscription.ColumnName = *payload.ColumnName
+	}
+	if payload.NewValue != nil {
+		subscription.NewValue = payload.NewValue
+		if *payload.NewValue == "" {
+			subscription.NewValue = nil
+		}
+	}
+	if payload.Url != nil {
+		subscription.Url = *payload.Url
+	}
+	if payload.Secret != nil {
+		subscription.Secret = *payload.Secret
+	}
+	if payload.IsActive != nil {
+		subscription.IsActive = *payload.IsActive
+	}
+
+	err = s.checkColumn(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	err = s.webhookRepo.Update(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *webhookService) Delete(ctx context.Context, id int64) error {
+	_, err := s.webhookRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.webhookRepo.Delete(ctx, id)
+}
+
+// checkColumn rejects the subscriptions to a column that is not a dynamic column refreshed row by row.
+// The values of a view mode column are recomputed by refreshing its view and a virtual column is computed when read,
+// no change is published for them.
+func (s *webhookService) checkColumn(ctx context.Context, subscription *WebhookSubscription) error {
+	columns, err := s.dynamicColumnRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, col := range columns {
+		if col.TableName != subscription.TableName || col.Name != subscription.ColumnName {
+			continue
+		}
+		if !col.IsStored() {
+			return fmt.Errorf("%w: %s.%s is a %s column, its changes are not published",
+				ErrInvalidSubscription, col.TableName, col.Name, col.RefreshMode)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s.%s is not a dynamic column", ErrInvalidSubscription, subscription.TableName, subscription.ColumnName)
+}
+
+/*
+* PublishChanges writes a delivery for every changed value to every active subscription of the column it matches.
+* It implements dynamiccolumn.ChangePublisher, the deliveries are written in the transaction of the refresh.
+ */
+func (s *webhookService) PublishChanges(
+	ctx context.Context, col dynamiccolumn.DynamicColumn, changes []dynamiccolumn.ValueChange, origin dynamiccolumn.RefreshOrigin) error {
+	subscriptions, err := s.webhookRepo.GetActiveByColumn(ctx, col.TableName, col.Name)
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+
+	var requestId *string
+	if id := s.GetRequestId(ctx); id != "" {
+		requestId = &id
+	}
+	originIds := origin.Ids
+	if originIds == nil {
+		originIds = []int64{}
+	}
+	now := time.Now()
+
+	deliveries := make([]WebhookDelivery, 0)
+	for _, change := range changes {
+		for _, subscription := range subscriptions {
+			if !subscription.matches(change) {
+				continue
+			}
+			deliveries = append(deliveries, WebhookDelivery{
+				SubscriptionID: subscription.ID,
+				Payload: WebhookPayload{
+					Event:           constants.WEBHOOK_EVENT,
+					DynamicColumnID: col.ID,
+					TableName:       col.TableName,
+					ColumnName:      col.Name,
+					RecordId:        change.RecordId,
+					OldValue:        change.OldValue,
+					NewValue:        change.NewValue,
+					OriginTable:     origin.Table,
+					OriginAction:    origin.Action,
+					OriginIds:       originIds,
+					RequestId:       requestId,
+					ChangedAt:       now,
+				},
+				Status:        constants.WebhookDeliveryStatusPending,
+				NextAttemptAt: now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.webhookRepo.CreateDeliveries(ctx, deliveries)
+}
+
+// matches checks the value filter of the subscription, a nil new value never matches a filter
+func (subscription WebhookSubscription) matches(change dynamiccolumn.ValueChange) bool {
+	if subscription.NewValue == nil {
+		return true
+	}
+	return change.NewValue != nil && *change.NewValue == *subscription.NewValue
+}
+
+func (s *webhookService) GetAllDeliveries(ctx context.Context, query DeliveryQuery) []WebhookDelivery {
+	if query.Limit == 0 {
+		query.Limit = constants.HISTORY_DEFAULT_LIMIT
+	}
+	query.Limit = min(query.Limit, constants.HISTORY_MAX_LIMIT)
+	return s.webhookRepo.GetAllDeliveries(ctx, query)
+}
+
+// Replay queues a dead or an already delivered delivery again, with fresh attempts and its original payload
+func (s *webhookService) Replay(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.GetDeliveryById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == constants.WebhookDeliveryStatusPending {
+		return nil, fmt.Errorf("%w: delivery %d is already waiting for its next attempt", ErrDeliveryPending, id)
+	}
+	delivery.Status = constants.WebhookDeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.Error = ""
+	err = s.webhookRepo.UpdateDelivery(ctx, delivery)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// RunPending delivers the due deliveries batch by batch.
+// ctx must carry the root database connection, not a request transaction.
+func (s *webhookService) RunPending(ctx context.Context, batchSize int) (*DeliveryRunResult, error) {
+	if batchSize <= 0 {
+		batchSize = constants.WEBHOOK_BATCH_SIZE
+	}
+	result := &DeliveryRunResult{}
+	for {
+		claimed, err := s.runBatch(ctx, batchSize, result)
+		if err != nil {
+			return result, err
+		}
+		if claimed < batchSize {
+			return result, nil
+		}
+	}
+}
+
+/*
+* runBatch claims a batch of due deliveries in a short transaction, then POSTs them one after the other outside of it.
+* The claim leases the deliveries for as long as the whole batch may take, so no other worker sends them meanwhile.
+* The result of every delivery is recorded on its own: delivered, scheduled again after its backoff,
+* or moved to the dead-letter list once out of attempts.
+ */
+func (s *webhookService) runBatch(ctx context.Context, batchSize int, result *DeliveryRunResult) (int, error) {
+	lease := time.Duration(batchSize+1) * constants.WEBHOOK_TIMEOUT
+	var deliveries []WebhookDelivery
+	var subscriptions []WebhookSubscription
+	err := s.GetDbTx(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := context.WithValue(ctx, config.ContextKeyDB, tx)
+
+		var err error
+		deliveries, err = s.webhookRepo.ClaimDue(txCtx, batchSize, lease)
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+		subscriptionIds := make([]int64, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			subscriptionIds = append(subscriptionIds, delivery.SubscriptionID)
+		}
+		subscriptions, err = s.webhookRepo.GetByIds(txCtx, subscriptionIds)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	byId := make(map[int64]WebhookSubscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		byId[subscription.ID] = subscription
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		statusCode, err := s.deliver(ctx, byId[delivery.SubscriptionID], delivery)
+		delivery.Attempts++
+		delivery.LastStatusCode = statusCode
+		delivery.LockedUntil = nil
+		switch {
+		case err == nil:
+			now := time.Now()
+			delivery.Status = constants.WebhookDeliveryStatusDelivered
+			delivery.DeliveredAt = &now
+			delivery.Error = ""
+			result.Delivered++
+		case delivery.Attempts >= constants.WEBHOOK_MAX_ATTEMPTS:
+			delivery.Status = constants.WebhookDeliveryStatusDead
+			delivery.Error = err.Error()
+			result.Dead++
+		default:
+			delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts))
+			delivery.Error = err.Error()
+			result.Retried++
+		}
+		// A result failing to be recorded leaves the delivery to be sent again once its lease expires
+		err = s.webhookRepo.UpdateDelivery(ctx, delivery)
+		if err != nil {
+			return len(deliveries), err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// backoff is the wait before the next attempt of a delivery that failed attempts times
+func backoff(attempts int) time.Duration {
+	wait := constants.WEBHOOK_BACKOFF_BASE
+	for i := 1; i < attempts && wait < constants.WEBHOOK_BACKOFF_MAX; i++ {
+		wait *= 2
+	}
+	return min(wait, constants.WEBHOOK_BACKOFF_MAX)
+}
+
+// Run delivers the due deliveries at every interval until ctx is done.
+// ctx must carry the root database connection, not a request transaction.
+func (s *webhookService) Run(ctx context.Context, interval time.Duration, batchSize int, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := s.RunPending(ctx, batchSize)
+		if result.Delivered > 0 || result.Retried > 0 || result.Dead > 0 {
+			logger.Info("webhook deliveries sent", "delivered", result.Delivered, "retried", result.Retried, "dead", result.Dead)
+		}
+		if err != nil {
+			logger.Error("webhook delivery failed", "error", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE dynamic_column ADD COLUMN IF NOT EXISTS user_formula TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE dynamic_column DROP COLUMN IF EXISTS user_formula;
+-- +goose StatementEnd
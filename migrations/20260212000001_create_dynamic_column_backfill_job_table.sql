@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS dynamic_column_backfill_job (
+    id BIGSERIAL PRIMARY KEY,
+    dynamic_column_id BIGINT NOT NULL,
+    table_name VARCHAR(255) NOT NULL,
+    column_name VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    chunk_size INTEGER NOT NULL DEFAULT 10000,
+    last_id BIGINT NOT NULL DEFAULT 0,
+    total_rows BIGINT NOT NULL DEFAULT 0,
+    processed_rows BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_backfill_job_dynamic_column FOREIGN KEY (dynamic_column_id) REFERENCES dynamic_column(id) ON DELETE CASCADE,
+    CONSTRAINT chk_backfill_job_chunk_size_positive CHECK (chunk_size > 0)
+);
+
+CREATE INDEX idx_dynamic_column_backfill_job_status ON dynamic_column_backfill_job(status);
+CREATE INDEX idx_dynamic_column_backfill_job_dynamic_column_id ON dynamic_column_backfill_job(dynamic_column_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS dynamic_column_backfill_job;
+-- +goose StatementEnd
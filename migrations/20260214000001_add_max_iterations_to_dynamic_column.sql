@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE dynamic_column ADD COLUMN IF NOT EXISTS max_iterations INT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE dynamic_column DROP COLUMN IF EXISTS max_iterations;
+-- +goose StatementEnd
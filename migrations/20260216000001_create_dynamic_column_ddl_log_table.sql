@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS dynamic_column_ddl_log (
+    id BIGSERIAL PRIMARY KEY,
+    dynamic_column_id BIGINT NOT NULL,
+    table_name VARCHAR(255) NOT NULL,
+    column_name VARCHAR(255) NOT NULL,
+    operation VARCHAR(50) NOT NULL,
+    statement TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_dynamic_column_ddl_log_dynamic_column_id ON dynamic_column_ddl_log(dynamic_column_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS dynamic_column_ddl_log;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE dynamic_column ADD COLUMN IF NOT EXISTS refresh_cron VARCHAR(255) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE dynamic_column DROP COLUMN IF EXISTS refresh_cron;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS dynamic_column_refresh_run (
+    id BIGSERIAL PRIMARY KEY,
+    cron VARCHAR(255) NOT NULL,
+    scheduled_at TIMESTAMPTZ NOT NULL,
+    columns TEXT NOT NULL DEFAULT '',
+    processed_rows BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(50) NOT NULL DEFAULT 'running',
+    error TEXT,
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_refresh_run_cron_scheduled_at UNIQUE (cron, scheduled_at)
+);
+
+CREATE INDEX idx_dynamic_column_refresh_run_scheduled_at ON dynamic_column_refresh_run(scheduled_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS dynamic_column_refresh_run;
+-- +goose StatementEnd
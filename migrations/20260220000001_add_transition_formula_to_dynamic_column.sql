@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE dynamic_column ADD COLUMN IF NOT EXISTS transition_formula TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE dynamic_column DROP COLUMN IF EXISTS transition_formula;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS dynamic_column_transition (
+    table_name VARCHAR(255) NOT NULL,
+    column_name VARCHAR(255) NOT NULL,
+    record_id BIGINT NOT NULL,
+    transition_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (table_name, column_name, record_id)
+);
+
+CREATE INDEX idx_dynamic_column_transition_transition_at ON dynamic_column_transition(transition_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS dynamic_column_transition;
+-- +goose StatementEnd
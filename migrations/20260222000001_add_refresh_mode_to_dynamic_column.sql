@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE dynamic_column ADD COLUMN IF NOT EXISTS refresh_mode VARCHAR(20) NOT NULL DEFAULT 'sync';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE dynamic_column DROP COLUMN IF EXISTS refresh_mode;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS dynamic_column_history (
+    id BIGSERIAL PRIMARY KEY,
+    dynamic_column_id BIGINT NOT NULL,
+    table_name VARCHAR(255) NOT NULL,
+    column_name VARCHAR(255) NOT NULL,
+    record_id BIGINT NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    origin_table VARCHAR(255) NOT NULL,
+    origin_action VARCHAR(50) NOT NULL,
+    origin_ids JSONB NOT NULL DEFAULT '[]',
+    request_id VARCHAR(255),
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_dynamic_column_history_record ON dynamic_column_history(table_name, record_id, column_name, changed_at);
+CREATE INDEX idx_dynamic_column_history_request_id ON dynamic_column_history(request_id) WHERE request_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS dynamic_column_history;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE webhook_delivery ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE webhook_delivery DROP COLUMN IF EXISTS locked_until;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE dynamic_column_refresh_outbox ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+DROP INDEX IF EXISTS idx_dynamic_column_refresh_outbox_pending;
+CREATE INDEX idx_dynamic_column_refresh_outbox_pending ON dynamic_column_refresh_outbox(next_attempt_at, id) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_dynamic_column_refresh_outbox_pending;
+CREATE INDEX idx_dynamic_column_refresh_outbox_pending ON dynamic_column_refresh_outbox(id) WHERE status = 'pending';
+
+ALTER TABLE dynamic_column_refresh_outbox DROP COLUMN IF EXISTS next_attempt_at;
+-- +goose StatementEnd
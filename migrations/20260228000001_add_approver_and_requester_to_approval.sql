@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE approval ADD COLUMN IF NOT EXISTS approver_employee_id BIGINT;
+ALTER TABLE approval ADD COLUMN IF NOT EXISTS requester_employee_id BIGINT;
+ALTER TABLE approval ADD CONSTRAINT fk_approval_approver_employee FOREIGN KEY (approver_employee_id) REFERENCES employee(id) ON DELETE SET NULL;
+ALTER TABLE approval ADD CONSTRAINT fk_approval_requester_employee FOREIGN KEY (requester_employee_id) REFERENCES employee(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_approval_approver_employee_id ON approval(approver_employee_id);
+CREATE INDEX IF NOT EXISTS idx_approval_requester_employee_id ON approval(requester_employee_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_approval_requester_employee_id;
+DROP INDEX IF EXISTS idx_approval_approver_employee_id;
+ALTER TABLE approval DROP CONSTRAINT IF EXISTS fk_approval_requester_employee;
+ALTER TABLE approval DROP CONSTRAINT IF EXISTS fk_approval_approver_employee;
+ALTER TABLE approval DROP COLUMN IF EXISTS requester_employee_id;
+ALTER TABLE approval DROP COLUMN IF EXISTS approver_employee_id;
+-- +goose StatementEnd